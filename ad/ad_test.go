@@ -0,0 +1,147 @@
+package ad
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+// elementary applies all operations of Scalar to x
+func elementary[T Scalar[T]](x T) []T {
+	two := x.Const(2.0)
+	return []T{
+		x.Add(x.Mul(x)), x.Sub(two.Mul(x)), x.Mul(x).Mul(x), two.Div(x), x.Neg(), x.Scale(3.0),
+		x.Sqrt(), x.Exp(), x.Log(), x.Sin(), x.Cos(), x.Pow(2.5),
+	}
+}
+
+func TestElementary(t *testing.T) {
+	x := 0.7
+	// values, first and second derivatives of the functions in elementary
+	f := []float64{x + x*x, -x, x * x * x, 2 / x, -x, 3 * x,
+		math.Sqrt(x), math.Exp(x), math.Log(x), math.Sin(x), math.Cos(x), math.Pow(x, 2.5)}
+	df := []float64{1 + 2*x, -1, 3 * x * x, -2 / (x * x), -1, 3,
+		0.5 / math.Sqrt(x), math.Exp(x), 1 / x, math.Cos(x), -math.Sin(x), 2.5 * math.Pow(x, 1.5)}
+	ddf := []float64{2, 0, 6 * x, 4 / (x * x * x), 0, 0,
+		-0.25 / (x * math.Sqrt(x)), math.Exp(x), -1 / (x * x), -math.Sin(x), -math.Cos(x), 3.75 * math.Sqrt(x)}
+
+	floats := elementary(Float(x))
+	duals := elementary(Dual{Value: x, Derivative: 2.0})
+	hyper := elementary(HyperDual{Value: x, E1: 2.0, E2: 3.0})
+	for k := range f {
+		if !util.EpsEqual(float64(floats[k]), f[k], 1e-12) || !util.EpsEqual(duals[k].Real(), f[k], 1e-12) || !util.EpsEqual(hyper[k].Real(), f[k], 1e-12) {
+			t.Errorf("Function %d: value %g, dual %g, hyper-dual %g, expected %g", k, floats[k], duals[k].Value, hyper[k].Value, f[k])
+		}
+		if !util.EpsEqual(duals[k].Derivative, 2.0*df[k], 1e-12) {
+			t.Errorf("Function %d: dual derivative %g, expected %g", k, duals[k].Derivative, 2.0*df[k])
+		}
+		if !util.EpsEqual(hyper[k].E1, 2.0*df[k], 1e-12) || !util.EpsEqual(hyper[k].E2, 3.0*df[k], 1e-12) {
+			t.Errorf("Function %d: hyper-dual derivatives %g, %g, expected %g, %g", k, hyper[k].E1, hyper[k].E2, 2.0*df[k], 3.0*df[k])
+		}
+		if !util.EpsEqual(hyper[k].E12, 6.0*ddf[k], 1e-12) {
+			t.Errorf("Function %d: hyper-dual second derivative %g, expected %g", k, hyper[k].E12, 6.0*ddf[k])
+		}
+	}
+}
+
+// f(y, p) = (p0 y0 y1, sin(y0) + p1 y1^3)
+func example[T Scalar[T]](t float64, yT, p []T, dy_out []T) {
+	dy_out[0] = p[0].Mul(yT[0]).Mul(yT[1])
+	dy_out[1] = yT[0].Sin().Add(p[1].Mul(yT[1].Pow(3.0)))
+}
+
+func exampleSystem() *ParametricSystem {
+	return &ParametricSystem{Value: example[Float], Dual: example[Dual], HyperDual: example[HyperDual]}
+}
+
+func TestSystem(t *testing.T) {
+	p := []float64{2.0, -0.5}
+	s := exampleSystem().Fix(p)
+	y := []float64{0.3, 1.2}
+	expected := [][]float64{
+		{p[0] * y[1], p[0] * y[0]},
+		{math.Cos(y[0]), 3.0 * p[1] * y[1] * y[1]},
+	}
+
+	dy := make([]float64, 2)
+	s.Function()(0, y, dy)
+	if !util.EpsEqual(dy[0], p[0]*y[0]*y[1], 1e-12) || !util.EpsEqual(dy[1], math.Sin(y[0])+p[1]*y[1]*y[1]*y[1], 1e-12) {
+		t.Errorf("Function evaluates to %v", dy)
+	}
+
+	dense := util.MakeRectangular(2, 2)
+	s.Jacobian()(0, y, dense)
+	sparse := ode.NewSparseMatrix(ode.NewSparsity([][]int{{0, 1}, {0, 1}}))
+	s.SparseJacobian()(0, y, sparse)
+	for i := range expected {
+		for j := range expected[i] {
+			if !util.EpsEqual(dense[i][j], expected[i][j], 1e-12) || !util.EpsEqual(sparse.Values[sparse.Index(i, j)], expected[i][j], 1e-12) {
+				t.Errorf("J[%d][%d] = %g, sparse %g, expected %g", i, j, dense[i][j], sparse.Values[sparse.Index(i, j)], expected[i][j])
+			}
+		}
+	}
+
+	v, w := []float64{1.0, -2.0}, []float64{0.5, 3.0}
+	jv := make([]float64, 2)
+	s.JacobianVector()(0, y, v, jv)
+	for i := range jv {
+		if product := expected[i][0]*v[0] + expected[i][1]*v[1]; !util.EpsEqual(jv[i], product, 1e-12) {
+			t.Errorf("(J v)[%d] = %g, expected %g", i, jv[i], product)
+		}
+	}
+
+	d2f := make([]float64, 2)
+	s.SecondDerivative(0, y, v, w, d2f)
+	second := []float64{p[0] * (v[0]*w[1] + v[1]*w[0]), -math.Sin(y[0])*v[0]*w[0] + 6.0*p[1]*y[1]*v[1]*w[1]}
+	for i := range d2f {
+		if !util.EpsEqual(d2f[i], second[i], 1e-12) {
+			t.Errorf("f''[v, w][%d] = %g, expected %g", i, d2f[i], second[i])
+		}
+	}
+}
+
+func TestSensitivity(t *testing.T) {
+	p := []float64{2.0, -0.5}
+	y := []float64{0.3, 1.2}
+	sensitivity := [][]float64{{1.0, 0.5}, {-1.0, 2.0}}
+	ds := util.MakeRectangular(2, 2)
+	exampleSystem().Sensitivity()(0, y, p, sensitivity, ds)
+
+	jacobian := [][]float64{
+		{p[0] * y[1], p[0] * y[0]},
+		{math.Cos(y[0]), 3.0 * p[1] * y[1] * y[1]},
+	}
+	dfdp := [][]float64{{y[0] * y[1], 0.0}, {0.0, y[1] * y[1] * y[1]}}
+	for j := range p {
+		for i := range y {
+			expected := jacobian[i][0]*sensitivity[j][0] + jacobian[i][1]*sensitivity[j][1] + dfdp[i][j]
+			if !util.EpsEqual(ds[j][i], expected, 1e-12) {
+				t.Errorf("Sensitivity %d, component %d: %g, expected %g", j, i, ds[j][i], expected)
+			}
+		}
+	}
+}
+
+func TestSystemAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skipf("Skipping because the race detector drops pooled buffers.")
+	}
+	// the arguments over Float and Dual are reused from evaluation to evaluation
+	p, y, v := []float64{2.0, -0.5}, []float64{0.3, 1.2}, []float64{1.0, -2.0}
+	s := exampleSystem()
+	function, jacobianVector, parametric, sensitivity := s.Fix(p).Function(), s.Fix(p).JacobianVector(), s.Parametric(), s.Sensitivity()
+	dy, sens, ds := make([]float64, 2), util.MakeRectangular(2, 2), util.MakeRectangular(2, 2)
+
+	evaluations := map[string]func(){
+		"Function":       func() { function(0, y, dy) },
+		"JacobianVector": func() { jacobianVector(0, y, v, dy) },
+		"Parametric":     func() { parametric(0, y, p, dy) },
+		"Sensitivity":    func() { sensitivity(0, y, p, sens, ds) },
+	}
+	for name, evaluate := range evaluations {
+		if allocs := testing.AllocsPerRun(100, evaluate); allocs != 0 {
+			t.Errorf("%s: %g allocations per evaluation", name, allocs)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ad
+
+import "math"
+
+// Dual is the dual number Value + Derivative ε with ε^2 = 0. Evaluating a function
+// at x + v ε gives its value and the directional derivative in direction v
+type Dual struct {
+	Value, Derivative float64
+}
+
+// chain applies a function with value f and derivative df at x.Value
+func (x Dual) chain(f, df float64) Dual {
+	return Dual{f, df * x.Derivative}
+}
+
+func (x Dual) Add(y Dual) Dual      { return Dual{x.Value + y.Value, x.Derivative + y.Derivative} }
+func (x Dual) Sub(y Dual) Dual      { return Dual{x.Value - y.Value, x.Derivative - y.Derivative} }
+func (x Dual) Neg() Dual            { return Dual{-x.Value, -x.Derivative} }
+func (x Dual) Scale(c float64) Dual { return Dual{c * x.Value, c * x.Derivative} }
+
+func (x Dual) Mul(y Dual) Dual {
+	return Dual{x.Value * y.Value, x.Derivative*y.Value + x.Value*y.Derivative}
+}
+
+func (x Dual) Div(y Dual) Dual {
+	quotient := x.Value / y.Value
+	return Dual{quotient, (x.Derivative - quotient*y.Derivative) / y.Value}
+}
+
+func (x Dual) Sqrt() Dual {
+	root := math.Sqrt(x.Value)
+	return x.chain(root, 0.5/root)
+}
+
+func (x Dual) Exp() Dual {
+	exp := math.Exp(x.Value)
+	return x.chain(exp, exp)
+}
+
+func (x Dual) Log() Dual { return x.chain(math.Log(x.Value), 1.0/x.Value) }
+func (x Dual) Sin() Dual { return x.chain(math.Sin(x.Value), math.Cos(x.Value)) }
+func (x Dual) Cos() Dual { return x.chain(math.Cos(x.Value), -math.Sin(x.Value)) }
+
+func (x Dual) Pow(p float64) Dual {
+	return x.chain(math.Pow(x.Value, p), p*math.Pow(x.Value, p-1.0))
+}
+
+func (x Dual) Const(c float64) Dual { return Dual{Value: c} }
+func (x Dual) Real() float64        { return x.Value }
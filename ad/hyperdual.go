@@ -0,0 +1,74 @@
+package ad
+
+import "math"
+
+// HyperDual is the number Value + E1 ε1 + E2 ε2 + E12 ε1 ε2 with ε1^2 = ε2^2 = 0.
+// Evaluating a function at x + v ε1 + w ε2 gives the directional derivatives in
+// directions v and w and the second derivative in directions v and w in E12
+type HyperDual struct {
+	Value, E1, E2, E12 float64
+}
+
+// chain applies a function with value f and derivatives df, ddf at x.Value
+func (x HyperDual) chain(f, df, ddf float64) HyperDual {
+	return HyperDual{f, df * x.E1, df * x.E2, df*x.E12 + ddf*x.E1*x.E2}
+}
+
+func (x HyperDual) Add(y HyperDual) HyperDual {
+	return HyperDual{x.Value + y.Value, x.E1 + y.E1, x.E2 + y.E2, x.E12 + y.E12}
+}
+
+func (x HyperDual) Sub(y HyperDual) HyperDual {
+	return HyperDual{x.Value - y.Value, x.E1 - y.E1, x.E2 - y.E2, x.E12 - y.E12}
+}
+
+func (x HyperDual) Neg() HyperDual { return HyperDual{-x.Value, -x.E1, -x.E2, -x.E12} }
+
+func (x HyperDual) Scale(c float64) HyperDual {
+	return HyperDual{c * x.Value, c * x.E1, c * x.E2, c * x.E12}
+}
+
+func (x HyperDual) Mul(y HyperDual) HyperDual {
+	return HyperDual{
+		x.Value * y.Value,
+		x.E1*y.Value + x.Value*y.E1,
+		x.E2*y.Value + x.Value*y.E2,
+		x.E12*y.Value + x.E1*y.E2 + x.E2*y.E1 + x.Value*y.E12,
+	}
+}
+
+func (x HyperDual) Div(y HyperDual) HyperDual {
+	inverse := 1.0 / y.Value
+	return x.Mul(y.chain(inverse, -inverse*inverse, 2.0*inverse*inverse*inverse))
+}
+
+func (x HyperDual) Sqrt() HyperDual {
+	root := math.Sqrt(x.Value)
+	return x.chain(root, 0.5/root, -0.25/(root*x.Value))
+}
+
+func (x HyperDual) Exp() HyperDual {
+	exp := math.Exp(x.Value)
+	return x.chain(exp, exp, exp)
+}
+
+func (x HyperDual) Log() HyperDual {
+	return x.chain(math.Log(x.Value), 1.0/x.Value, -1.0/(x.Value*x.Value))
+}
+
+func (x HyperDual) Sin() HyperDual {
+	sin, cos := math.Sincos(x.Value)
+	return x.chain(sin, cos, -sin)
+}
+
+func (x HyperDual) Cos() HyperDual {
+	sin, cos := math.Sincos(x.Value)
+	return x.chain(cos, -sin, -cos)
+}
+
+func (x HyperDual) Pow(p float64) HyperDual {
+	return x.chain(math.Pow(x.Value, p), p*math.Pow(x.Value, p-1.0), p*(p-1.0)*math.Pow(x.Value, p-2.0))
+}
+
+func (x HyperDual) Const(c float64) HyperDual { return HyperDual{Value: c} }
+func (x HyperDual) Real() float64             { return x.Value }
@@ -0,0 +1,58 @@
+// Package ad provides forward mode automatic differentiation with dual and hyper-dual numbers.
+// Right hand sides are written once as generic functions over a Scalar and instantiated
+// for Float values and for the number types the derivatives are computed with
+package ad
+
+import (
+	"math"
+	"math/big"
+)
+
+// Scalar is the arithmetic available to generic right hand sides,
+// Go has no operator overloading, so all operations are methods
+type Scalar[T any] interface {
+	Add(y T) T
+	Sub(y T) T
+	Mul(y T) T
+	Div(y T) T
+	Neg() T
+	// Scale multiplies by a constant
+	Scale(c float64) T
+	Sqrt() T
+	Exp() T
+	Log() T
+	Sin() T
+	Cos() T
+	// Pow raises to a constant power
+	Pow(p float64) T
+	// Const returns the constant c, independent of the receiver
+	Const(c float64) T
+	// Real returns the value without derivative parts
+	Real() float64
+}
+
+// Float is a plain float64 as Scalar
+type Float float64
+
+func (x Float) Add(y Float) Float     { return x + y }
+func (x Float) Sub(y Float) Float     { return x - y }
+func (x Float) Mul(y Float) Float     { return x * y }
+func (x Float) Div(y Float) Float     { return x / y }
+func (x Float) Neg() Float            { return -x }
+func (x Float) Scale(c float64) Float { return Float(c) * x }
+func (x Float) Sqrt() Float           { return Float(math.Sqrt(float64(x))) }
+func (x Float) Exp() Float            { return Float(math.Exp(float64(x))) }
+func (x Float) Log() Float            { return Float(math.Log(float64(x))) }
+func (x Float) Sin() Float            { return Float(math.Sin(float64(x))) }
+func (x Float) Cos() Float            { return Float(math.Cos(float64(x))) }
+func (x Float) Pow(p float64) Float   { return Float(math.Pow(float64(x), p)) }
+func (x Float) Const(c float64) Float { return Float(c) }
+func (x Float) Real() float64         { return float64(x) }
+
+// Precision and Rat make Float a precision.Real, the generic integrators compute with
+// float64 directly for it, so it is as fast as the float64 API
+func (x Float) Precision() uint { return 53 }
+func (x Float) Rat(r *big.Rat) Float {
+	f, _ := r.Float64()
+	return Float(f)
+}
@@ -0,0 +1,234 @@
+package ad
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"sync"
+)
+
+// Fcn is a right hand side yT' = f(t, yT) over the scalar type T
+type Fcn[T Scalar[T]] func(t float64, yT []T, dy_out []T)
+
+// ParametricFcn is a right hand side yT' = f(t, yT, p) over the scalar type T
+type ParametricFcn[T Scalar[T]] func(t float64, yT, p []T, dy_out []T)
+
+// System is a right hand side written once as a generic function fcn and instantiated as
+// System{Value: fcn[Float], Dual: fcn[Dual], HyperDual: fcn[HyperDual]}.
+// HyperDual is only needed for second derivatives
+type System struct {
+	Value     Fcn[Float]
+	Dual      Fcn[Dual]
+	HyperDual Fcn[HyperDual]
+}
+
+// ParametricSystem is a parametric right hand side instantiated like a System
+type ParametricSystem struct {
+	Value     ParametricFcn[Float]
+	Dual      ParametricFcn[Dual]
+	HyperDual ParametricFcn[HyperDual]
+}
+
+// Fix returns fcn for the constant parameters p
+func Fix[T Scalar[T]](fcn ParametricFcn[T], p []float64) Fcn[T] {
+	if fcn == nil {
+		return nil
+	}
+	var zero T
+	constants := make([]T, len(p))
+	for j := range p {
+		constants[j] = zero.Const(p[j])
+	}
+	return func(t float64, yT []T, dy_out []T) { fcn(t, yT, constants, dy_out) }
+}
+
+// Function returns fcn for float64 values, converted with prototype.Const,
+// e.g. for the step size estimates of the generic integrators
+func Function[T Scalar[T]](fcn Fcn[T], prototype T) ode.Function {
+	pool := newBuffers[T]()
+	return func(t float64, yT []float64, dy_out []float64) {
+		b := pool.Get().(*buffers[T])
+		b.y, b.dy = resize(b.y, len(yT)), resize(b.dy, len(dy_out))
+		for i, v := range yT {
+			b.y[i] = prototype.Const(v)
+		}
+		fcn(t, b.y, b.dy)
+		for i, v := range b.dy {
+			dy_out[i] = v.Real()
+		}
+		pool.Put(b)
+	}
+}
+
+// Fix returns the system for the constant parameters p
+func (s *ParametricSystem) Fix(p []float64) *System {
+	return &System{Value: Fix(s.Value, p), Dual: Fix(s.Dual, p), HyperDual: Fix(s.HyperDual, p)}
+}
+
+// the arguments of one evaluation over the scalar type T, reused from evaluation to evaluation
+type buffers[T any] struct {
+	y, p, dy []T
+}
+
+// newBuffers returns a pool of buffers, the right hand sides may be evaluated concurrently
+func newBuffers[T any]() *sync.Pool {
+	return &sync.Pool{New: func() interface{} { return new(buffers[T]) }}
+}
+
+// resize returns x with length n, reallocated only if its capacity is too small
+func resize[T any](x []T, n int) []T {
+	if cap(x) < n {
+		return make([]T, n)
+	}
+	return x[:n]
+}
+
+// toFloats converts x into y, which is resized
+func toFloats(x []float64, y []Float) []Float {
+	y = resize(y, len(x))
+	for i, v := range x {
+		y[i] = Float(v)
+	}
+	return y
+}
+
+// toDuals converts x into y, which is resized, and seeds the derivative parts with direction,
+// which may be nil
+func toDuals(x, direction []float64, y []Dual) []Dual {
+	y = resize(y, len(x))
+	for i, v := range x {
+		y[i].Value, y[i].Derivative = v, 0.0
+		if direction != nil {
+			y[i].Derivative = direction[i]
+		}
+	}
+	return y
+}
+
+// Function returns the right hand side for float64 values
+func (s *System) Function() ode.Function {
+	value, pool := s.Value, newBuffers[Float]()
+	return func(t float64, yT []float64, dy_out []float64) {
+		b := pool.Get().(*buffers[Float])
+		b.y, b.dy = toFloats(yT, b.y), resize(b.dy, len(dy_out))
+		value(t, b.y, b.dy)
+		for i, v := range b.dy {
+			dy_out[i] = float64(v)
+		}
+		pool.Put(b)
+	}
+}
+
+// JacobianVector returns the Jacobian-vector products, each costs one evaluation with dual numbers
+func (s *System) JacobianVector() ode.JacobianVectorFunction {
+	dual, pool := s.Dual, newBuffers[Dual]()
+	return func(t float64, yT, v []float64, jv_out []float64) {
+		b := pool.Get().(*buffers[Dual])
+		b.y, b.dy = toDuals(yT, v, b.y), resize(b.dy, len(jv_out))
+		dual(t, b.y, b.dy)
+		for i := range b.dy {
+			jv_out[i] = b.dy[i].Derivative
+		}
+		pool.Put(b)
+	}
+}
+
+// Jacobian returns the dense Jacobian, computed column by column with dual numbers
+func (s *System) Jacobian() ode.JacobianFunction {
+	dual := s.Dual
+	return func(t float64, yT []float64, jac_out [][]float64) {
+		y, dy := toDuals(yT, nil, nil), make([]Dual, len(yT))
+		for col := range y {
+			y[col].Derivative = 1.0
+			dual(t, y, dy)
+			y[col].Derivative = 0.0
+			for i := range dy {
+				jac_out[i][col] = dy[i].Derivative
+			}
+		}
+	}
+}
+
+// SparseJacobian returns the Jacobian on the pattern of jac_out, structurally orthogonal
+// columns are computed together with one evaluation with dual numbers
+func (s *System) SparseJacobian() ode.SparseJacobianFunction {
+	dual := s.Dual
+	return func(t float64, yT []float64, jac_out *ode.SparseMatrix) {
+		colors, count := jac_out.Coloring()
+		y, dy := toDuals(yT, nil, nil), make([]Dual, len(yT))
+		for color := 0; color < count; color++ {
+			for col := range y {
+				if colors[col] == color {
+					y[col].Derivative = 1.0
+				} else {
+					y[col].Derivative = 0.0
+				}
+			}
+			dual(t, y, dy)
+			for i := range dy {
+				for k := jac_out.RowStart[i]; k < jac_out.RowStart[i+1]; k++ {
+					if colors[jac_out.Columns[k]] == color {
+						jac_out.Values[k] = dy[i].Derivative
+					}
+				}
+			}
+		}
+	}
+}
+
+// SecondDerivative computes the second derivative of the right hand side at (t, yT)
+// in the directions v and w with one evaluation with hyper-dual numbers
+func (s *System) SecondDerivative(t float64, yT, v, w []float64, d2f_out []float64) {
+	y, dy := make([]HyperDual, len(yT)), make([]HyperDual, len(yT))
+	for i := range y {
+		y[i] = HyperDual{Value: yT[i], E1: v[i], E2: w[i]}
+	}
+	s.HyperDual(t, y, dy)
+	for i := range dy {
+		d2f_out[i] = dy[i].E12
+	}
+}
+
+// Configure sets the right hand side, the Jacobian-vector products and the Jacobian of c,
+// the Jacobian on the pattern of c.Sparsity if it is set
+func (s *System) Configure(c *ode.Config) {
+	c.Fcn, c.FcnBlocked = s.Function(), nil
+	c.JacobianVector = s.JacobianVector()
+	if c.Sparsity != nil {
+		c.SparseJacobian, c.Jacobian = s.SparseJacobian(), nil
+	} else {
+		c.Jacobian, c.SparseJacobian = s.Jacobian(), nil
+	}
+}
+
+// Parametric returns the right hand side for float64 values
+func (s *ParametricSystem) Parametric() ode.ParametricFunction {
+	value, pool := s.Value, newBuffers[Float]()
+	return func(t float64, yT, p []float64, dy_out []float64) {
+		b := pool.Get().(*buffers[Float])
+		b.y, b.p, b.dy = toFloats(yT, b.y), toFloats(p, b.p), resize(b.dy, len(dy_out))
+		value(t, b.y, b.p, b.dy)
+		for i, v := range b.dy {
+			dy_out[i] = float64(v)
+		}
+		pool.Put(b)
+	}
+}
+
+// Sensitivity returns the right hand side of the sensitivity equations, the sensitivity
+// of each parameter costs one evaluation with dual numbers in the direction (s_j, e_j)
+func (s *ParametricSystem) Sensitivity() ode.SensitivityFunction {
+	dual, pool := s.Dual, newBuffers[Dual]()
+	return func(t float64, yT, p []float64, sensitivity [][]float64, ds_out [][]float64) {
+		b := pool.Get().(*buffers[Dual])
+		b.p, b.dy = toDuals(p, nil, b.p), resize(b.dy, len(yT))
+		for j := range p {
+			b.p[j].Derivative = 1.0
+			b.y = toDuals(yT, sensitivity[j], b.y)
+			dual(t, b.y, b.p, b.dy)
+			b.p[j].Derivative = 0.0
+			for i := range b.dy {
+				ds_out[j][i] = b.dy[i].Derivative
+			}
+		}
+		pool.Put(b)
+	}
+}
@@ -0,0 +1,70 @@
+// Command reference computes a reference solution of a problem to many digits by
+// extrapolation over big.Float and writes it in the format of problems.ReadReference, e.g.
+//
+//	go run ./cmd/reference -problem mbody -size 4 -o problems/references/mbody4.ref
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/reference"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/problems"
+	"io"
+	"log"
+	"math"
+	"os"
+)
+
+func main() {
+	problem := flag.String("problem", "mbody", "problem, mbody or bruss2d")
+	size := flag.Uint("size", 4, "number of bodies or grid points per dimension")
+	t0 := flag.Float64("t0", 0.0, "initial time")
+	t1 := flag.Float64("t1", 1.0, "final time")
+	digits := flag.Uint("digits", 40, "significant digits of the solution")
+	output := flag.String("o", "", "output file, standard output if empty")
+	flag.Parse()
+
+	p, err := problems.NewReferenceProblem(*problem, *size)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fcn, err := problems.ReferenceFcn[precision.Big](*problem, *size)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// a few more digits for the tolerance, and ten more plus guard bits for the arithmetic
+	tolerance := math.Pow(10.0, -float64(*digits+3))
+	bits := uint(float64(*digits+10)*math.Log2(10.0)) + 32
+	y := precision.FromFloat64(p.Initialize(), precision.NewBig(0.0, bits))
+
+	config := &ode.Config{AbsoluteTolerance: tolerance, RelativeTolerance: tolerance, MaxStepCount: 1000000}
+	stat, err := reference.NewExtrapolation[precision.Big]().Integrate(*t0, *t1, y, reference.Autonomous(fcn), config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ref := &problems.Reference{
+		Problem: *problem, Size: *size, T0: *t0, T1: *t1, Digits: *digits,
+		Comment: fmt.Sprintf("%s %d, extrapolation over %d bits with tolerance %g\n%d steps, %d rejected, %d evaluations",
+			*problem, *size, bits, tolerance, stat.StepCount, stat.RejectedCount, stat.EvaluationCount),
+	}
+	for _, v := range y {
+		ref.Values = append(ref.Values, v.Float().Text('e', int(*digits)-1))
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := ref.Write(w); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,160 @@
+package linalg
+
+import "math"
+
+// Banded is a square matrix whose entries (i, j) vanish for j < i - Lower and j > i + Upper
+type Banded struct {
+	N, Lower, Upper int
+	// columns of the band, entry (i, j) is stored in data[j][i - j + Upper]
+	data [][]float64
+}
+
+// NewBanded allocates a zero n x n matrix with the given bandwidths
+func NewBanded(n, lower, upper int) *Banded {
+	b := &Banded{N: n, Lower: lower, Upper: upper}
+	b.data = makeRectangular(n, lower+upper+1)
+	return b
+}
+
+// InBand reports whether entry (i, j) may be nonzero
+func (b *Banded) InBand(i, j int) bool {
+	return j-i <= b.Upper && i-j <= b.Lower && i >= 0 && j >= 0 && i < b.N && j < b.N
+}
+
+// At returns entry (i, j)
+func (b *Banded) At(i, j int) float64 {
+	if !b.InBand(i, j) {
+		return 0.0
+	}
+	return b.data[j][i-j+b.Upper]
+}
+
+// Set sets entry (i, j), which has to be within the band
+func (b *Banded) Set(i, j int, value float64) {
+	b.data[j][i-j+b.Upper] = value
+}
+
+// MulVec computes y_out = b x
+func (b *Banded) MulVec(x, y_out []float64) {
+	for i := range y_out {
+		y_out[i] = 0.0
+	}
+	for j := 0; j < b.N; j++ {
+		for i := imax(0, j-b.Upper); i <= imin(b.N-1, j+b.Lower); i++ {
+			y_out[i] += b.data[j][i-j+b.Upper] * x[j]
+		}
+	}
+}
+
+// BandedLU is the LU decomposition with partial pivoting of a banded matrix.
+// Pivoting widens the upper band of U to Lower + Upper
+type BandedLU struct {
+	n, lower, upper int
+	// entry (i, j) is stored in band[j][i - j + lower + upper]
+	band  [][]float64
+	pivot []int
+}
+
+func (f *BandedLU) at(i, j int) *float64 {
+	return &f.band[j][i-j+f.lower+f.upper]
+}
+
+// Factorize computes the decomposition P b = L U, b is not modified
+func (f *BandedLU) Factorize(b *Banded) error {
+	if f.n != b.N || f.lower != b.Lower || f.upper != b.Upper || f.band == nil {
+		f.n, f.lower, f.upper = b.N, b.Lower, b.Upper
+		f.band = makeRectangular(b.N, 2*b.Lower+b.Upper+1)
+		f.pivot = make([]int, b.N)
+	}
+	n, lower, width := f.n, f.lower, f.lower+f.upper
+	for j := 0; j < n; j++ {
+		for k := range f.band[j] {
+			f.band[j][k] = 0.0
+		}
+		copy(f.band[j][lower:], b.data[j])
+	}
+
+	for k := 0; k < n; k++ {
+		last := imin(n-1, k+lower)
+		p := k
+		for i := k + 1; i <= last; i++ {
+			if math.Abs(*f.at(i, k)) > math.Abs(*f.at(p, k)) {
+				p = i
+			}
+		}
+		f.pivot[k] = p
+		pivot := *f.at(p, k)
+		if pivot == 0.0 {
+			return &SingularError{Step: k}
+		}
+
+		lastColumn := imin(n-1, k+width)
+		if p != k {
+			for j := k; j <= lastColumn; j++ {
+				rowK, rowP := f.at(k, j), f.at(p, j)
+				*rowK, *rowP = *rowP, *rowK
+			}
+		}
+
+		// column by column, the band of each column is contiguous
+		columnK := f.band[k][width : last-k+width+1]
+		for i := range columnK[1:] {
+			columnK[i+1] /= pivot
+		}
+		for j := k + 1; j <= lastColumn; j++ {
+			column := f.band[j]
+			factor := column[k-j+width]
+			if factor == 0.0 {
+				continue
+			}
+			for i := k + 1; i <= last; i++ {
+				column[i-j+width] -= columnK[i-k] * factor
+			}
+		}
+	}
+	return nil
+}
+
+// Solve overwrites x with the solution of b x = rhs for the last factorized matrix b
+func (f *BandedLU) Solve(x []float64) {
+	n, lower, width := f.n, f.lower, f.lower+f.upper
+
+	// row interchanges are applied in the order of elimination
+	for k := 0; k < n; k++ {
+		x[k], x[f.pivot[k]] = x[f.pivot[k]], x[k]
+		for i := k + 1; i <= imin(n-1, k+lower); i++ {
+			x[i] -= *f.at(i, k) * x[k]
+		}
+	}
+	// column oriented back substitution
+	for j := n - 1; j >= 0; j-- {
+		x[j] /= *f.at(j, j)
+		column := f.band[j]
+		for i := imax(0, j-width); i < j; i++ {
+			x[i] -= column[i-j+width] * x[j]
+		}
+	}
+}
+
+func makeRectangular(rows, cols int) [][]float64 {
+	data := make([]float64, rows*cols)
+	a := make([][]float64, rows)
+	for i := range a {
+		a[i] = data[i*cols : (i+1)*cols]
+	}
+	return a
+}
+
+func imin(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func imax(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,100 @@
+package linalg
+
+import "math"
+
+// LU is the LU decomposition with partial pivoting of a dense square matrix.
+// A LU can be factorized again for a new matrix of the same size without allocating
+type LU struct {
+	n     int
+	lu    [][]float64
+	pivot []int
+}
+
+// Factorize computes the decomposition P a = L U, a is not modified
+func (f *LU) Factorize(a [][]float64) error {
+	n := len(a)
+	if f.n != n || f.lu == nil {
+		f.n = n
+		f.lu = makeRectangular(n, n)
+		f.pivot = make([]int, n)
+	}
+	for i := range a {
+		if len(a[i]) != n {
+			return &DimensionError{Expected: n, Actual: len(a[i])}
+		}
+		copy(f.lu[i], a[i])
+	}
+
+	lu := f.lu
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(lu[i][k]) > math.Abs(lu[p][k]) {
+				p = i
+			}
+		}
+		f.pivot[k] = p
+		if lu[p][k] == 0.0 {
+			return &SingularError{Step: k}
+		}
+		lu[k], lu[p] = lu[p], lu[k]
+
+		for i := k + 1; i < n; i++ {
+			lu[i][k] /= lu[k][k]
+			factor := lu[i][k]
+			if factor == 0.0 {
+				continue
+			}
+			for j := k + 1; j < n; j++ {
+				lu[i][j] -= factor * lu[k][j]
+			}
+		}
+	}
+	return nil
+}
+
+// Solve overwrites b with the solution of a x = b for the last factorized matrix a
+func (f *LU) Solve(b []float64) {
+	n, lu := f.n, f.lu
+	for k := 0; k < n; k++ {
+		b[k], b[f.pivot[k]] = b[f.pivot[k]], b[k]
+	}
+	for i := 1; i < n; i++ {
+		for j := 0; j < i; j++ {
+			b[i] -= lu[i][j] * b[j]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			b[i] -= lu[i][j] * b[j]
+		}
+		b[i] /= lu[i][i]
+	}
+}
+
+// MulVec computes y_out = a x for a dense matrix a
+func MulVec(a [][]float64, x, y_out []float64) {
+	for i := range a {
+		sum := 0.0
+		for j, value := range a[i] {
+			sum += value * x[j]
+		}
+		y_out[i] = sum
+	}
+}
+
+func norm(x []float64) float64 {
+	sum := 0.0
+	for _, value := range x {
+		sum += value * value
+	}
+	return math.Sqrt(sum)
+}
+
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
@@ -0,0 +1,50 @@
+package linalg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Categories of solver failures, use errors.Is to test an error against them
+// and errors.As to get the details
+var (
+	ErrSingular     = errors.New("singular matrix")
+	ErrNotConverged = errors.New("iteration did not converge")
+	ErrDimension    = errors.New("dimension mismatch")
+)
+
+// SingularError reports a zero pivot during a factorization
+type SingularError struct {
+	// Step is the elimination step at which no pivot was found
+	Step int
+}
+
+func (e *SingularError) Error() string {
+	return fmt.Sprintf("singular matrix: no pivot in step %d", e.Step)
+}
+
+func (e *SingularError) Is(target error) bool { return target == ErrSingular }
+
+// ConvergenceError reports that an iterative solver stopped before reaching the tolerance
+type ConvergenceError struct {
+	Iterations int
+	// Residual is the norm of the last residual relative to the right hand side
+	Residual float64
+}
+
+func (e *ConvergenceError) Error() string {
+	return fmt.Sprintf("iteration did not converge: relative residual %g after %d iterations", e.Residual, e.Iterations)
+}
+
+func (e *ConvergenceError) Is(target error) bool { return target == ErrNotConverged }
+
+// DimensionError reports operands of incompatible sizes
+type DimensionError struct {
+	Expected, Actual int
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("dimension mismatch: expected %d, got %d", e.Expected, e.Actual)
+}
+
+func (e *DimensionError) Is(target error) bool { return target == ErrDimension }
@@ -0,0 +1,112 @@
+package linalg
+
+import "math"
+
+// degree of the diagonal Pade approximation used by Expm
+const padeDegree = 6
+
+// Expm computes exp_out = exp(a) of a dense square matrix by scaling and squaring
+// with a diagonal Pade approximation. It is meant for the small matrices of projection methods
+func Expm(a [][]float64, exp_out [][]float64) error {
+	n := len(a)
+	if len(exp_out) != n {
+		return &DimensionError{Expected: n, Actual: len(exp_out)}
+	}
+	for i := range a {
+		if len(a[i]) != n {
+			return &DimensionError{Expected: n, Actual: len(a[i])}
+		}
+		if len(exp_out[i]) != n {
+			return &DimensionError{Expected: n, Actual: len(exp_out[i])}
+		}
+	}
+
+	// scale the matrix until its norm is below 1/2
+	normInf := 0.0
+	for i := range a {
+		sum := 0.0
+		for _, value := range a[i] {
+			sum += math.Abs(value)
+		}
+		normInf = math.Max(normInf, sum)
+	}
+	squarings := 0
+	if normInf > 0.5 {
+		squarings = int(math.Ceil(math.Log2(normInf / 0.5)))
+	}
+	scale := math.Ldexp(1.0, -squarings)
+
+	x := makeRectangular(n, n)
+	power := makeRectangular(n, n)
+	numerator := makeRectangular(n, n)
+	denominator := makeRectangular(n, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x[i][j] = scale * a[i][j]
+			power[i][j] = x[i][j]
+		}
+		numerator[i][i] = 1.0
+		denominator[i][i] = 1.0
+	}
+
+	// N = sum c_k X^k, D = sum (-1)^k c_k X^k
+	c, sign := 1.0, 1.0
+	for k := 1; k <= padeDegree; k++ {
+		c *= float64(padeDegree-k+1) / float64(k*(2*padeDegree-k+1))
+		sign = -sign
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				numerator[i][j] += c * power[i][j]
+				denominator[i][j] += sign * c * power[i][j]
+			}
+		}
+		if k < padeDegree {
+			matMul(x, power, exp_out)
+			for i := range power {
+				copy(power[i], exp_out[i])
+			}
+		}
+	}
+
+	// exp(X) ~ D^-1 N, column by column
+	var lu LU
+	if err := lu.Factorize(denominator); err != nil {
+		return err
+	}
+	column := make([]float64, n)
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			column[i] = numerator[i][j]
+		}
+		lu.Solve(column)
+		for i := 0; i < n; i++ {
+			exp_out[i][j] = column[i]
+		}
+	}
+
+	for s := 0; s < squarings; s++ {
+		matMul(exp_out, exp_out, power)
+		for i := range power {
+			copy(exp_out[i], power[i])
+		}
+	}
+	return nil
+}
+
+// c_out = a b for dense square matrices, c_out must not alias a or b
+func matMul(a, b, c_out [][]float64) {
+	n := len(a)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			c_out[i][j] = 0.0
+		}
+		for k := 0; k < n; k++ {
+			if a[i][k] == 0.0 {
+				continue
+			}
+			for j := 0; j < n; j++ {
+				c_out[i][j] += a[i][k] * b[k][j]
+			}
+		}
+	}
+}
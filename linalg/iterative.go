@@ -0,0 +1,292 @@
+package linalg
+
+import "math"
+
+// LinearOperator computes y_out = A x for a matrix A that need not be stored
+type LinearOperator func(x, y_out []float64)
+
+// Preconditioner computes z_out as an approximation of the solution of A z = r,
+// e.g. with the factorization of an approximation of A
+type Preconditioner func(r, z_out []float64)
+
+type IterativeConfig struct {
+	// Tolerance is the norm of the residual relative to the norm of the right hand side
+	// at which the iteration stops. Default 1e-8
+	Tolerance float64
+
+	// MaxIterations is the maximal number of iterations, i.e. of basis vectors of the Krylov space
+	// for GMRES and of steps with two matrix vector products for BiCGStab. Default 10 n
+	MaxIterations int
+
+	// Restart is the dimension of the Krylov space after which GMRES restarts. Default 30
+	Restart int
+
+	// Preconditioner, if set, is applied from the right, so the residual
+	// used in the stopping criterion is the one of the original system
+	Preconditioner Preconditioner
+
+	// Workspace, if set, keeps the vectors of the iteration between calls,
+	// so repeated solves of systems of the same size don't allocate
+	Workspace *IterativeWorkspace
+}
+
+// IterativeWorkspace holds the vectors of GMRES and BiCGStab. The zero value is ready to use,
+// a workspace may not be used by concurrent solves
+type IterativeWorkspace struct {
+	vectors, hessenberg [][]float64
+}
+
+// get returns count vectors of length n, reusing those of w if possible
+func (w *IterativeWorkspace) get(count, n int) [][]float64 {
+	if w == nil {
+		return makeRectangular(count, n)
+	}
+	if len(w.vectors) < count || len(w.vectors[0]) != n {
+		w.vectors = makeRectangular(count, n)
+	}
+	return w.vectors[:count]
+}
+
+// the Hessenberg matrix and the Givens rotations of GMRES with restart m
+func (w *IterativeWorkspace) getHessenberg(m int) [][]float64 {
+	if w == nil {
+		return makeRectangular(m+4, m+1)
+	}
+	if len(w.hessenberg) != m+4 {
+		w.hessenberg = makeRectangular(m+4, m+1)
+	}
+	return w.hessenberg
+}
+
+func (c *IterativeConfig) withDefaults(n int) (e IterativeConfig) {
+	if c != nil {
+		e = *c
+	}
+	if e.Tolerance <= 0.0 {
+		e.Tolerance = 1e-8
+	}
+	if e.MaxIterations <= 0 {
+		e.MaxIterations = 10 * n
+	}
+	if e.Restart <= 0 {
+		e.Restart = 30
+	}
+	if e.Restart > n {
+		e.Restart = n
+	}
+	if e.Preconditioner == nil {
+		e.Preconditioner = func(r, z_out []float64) { copy(z_out, r) }
+	}
+	return
+}
+
+// GMRES solves A x = b with the restarted generalized minimal residual method.
+// x holds the initial guess and the solution on return
+func GMRES(a LinearOperator, b, x []float64, config *IterativeConfig) (iterations int, err error) {
+	n := len(b)
+	if len(x) != n {
+		return 0, &DimensionError{Expected: n, Actual: len(x)}
+	}
+	c := config.withDefaults(n)
+	m := c.Restart
+
+	bNorm := norm(b)
+	if bNorm == 0.0 {
+		for i := range x {
+			x[i] = 0.0
+		}
+		return
+	}
+
+	// orthonormal basis of the Krylov space, Hessenberg matrix and Givens rotations
+	vectors := c.Workspace.get(2*m+2, n)
+	v, z, w := vectors[:m+1], vectors[m+1:2*m+1], vectors[2*m+1]
+	small := c.Workspace.getHessenberg(m)
+	h := small[:m+1]
+	cs, sn, g := small[m+1][:m], small[m+2][:m], small[m+3]
+	for j := range h {
+		for k := range h[j] {
+			h[j][k] = 0.0
+		}
+	}
+
+	residual := math.Inf(1)
+	for iterations < c.MaxIterations {
+		// r = b - A x
+		a(x, w)
+		for i := range w {
+			v[0][i] = b[i] - w[i]
+		}
+		beta := norm(v[0])
+		residual = beta / bNorm
+		if residual <= c.Tolerance {
+			return
+		}
+		for i := range v[0] {
+			v[0][i] /= beta
+		}
+		for i := range g {
+			g[i] = 0.0
+		}
+		g[0] = beta
+
+		k := 0
+		for ; k < m && iterations < c.MaxIterations; k++ {
+			iterations++
+			c.Preconditioner(v[k], z[k])
+			a(z[k], w)
+
+			// modified Gram-Schmidt
+			for j := 0; j <= k; j++ {
+				h[j][k] = dot(w, v[j])
+				for i := range w {
+					w[i] -= h[j][k] * v[j][i]
+				}
+			}
+			h[k+1][k] = norm(w)
+			if h[k+1][k] != 0.0 {
+				for i := range w {
+					v[k+1][i] = w[i] / h[k+1][k]
+				}
+			}
+
+			// apply the previous rotations and compute the next one
+			for j := 0; j < k; j++ {
+				h[j][k], h[j+1][k] = cs[j]*h[j][k]+sn[j]*h[j+1][k], -sn[j]*h[j][k]+cs[j]*h[j+1][k]
+			}
+			r := math.Hypot(h[k][k], h[k+1][k])
+			if r == 0.0 {
+				cs[k], sn[k] = 1.0, 0.0
+			} else {
+				cs[k], sn[k] = h[k][k]/r, h[k+1][k]/r
+			}
+			h[k][k], h[k+1][k] = r, 0.0
+			g[k], g[k+1] = cs[k]*g[k], -sn[k]*g[k]
+
+			residual = math.Abs(g[k+1]) / bNorm
+			if residual <= c.Tolerance || h[k][k] == 0.0 {
+				k++
+				break
+			}
+		}
+
+		// x += Z y with the least squares solution y of H y = g
+		y := g[:k]
+		for j := k - 1; j >= 0; j-- {
+			for l := j + 1; l < k; l++ {
+				y[j] -= h[j][l] * y[l]
+			}
+			if h[j][j] != 0.0 {
+				y[j] /= h[j][j]
+			}
+		}
+		for j := 0; j < k; j++ {
+			for i := range x {
+				x[i] += y[j] * z[j][i]
+			}
+		}
+
+		if residual <= c.Tolerance {
+			return
+		}
+	}
+
+	err = &ConvergenceError{Iterations: iterations, Residual: residual}
+	return
+}
+
+// BiCGStab solves A x = b with the stabilized biconjugate gradient method.
+// x holds the initial guess and the solution on return
+func BiCGStab(a LinearOperator, b, x []float64, config *IterativeConfig) (iterations int, err error) {
+	n := len(b)
+	if len(x) != n {
+		return 0, &DimensionError{Expected: n, Actual: len(x)}
+	}
+	c := config.withDefaults(n)
+
+	bNorm := norm(b)
+	if bNorm == 0.0 {
+		for i := range x {
+			x[i] = 0.0
+		}
+		return
+	}
+
+	vectors := c.Workspace.get(8, n)
+	r, rHat, p, v := vectors[0], vectors[1], vectors[2], vectors[3]
+	s, t, pHat, sHat := vectors[4], vectors[5], vectors[6], vectors[7]
+	for i := range p {
+		p[i] = 0.0
+	}
+
+	a(x, v)
+	for i := range r {
+		r[i] = b[i] - v[i]
+		v[i] = 0.0
+	}
+	copy(rHat, r)
+	rho, alpha, omega := 1.0, 1.0, 1.0
+
+	residual := norm(r) / bNorm
+	for residual > c.Tolerance {
+		if iterations >= c.MaxIterations {
+			err = &ConvergenceError{Iterations: iterations, Residual: residual}
+			return
+		}
+		iterations++
+
+		rhoNext := dot(rHat, r)
+		if rhoNext == 0.0 || omega == 0.0 {
+			// breakdown
+			err = &ConvergenceError{Iterations: iterations, Residual: residual}
+			return
+		}
+		beta := (rhoNext / rho) * (alpha / omega)
+		rho = rhoNext
+		for i := range p {
+			p[i] = r[i] + beta*(p[i]-omega*v[i])
+		}
+
+		c.Preconditioner(p, pHat)
+		a(pHat, v)
+		alpha = rho / dot(rHat, v)
+		for i := range s {
+			s[i] = r[i] - alpha*v[i]
+		}
+		if norm(s)/bNorm <= c.Tolerance {
+			for i := range x {
+				x[i] += alpha * pHat[i]
+			}
+			residual = norm(s) / bNorm
+			return
+		}
+
+		c.Preconditioner(s, sHat)
+		a(sHat, t)
+		omega = dot(t, s) / dot(t, t)
+		for i := range x {
+			x[i] += alpha*pHat[i] + omega*sHat[i]
+			r[i] = s[i] - omega*t[i]
+		}
+		residual = norm(r) / bNorm
+	}
+	return
+}
+
+// JacobiPreconditioner returns the preconditioner scaling with the inverse diagonal of a
+func JacobiPreconditioner(a *CSR) Preconditioner {
+	inverse := make([]float64, a.N)
+	a.Diagonal(inverse)
+	for i := range inverse {
+		if inverse[i] != 0.0 {
+			inverse[i] = 1.0 / inverse[i]
+		} else {
+			inverse[i] = 1.0
+		}
+	}
+	return func(r, z_out []float64) {
+		for i := range r {
+			z_out[i] = inverse[i] * r[i]
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package linalg
+
+import (
+	"errors"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+// I - h J for the Jacobian J of the Brusselator on an n x n grid
+func brussMatrix(n uint, h float64) *CSR {
+	bruss := problems.NewBruss2D(n)
+	jacobian := ode.NewSparseMatrix(bruss.Sparsity())
+	bruss.Jacobian(0, bruss.Initialize(), jacobian)
+
+	a := &CSR{N: jacobian.Size(), RowStart: jacobian.RowStart, Columns: jacobian.Columns}
+	a.Values = make([]float64, len(jacobian.Values))
+	for i := 0; i < a.N; i++ {
+		for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+			a.Values[k] = -h * jacobian.Values[k]
+			if a.Columns[k] == i {
+				a.Values[k] += 1.0
+			}
+		}
+	}
+	return a
+}
+
+func rightHandSide(n int) []float64 {
+	b := make([]float64, n)
+	for i := range b {
+		b[i] = math.Sin(float64(i)) + 1.0
+	}
+	return b
+}
+
+// norm of b - A x relative to the norm of b
+func relativeResidual(a LinearOperator, x, b []float64) float64 {
+	ax := make([]float64, len(b))
+	a(x, ax)
+	for i := range ax {
+		ax[i] -= b[i]
+	}
+	return norm(ax) / norm(b)
+}
+
+func TestDenseLU(t *testing.T) {
+	a := brussMatrix(6, 0.1)
+	dense := makeRectangular(a.N, a.N)
+	for i := 0; i < a.N; i++ {
+		for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+			dense[i][a.Columns[k]] += a.Values[k]
+		}
+	}
+
+	var lu LU
+	if err := lu.Factorize(dense); err != nil {
+		t.Fatalf("Factorization failed - %s", err.Error())
+	}
+	b := rightHandSide(a.N)
+	x := append([]float64(nil), b...)
+	lu.Solve(x)
+
+	if residual := relativeResidual(func(x, y []float64) { MulVec(dense, x, y) }, x, b); residual > 1e-12 {
+		t.Errorf("Relative residual %g", residual)
+	}
+
+	singular := [][]float64{{1, 2}, {2, 4}}
+	if err := lu.Factorize(singular); !errors.Is(err, ErrSingular) {
+		t.Errorf("Expected ErrSingular, got %v", err)
+	}
+}
+
+func TestBandedLU(t *testing.T) {
+	// neighbours in y direction are 2 n entries apart
+	const n = 6
+	b := rightHandSide(2 * n * n)
+
+	var lu BandedLU
+	for _, h := range []float64{0.1, 1.0} {
+		a := brussMatrix(n, h)
+		banded := NewBanded(a.N, 2*n, 2*n)
+		for i := 0; i < a.N; i++ {
+			for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+				banded.Set(i, a.Columns[k], banded.At(i, a.Columns[k])+a.Values[k])
+			}
+		}
+
+		if err := lu.Factorize(banded); err != nil {
+			t.Fatalf("Factorization failed - %s", err.Error())
+		}
+		x := append([]float64(nil), b...)
+		lu.Solve(x)
+
+		if residual := relativeResidual(a.MulVec, x, b); residual > 1e-12 {
+			t.Errorf("h = %g: relative residual %g", h, residual)
+		}
+	}
+}
+
+func TestSparseLU(t *testing.T) {
+	b := rightHandSide(2 * 16 * 16)
+
+	for _, threshold := range []float64{0.1, 1.0} {
+		lu := SparseLU{Threshold: threshold}
+		for _, h := range []float64{0.01, 1.0} {
+			a := brussMatrix(16, h)
+			if err := lu.Factorize(a); err != nil {
+				t.Fatalf("Factorization failed - %s", err.Error())
+			}
+			x := append([]float64(nil), b...)
+			lu.Solve(x)
+
+			if residual := relativeResidual(a.MulVec, x, b); residual > 1e-12 {
+				t.Errorf("threshold %g, h = %g: relative residual %g", threshold, h, residual)
+			}
+			if testing.Verbose() {
+				t.Logf("threshold %g, h = %g: %d entries in A, %d in L and U", threshold, h, len(a.Values), lu.NonZeros())
+			}
+		}
+	}
+
+	// structurally singular
+	singular := &CSR{N: 2, RowStart: []int{0, 1, 2}, Columns: []int{0, 0}, Values: []float64{1, 1}}
+	var lu SparseLU
+	if err := lu.Factorize(singular); !errors.Is(err, ErrSingular) {
+		t.Errorf("Expected ErrSingular, got %v", err)
+	}
+}
+
+func TestIterative(t *testing.T) {
+	a := brussMatrix(16, 0.1)
+	b := rightHandSide(a.N)
+
+	var lu SparseLU
+	if err := lu.Factorize(a); err != nil {
+		t.Fatalf("Factorization failed - %s", err.Error())
+	}
+	exact := func(r, z_out []float64) {
+		copy(z_out, r)
+		lu.Solve(z_out)
+	}
+
+	solvers := []struct {
+		Name   string
+		Solver func(LinearOperator, []float64, []float64, *IterativeConfig) (int, error)
+	}{
+		{"GMRES", GMRES},
+		{"BiCGStab", BiCGStab},
+	}
+	preconditioners := []struct {
+		Name           string
+		Preconditioner Preconditioner
+		MaxIterations  int
+	}{
+		{"none", nil, 0},
+		{"Jacobi", JacobiPreconditioner(a), 0},
+		{"SparseLU", exact, 2},
+	}
+
+	for _, s := range solvers {
+		for _, p := range preconditioners {
+			x := make([]float64, a.N)
+			config := IterativeConfig{Tolerance: 1e-10, Preconditioner: p.Preconditioner}
+			iterations, err := s.Solver(a.MulVec, b, x, &config)
+			if err != nil {
+				t.Fatalf("%s (%s): %s", s.Name, p.Name, err.Error())
+			}
+			if residual := relativeResidual(a.MulVec, x, b); residual > 1e-9 {
+				t.Errorf("%s (%s): relative residual %g", s.Name, p.Name, residual)
+			}
+			if p.MaxIterations > 0 && iterations > p.MaxIterations {
+				t.Errorf("%s (%s): %d iterations with an exact preconditioner", s.Name, p.Name, iterations)
+			}
+			if testing.Verbose() {
+				t.Logf("%s (%s): %d iterations", s.Name, p.Name, iterations)
+			}
+		}
+	}
+
+	// a workspace is reused by repeated solves without allocating
+	var workspace IterativeWorkspace
+	operator := a.MulVec
+	for _, s := range solvers {
+		config := IterativeConfig{Tolerance: 1e-10, Preconditioner: exact, Workspace: &workspace}
+		x := make([]float64, a.N)
+		solve := func() {
+			for i := range x {
+				x[i] = 0.0
+			}
+			if _, err := s.Solver(operator, b, x, &config); err != nil {
+				t.Fatalf("%s with workspace: %s", s.Name, err.Error())
+			}
+		}
+		solve()
+		if allocations := testing.AllocsPerRun(5, solve); allocations > 0 {
+			t.Errorf("%s: %g allocations per solve with a workspace", s.Name, allocations)
+		}
+		if residual := relativeResidual(a.MulVec, x, b); residual > 1e-9 {
+			t.Errorf("%s with workspace: relative residual %g", s.Name, residual)
+		}
+	}
+
+	// too few iterations
+	x := make([]float64, a.N)
+	var convergence *ConvergenceError
+	if _, err := GMRES(a.MulVec, b, x, &IterativeConfig{MaxIterations: 2}); !errors.As(err, &convergence) {
+		t.Errorf("Expected ConvergenceError, got %v", err)
+	}
+}
+
+func TestExpm(t *testing.T) {
+	// rotation generator with a diagonal shift, exp is a scaled rotation
+	for _, angle := range []float64{0.1, 3.0, 40.0} {
+		a := [][]float64{{-1.0, -angle}, {angle, -1.0}}
+		exp := makeRectangular(2, 2)
+		if err := Expm(a, exp); err != nil {
+			t.Fatalf("Expm failed - %s", err.Error())
+		}
+
+		c, s := math.Exp(-1.0)*math.Cos(angle), math.Exp(-1.0)*math.Sin(angle)
+		expected := [][]float64{{c, -s}, {s, c}}
+		for i := range expected {
+			for j := range expected[i] {
+				if math.Abs(exp[i][j]-expected[i][j]) > 1e-12 {
+					t.Errorf("exp(A)[%d][%d] = %g for angle %g, expected %g", i, j, exp[i][j], angle, expected[i][j])
+				}
+			}
+		}
+	}
+
+	var dimension *DimensionError
+	if err := Expm([][]float64{{1, 2}}, makeRectangular(1, 1)); !errors.As(err, &dimension) {
+		t.Errorf("Expected DimensionError, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package linalg
+
+import (
+	"container/heap"
+	"math"
+)
+
+// CSR is a square sparse matrix in compressed sparse row format: the columns
+// of row i are Columns[RowStart[i]:RowStart[i+1]], the entries Values[RowStart[i]:RowStart[i+1]]
+type CSR struct {
+	N        int
+	RowStart []int
+	Columns  []int
+	Values   []float64
+}
+
+// MulVec computes y_out = a x
+func (a *CSR) MulVec(x, y_out []float64) {
+	for i := 0; i < a.N; i++ {
+		sum := 0.0
+		for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+			sum += a.Values[k] * x[a.Columns[k]]
+		}
+		y_out[i] = sum
+	}
+}
+
+// Diagonal writes the diagonal entries of a into d_out
+func (a *CSR) Diagonal(d_out []float64) {
+	for i := 0; i < a.N; i++ {
+		d_out[i] = 0.0
+		for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+			if a.Columns[k] == i {
+				d_out[i] += a.Values[k]
+			}
+		}
+	}
+}
+
+// SparseLU is the LU decomposition of a sparse matrix computed row by row
+// with threshold partial pivoting over the columns: a Q = L U.
+// A SparseLU can be factorized again, reusing its storage
+type SparseLU struct {
+	// Threshold in (0, 1] is the relative size a diagonal entry needs to be chosen as pivot,
+	// 1 is classic partial pivoting, smaller values preserve the sparsity better. Default 0.1
+	Threshold float64
+
+	n int
+	// rows of L, positions refer to elimination steps
+	lStart     []int
+	lPositions []int
+	lValues    []float64
+	// rows of U without the pivot, columns refer to the original matrix
+	uStart   []int
+	uColumns []int
+	uValues  []float64
+	pivots   []float64
+	// column eliminated in each step and its inverse
+	order, position []int
+
+	// dense work row and the positions of its nonzero entries
+	work    []float64
+	nonzero []bool
+	pattern []int
+	pending positionHeap
+}
+
+// Factorize computes the decomposition of a, a is not modified
+func (f *SparseLU) Factorize(a *CSR) error {
+	n := a.N
+	if f.n != n || f.work == nil {
+		f.n = n
+		f.lStart, f.uStart = make([]int, n+1), make([]int, n+1)
+		f.pivots = make([]float64, n)
+		f.order, f.position = make([]int, n), make([]int, n)
+		f.work, f.nonzero = make([]float64, n), make([]bool, n)
+	}
+	threshold := f.Threshold
+	if threshold <= 0.0 || threshold > 1.0 {
+		threshold = 0.1
+	}
+
+	f.lPositions, f.lValues = f.lPositions[:0], f.lValues[:0]
+	f.uColumns, f.uValues = f.uColumns[:0], f.uValues[:0]
+	for j := 0; j < n; j++ {
+		f.order[j], f.position[j] = j, j
+	}
+
+	for i := 0; i < n; i++ {
+		// scatter row i
+		f.pattern = f.pattern[:0]
+		f.pending.positions = f.pending.positions[:0]
+		for k := a.RowStart[i]; k < a.RowStart[i+1]; k++ {
+			f.add(a.Columns[k], a.Values[k], i)
+		}
+
+		// eliminate the entries in already pivoted columns in the order of elimination
+		for f.pending.Len() > 0 {
+			step := heap.Pop(&f.pending).(int)
+			column := f.order[step]
+			factor := f.work[column] / f.pivots[step]
+			f.work[column] = 0.0
+			f.nonzero[column] = false
+			if factor == 0.0 {
+				continue
+			}
+			f.lPositions = append(f.lPositions, step)
+			f.lValues = append(f.lValues, factor)
+			for k := f.uStart[step]; k < f.uStart[step+1]; k++ {
+				f.add(f.uColumns[k], -factor*f.uValues[k], i)
+			}
+		}
+		f.lStart[i+1] = len(f.lPositions)
+
+		// choose the pivot among the remaining columns, prefer the diagonal
+		diagonal, largest := f.order[i], -1
+		for _, column := range f.pattern {
+			if f.nonzero[column] && (largest < 0 || math.Abs(f.work[column]) > math.Abs(f.work[largest])) {
+				largest = column
+			}
+		}
+		if largest < 0 || f.work[largest] == 0.0 {
+			f.clear()
+			return &SingularError{Step: i}
+		}
+		pivot := largest
+		if f.nonzero[diagonal] && math.Abs(f.work[diagonal]) >= threshold*math.Abs(f.work[largest]) {
+			pivot = diagonal
+		}
+
+		// exchange the elimination steps of the diagonal and the pivot column
+		other := f.position[pivot]
+		f.order[i], f.order[other] = pivot, diagonal
+		f.position[pivot], f.position[diagonal] = i, other
+
+		f.pivots[i] = f.work[pivot]
+		for _, column := range f.pattern {
+			if f.nonzero[column] && column != pivot {
+				f.uColumns = append(f.uColumns, column)
+				f.uValues = append(f.uValues, f.work[column])
+			}
+		}
+		f.uStart[i+1] = len(f.uColumns)
+		f.clear()
+	}
+	return nil
+}
+
+// adds value to the work row, columns pivoted before step are scheduled for elimination
+func (f *SparseLU) add(column int, value float64, step int) {
+	if !f.nonzero[column] {
+		f.nonzero[column] = true
+		f.pattern = append(f.pattern, column)
+		if f.position[column] < step {
+			heap.Push(&f.pending, f.position[column])
+		}
+	}
+	f.work[column] += value
+}
+
+func (f *SparseLU) clear() {
+	for _, column := range f.pattern {
+		f.work[column] = 0.0
+		f.nonzero[column] = false
+	}
+}
+
+// NonZeros returns the number of entries of L and U, a measure of the fill-in
+func (f *SparseLU) NonZeros() int {
+	return len(f.lValues) + len(f.uValues) + f.n
+}
+
+// Solve overwrites b with the solution of a x = b for the last factorized matrix a
+func (f *SparseLU) Solve(b []float64) {
+	n := f.n
+
+	// L y = b
+	for i := 0; i < n; i++ {
+		for k := f.lStart[i]; k < f.lStart[i+1]; k++ {
+			b[i] -= f.lValues[k] * b[f.lPositions[k]]
+		}
+	}
+
+	// U z = y, z is indexed by elimination step
+	for i := n - 1; i >= 0; i-- {
+		for k := f.uStart[i]; k < f.uStart[i+1]; k++ {
+			b[i] -= f.uValues[k] * b[f.position[f.uColumns[k]]]
+		}
+		b[i] /= f.pivots[i]
+	}
+
+	// x = Q z
+	copy(f.work, b)
+	for i := 0; i < n; i++ {
+		b[f.order[i]] = f.work[i]
+	}
+	for i := range f.work {
+		f.work[i] = 0.0
+	}
+}
+
+// min heap of elimination steps
+type positionHeap struct {
+	positions []int
+}
+
+func (h *positionHeap) Len() int           { return len(h.positions) }
+func (h *positionHeap) Less(i, j int) bool { return h.positions[i] < h.positions[j] }
+func (h *positionHeap) Swap(i, j int) {
+	h.positions[i], h.positions[j] = h.positions[j], h.positions[i]
+}
+func (h *positionHeap) Push(x interface{}) { h.positions = append(h.positions, x.(int)) }
+func (h *positionHeap) Pop() interface{} {
+	last := h.positions[len(h.positions)-1]
+	h.positions = h.positions[:len(h.positions)-1]
+	return last
+}
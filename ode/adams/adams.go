@@ -0,0 +1,412 @@
+package adams
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+// GenericAdams integrates with variable step, variable order Adams methods over the
+// floating point type T. Time and the error control stay float64 as in rk.GenericRK,
+// the interpolation nodes are computed from the times at the precision of T
+type GenericAdams[T precision.Real[T]] struct {
+	IntegratorInfo
+	method AdamsMethod
+	// Gauss-Legendre rule at the precision of the zero value of T
+	gauss *gaussRule[T]
+}
+
+// adams is the float64 integrator, it computes with ad.Float on the memory of the caller
+type adams struct {
+	generic *GenericAdams[ad.Float]
+}
+
+// evaluation provides the right hand side and the starting values to the integration
+type evaluation[T precision.Real[T]] struct {
+	fcn, blocked ad.Fcn[T]
+	// start computes the solution at each of the times from yT at t, as Starter.Start
+	start func(t float64, yT []T, times []float64, values [][]T, c *Config) (Statistics, error)
+}
+
+type integration[T precision.Real[T]] struct {
+	Config
+	Statistics
+	evaluation[T]
+	n     uint
+	gauss *gaussRule[T]
+
+	// order of the predictor, the corrector has order+1
+	order int
+	// number of valid entries in the history
+	historyCount int
+	// past points and evaluations, most recent first
+	tHistory []T
+	fHistory [][]T
+
+	yPredicted, yCorrected, yLower, fPredicted, yError []T
+	nodes, weights                                     []T
+}
+
+func (a *adams) Info() IntegratorInfo {
+	return a.generic.Info()
+}
+
+func (a *adams) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	return a.generic.integrate(t, tEnd, precision.Float64s(yT), config, func(c *Config) evaluation[ad.Float] {
+		fcn := func(t float64, yT, dy_out []ad.Float) {
+			c.Fcn(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+		}
+		blocked := func(t float64, yT, dy_out []ad.Float) {
+			c.EvaluateBlocked(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+		}
+		start := func(t float64, yT []ad.Float, times []float64, values [][]ad.Float, c *Config) (Statistics, error) {
+			starter := c.Starter
+			if starter == nil {
+				dopri, err := rk.NewRK(rk.DoPri5)
+				if err != nil {
+					return Statistics{}, err
+				}
+				starter = &IntegratorStarter{Integrator: dopri}
+			}
+			rows := make([][]float64, len(values))
+			for i := range rows {
+				rows[i] = precision.ToFloat64(values[i])
+			}
+			return starter.Start(t, precision.ToFloat64(yT), times, rows, c)
+		}
+		return evaluation[ad.Float]{fcn: fcn, blocked: blocked, start: start}
+	})
+}
+
+// Integrate advances yT from t to tEnd with the right hand side fcn, the starting values
+// are computed with rk.GenericRK, Fcn, FcnBlocked, BlockSize and Starter of config are ignored
+func (a *GenericAdams[T]) Integrate(t, tEnd float64, yT []T, fcn ad.Fcn[T], config *Config) (stat Statistics, err error) {
+	if fcn == nil {
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+	if config == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+
+	var prototype T
+	if len(yT) > 0 {
+		prototype = yT[0]
+	}
+	adapted := *config
+	adapted.Fcn, adapted.FcnBlocked, adapted.BlockSize = ad.Function(fcn, prototype), nil, 0
+	adapted.Starter = nil
+	return a.integrate(t, tEnd, yT, &adapted, func(c *Config) evaluation[T] {
+		start := func(t float64, yT []T, times []float64, values [][]T, c *Config) (stat Statistics, err error) {
+			dopri, err := rk.NewGenericRK[T](rk.DoPri5)
+			if err != nil {
+				return
+			}
+			// the tolerances of IntegratorStarter without its float64 limit
+			startConfig := Config{RelativeTolerance: 0.1 * c.RelativeTolerance, AbsoluteTolerance: 0.1 * c.AbsoluteTolerance, NonFinitePolicy: c.NonFinitePolicy,
+				MinStepSize: c.MinStepSize, MaxStepSize: c.MaxStepSize, MaxStepCount: c.MaxStepCount, Cancel: c.Cancel}
+			for i, tValue := range times {
+				copy(values[i], yT)
+				startConfig.InitialStepSize = math.Min(tValue-t, c.MaxStepSize)
+				var startStat Statistics
+				startStat, err = dopri.Integrate(t, tValue, values[i], fcn, &startConfig)
+				stat.StepCount += startStat.StepCount
+				stat.RejectedCount += startStat.RejectedCount
+				stat.EvaluationCount += startStat.EvaluationCount
+				if err != nil {
+					return
+				}
+			}
+			return
+		}
+		return evaluation[T]{fcn: fcn, blocked: fcn, start: start}
+	})
+}
+
+// performs variable step, variable order Adams-Bashforth-Moulton integration
+func (a *GenericAdams[T]) integrate(t, tEnd float64, yT []T, config *Config, evaluator func(c *Config) evaluation[T]) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = a.Supports(&effective); err != nil {
+		return
+	}
+
+	in := a.setupIntegration(yT, &effective)
+	in.evaluation = evaluator(&in.Config)
+
+	tCurrent, stepNext, err := a.startupIntegration(&in, t, tEnd, yT)
+	if err != nil {
+		in.Effective = in.Config
+		stat = in.Statistics
+		return
+	}
+	stepEstimate := stepNext
+	// the time and the step size at the precision of T
+	tCurrentT, tEndT := in.tHistory[0], yT[0].Const(tEnd)
+	var step T
+
+	// repeat until tend
+	for tCurrent < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: tCurrent}
+			break
+		}
+
+		stepNext = stepEstimate
+		if tCurrent+stepNext > tEnd {
+			stepNext = tEnd - tCurrent
+			step = tEndT.Sub(tCurrentT)
+		} else {
+			step = yT[0].Const(stepNext)
+		}
+		in.StepCount++
+		k := in.order
+
+		// predict, evaluate
+		a.predict(&in, k, tCurrentT, step, yT, in.yPredicted)
+		in.blocked(tCurrent+stepNext, in.yPredicted, in.fPredicted)
+		in.EvaluationCount++
+
+		// correct
+		a.correct(&in, k, tCurrentT, step, yT)
+
+		// error estimates for the current and the neighbouring orders
+		errorCurrent := a.predictorError(&in, k, tCurrentT, step, yT)
+		if !util.IsFinite(errorCurrent) {
+			if in.NonFinitePolicy == AbortOnNonFinite {
+				index := util.FirstNonFinite(precision.ToFloat64(in.fPredicted))
+				if index < 0 {
+					index = util.FirstNonFinite(precision.ToFloat64(in.yCorrected))
+				}
+				err = &NonFiniteError{Time: tCurrent, StepSize: stepNext, Index: index}
+				break
+			}
+			errorCurrent = math.Inf(1)
+		}
+		factorCurrent := stepFactor(errorCurrent, k)
+
+		factorLower := 0.0
+		if k > 1 {
+			factorLower = stepFactor(a.predictorError(&in, k-1, tCurrentT, step, yT), k-1)
+		}
+
+		if errorCurrent > 1.0 {
+			// reject step
+			in.RejectedCount++
+			stepEstimate = stepNext * factorCurrent
+			if factorLower > factorCurrent {
+				in.order--
+				stepEstimate = stepNext * factorLower
+			}
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: tCurrent, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			factorHigher := 0.0
+			if k < maxOrder && in.historyCount > k {
+				factorHigher = stepFactor(a.predictorError(&in, k+1, tCurrentT, step, yT), k+1)
+			}
+
+			// accept step
+			tCurrent += stepNext
+			tCurrentT = tCurrentT.Add(step)
+			copy(yT, in.yCorrected)
+			a.pushHistory(&in, tCurrent, tCurrentT, yT)
+
+			// select the order allowing the largest next step
+			stepEstimate = stepNext * factorCurrent
+			if factorLower > factorCurrent && factorLower >= factorHigher {
+				in.order--
+				stepEstimate = stepNext * factorLower
+			} else if factorHigher > factorCurrent {
+				in.order++
+				stepEstimate = stepNext * factorHigher
+			}
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: tCurrent, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = tCurrent
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (a *GenericAdams[T]) setupIntegration(yT []T, c *Config) (i integration[T]) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// round the Gauss rule again if the initial values differ in precision from the zero value
+	i.gauss = a.gauss
+	if yT[0].Precision() != i.gauss.nodes[0].Precision() {
+		i.gauss = newGaussRule(yT[0])
+	}
+
+	// allocate temp matrices
+	i.tHistory = make([]T, maxOrder)
+	i.fHistory = make([][]T, maxOrder)
+	for j := range i.fHistory {
+		i.fHistory[j] = make([]T, i.n)
+	}
+	i.yPredicted = make([]T, i.n)
+	i.yCorrected = make([]T, i.n)
+	i.yLower = make([]T, i.n)
+	i.fPredicted = make([]T, i.n)
+	i.yError = make([]T, i.n)
+	i.nodes = make([]T, maxOrder+1)
+	i.weights = make([]T, maxOrder+1)
+
+	return
+}
+
+// computes the starting values with the configured Starter (default DOPRI)
+// and leaves yT at the last starting point
+func (a *GenericAdams[T]) startupIntegration(in *integration[T], t0, tEnd float64, yT []T) (tCurrent, step float64, err error) {
+	f0 := in.fHistory[startOrder-1]
+	in.fcn(t0, yT, f0)
+	in.EvaluationCount = 1
+
+	// guess initial step size if unspecified
+	step = in.InitialStepSize
+	if step <= 0.0 {
+		step = EstimateStepSize(t0, precision.ToFloat64(yT), precision.ToFloat64(f0), &in.Config, startOrder)
+	}
+	// leave room for at least one step after the starting procedure
+	step = math.Min(step, (tEnd-t0)/startOrder)
+
+	times := make([]float64, startOrder-1)
+	values := make([][]T, startOrder-1)
+	for i := range times {
+		times[i] = t0 + float64(i+1)*step
+		values[i] = make([]T, in.n)
+	}
+
+	startStat, err := in.start(t0, yT, times, values, &in.Config)
+	in.EvaluationCount += startStat.EvaluationCount
+	if err != nil {
+		err = &StartupError{Err: err}
+		return
+	}
+
+	// history is stored most recent first
+	in.tHistory[startOrder-1] = yT[0].Const(t0)
+	for i := range times {
+		j := startOrder - 2 - i
+		in.tHistory[j] = yT[0].Const(times[i])
+		in.fcn(times[i], values[i], in.fHistory[j])
+		in.EvaluationCount++
+	}
+	in.StartupEvaluationCount = in.EvaluationCount
+
+	in.order, in.historyCount = startOrder, startOrder
+	tCurrent = times[len(times)-1]
+	copy(yT, values[len(values)-1])
+	return
+}
+
+// computes the Adams-Bashforth predictor of order k
+func (a *GenericAdams[T]) predict(in *integration[T], k int, tCurrent, step T, yT, y_out []T) {
+	nodes, weights := in.nodes[:k], in.weights[:k]
+	for j := range nodes {
+		nodes[j] = in.tHistory[j].Sub(tCurrent).Div(step)
+	}
+	in.gauss.integrationWeights(nodes, weights)
+
+	copy(y_out, yT)
+	for j := range weights {
+		hw := step.Mul(weights[j])
+		for id := range y_out {
+			y_out[id] = y_out[id].Add(hw.Mul(in.fHistory[j][id]))
+		}
+	}
+}
+
+// computes the Adams-Moulton corrector of order k+1 using the evaluation of the predictor
+func (a *GenericAdams[T]) correct(in *integration[T], k int, tCurrent, step T, yT []T) {
+	nodes, weights := in.nodes[:k+1], in.weights[:k+1]
+	nodes[0] = step.Const(1.0)
+	for j := 0; j < k; j++ {
+		nodes[j+1] = in.tHistory[j].Sub(tCurrent).Div(step)
+	}
+	in.gauss.integrationWeights(nodes, weights)
+
+	hw := step.Mul(weights[0])
+	for id := range in.yCorrected {
+		in.yCorrected[id] = yT[id].Add(hw.Mul(in.fPredicted[id]))
+	}
+	for j := 0; j < k; j++ {
+		hw = step.Mul(weights[j+1])
+		for id := range in.yCorrected {
+			in.yCorrected[id] = in.yCorrected[id].Add(hw.Mul(in.fHistory[j][id]))
+		}
+	}
+}
+
+// estimates the local error of the order k predictor by its distance to the corrector
+func (a *GenericAdams[T]) predictorError(in *integration[T], k int, tCurrent, step T, yT []T) float64 {
+	yPredicted := in.yPredicted
+	if k != in.order {
+		yPredicted = in.yLower
+		a.predict(in, k, tCurrent, step, yT, yPredicted)
+	}
+
+	for id := range in.yError {
+		in.yError[id] = in.yCorrected[id].Sub(yPredicted[id])
+	}
+	return in.ErrorNorm(precision.ToFloat64(in.yError), precision.ToFloat64(yT), precision.ToFloat64(in.yCorrected))
+}
+
+// adds the accepted point to the history, evaluating it if necessary
+func (a *GenericAdams[T]) pushHistory(in *integration[T], tCurrent float64, tCurrentT T, yT []T) {
+	// rotate, the oldest row is reused for the new point
+	oldest := in.fHistory[maxOrder-1]
+	copy(in.fHistory[1:], in.fHistory[:maxOrder-1])
+	copy(in.tHistory[1:], in.tHistory[:maxOrder-1])
+	in.fHistory[0] = oldest
+
+	in.tHistory[0] = tCurrentT
+	if a.method == PECE {
+		in.blocked(tCurrent, yT, in.fHistory[0])
+		in.EvaluationCount++
+	} else {
+		copy(in.fHistory[0], in.fPredicted)
+	}
+
+	if in.historyCount < maxOrder {
+		in.historyCount++
+	}
+}
+
+// step size factor for an error estimate of a method of order k
+func stepFactor(errorEstimate float64, k int) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -1.0/float64(k+1))
+	return math.Max(0.2, math.Min(factor, 2.0)) // safety interval
+}
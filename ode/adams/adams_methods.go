@@ -0,0 +1,120 @@
+package adams
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
+)
+
+type AdamsMethod uint
+
+const (
+	PECE                 = AdamsMethod(iota) // predict, evaluate, correct, evaluate
+	PEC                                      // predict, evaluate, correct: one evaluation per step
+	NumberOfAdamsMethods = uint(iota)
+)
+
+const (
+	// maximal order of the predictor, the corrector is one order higher
+	maxOrder = 8
+	// order of the predictor after the starting procedure
+	startOrder = 4
+)
+
+// 6 point Gauss-Legendre rule on [-1, 1], exact up to degree 11
+// used to integrate the interpolation polynomials
+var gaussNodes = []float64{
+	-0.9324695142031521, -0.6612093864662645, -0.2386191860831969,
+	0.2386191860831969, 0.6612093864662645, 0.9324695142031521,
+}
+var gaussWeights = []float64{
+	0.1713244923791704, 0.3607615730481386, 0.4679139345726910,
+	0.4679139345726910, 0.3607615730481386, 0.1713244923791704,
+}
+
+// gaussRule is the Gauss-Legendre rule mapped to [0, 1] at the precision of T
+type gaussRule[T precision.Real[T]] struct {
+	nodes, weights []T
+}
+
+// newGaussRule rounds the rule to the precision of prototype, beyond float64 the nodes
+// are refined by Newton steps on the Legendre polynomial
+func newGaussRule[T precision.Real[T]](prototype T) *gaussRule[T] {
+	one, two := prototype.Const(1.0), prototype.Const(2.0)
+	rule := &gaussRule[T]{nodes: make([]T, len(gaussNodes)), weights: make([]T, len(gaussNodes))}
+	for q := range gaussNodes {
+		x, w := prototype.Const(gaussNodes[q]), prototype.Const(gaussWeights[q])
+		if prototype.Precision() > 53 {
+			for bits := uint(50); bits < prototype.Precision()+8; bits *= 2 {
+				p, dp := legendre(x, len(gaussNodes))
+				x = x.Sub(p.Div(dp))
+			}
+			// w = 2 / ((1 - x^2) P'(x)^2)
+			_, dp := legendre(x, len(gaussNodes))
+			w = two.Div(one.Sub(x.Mul(x)).Mul(dp.Mul(dp)))
+		}
+		rule.nodes[q], rule.weights[q] = x.Add(one).Scale(0.5), w.Scale(0.5)
+	}
+	return rule
+}
+
+// legendre evaluates the Legendre polynomial P_n and its derivative at x
+func legendre[T precision.Real[T]](x T, n int) (p, dp T) {
+	previous, p := x.Const(1.0), x
+	for k := 1; k < n; k++ {
+		// (k + 1) P_k+1 = (2k + 1) x P_k - k P_k-1
+		previous, p = p, x.Mul(p).Scale(float64(2*k+1)).Sub(previous.Scale(float64(k))).Div(x.Const(float64(k+1)))
+	}
+	// (x^2 - 1) P_n' = n (x P_n - P_n-1)
+	dp = x.Mul(p).Sub(previous).Scale(float64(n)).Div(x.Mul(x).Sub(x.Const(1.0)))
+	return
+}
+
+// NewGenericAdams creates the integrator of method m over the floating point type T
+func NewGenericAdams[T precision.Real[T]](m AdamsMethod) (a *GenericAdams[T], err error) {
+	a = &GenericAdams[T]{method: m}
+	a.Order = maxOrder
+
+	switch m {
+	case PECE:
+		a.Name = "AdamsPECE"
+		a.Stages = 2
+	case PEC:
+		a.Name = "AdamsPEC"
+		a.Stages = 1
+	default:
+		err = &ode.ConfigError{Field: "AdamsMethod", Reason: "unknown adams method"}
+		return
+	}
+
+	var zero T
+	a.gauss = newGaussRule(zero)
+	return
+}
+
+// NewAdams creates the integrator of method m for float64 values
+func NewAdams(m AdamsMethod) (i ode.Integrator, err error) {
+	generic, err := NewGenericAdams[ad.Float](m)
+	i = &adams{generic: generic}
+	return
+}
+
+// computes weights w, such that h * sum w_j f(nodes_j) integrates the polynomial
+// interpolating f in nodes over [0, 1].
+// nodes are given relative to the current time and scaled by the step size h
+func (rule *gaussRule[T]) integrationWeights(nodes []T, w []T) {
+	for j := range nodes {
+		w[j] = nodes[j].Const(0.0)
+	}
+	for q, x := range rule.nodes {
+		for j := range nodes {
+			basis := x.Const(1.0)
+			for m := range nodes {
+				if m != j {
+					basis = basis.Mul(x.Sub(nodes[m]).Div(nodes[j].Sub(nodes[m])))
+				}
+			}
+			w[j] = w[j].Add(rule.weights[q].Mul(basis))
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package adams
+
+import (
+	"errors"
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+func TestAllAdams(t *testing.T) {
+	integrators := make([]Integrator, NumberOfAdamsMethods)
+	for j := 0; j < int(NumberOfAdamsMethods); j++ {
+		a, err := NewAdams(AdamsMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create Adams Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = a
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestAdamsExponential(t *testing.T) {
+	for j := 0; j < int(NumberOfAdamsMethods); j++ {
+		adams, _ := NewAdams(AdamsMethod(j))
+
+		y := []float64{1.0}
+		config := Config{
+			Fcn: func(t float64, yT []float64, dy_out []float64) {
+				dy_out[0] = -yT[0]
+			},
+			AbsoluteTolerance: 1e-10,
+		}
+
+		stat, err := adams.Integrate(0, 5, y, &config)
+
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", adams.Info().Name, err.Error())
+		}
+		if !util.EpsEqual(y[0], math.Exp(-5), 1e-8) {
+			t.Errorf("%s: Expected %g but result was %g", adams.Info().Name, math.Exp(-5), y[0])
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations (%d startup)",
+				adams.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.StartupEvaluationCount)
+		}
+	}
+}
+
+func TestAdamsMBody4h(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	mbody := problems.NewMBody(4)
+	reference := mbody.Initialize()
+
+	_, err := dopri.Integrate(0, 5, reference, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-10})
+	if err != nil {
+		t.Fatalf("Reference integration failed - %s", err.Error())
+	}
+
+	for j := 0; j < int(NumberOfAdamsMethods); j++ {
+		adams, _ := NewAdams(AdamsMethod(j))
+		instance := mbody.Initialize()
+
+		config := Config{
+			Fcn:               mbody.Fcn,
+			AbsoluteTolerance: 1.e-8,
+			RelativeTolerance: 1.e-8,
+		}
+
+		stat, err := adams.Integrate(0, 5, instance, &config)
+
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", adams.Info().Name, err.Error())
+		}
+		for i := range instance {
+			if !util.EpsEqual(instance[i], reference[i], 1e-4) {
+				t.Fatalf("%s: result[%d] = %g differs from DoPri5 result %g", adams.Info().Name, i, instance[i], reference[i])
+			}
+		}
+
+		if testing.Verbose() {
+			t.Logf("MBody %s: %d steps, %d rejected, %d evaluations", adams.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestGenericAdams(t *testing.T) {
+	// y' = -y, y(0) = 1 on [0, 1] beyond float64 precision
+	decay := func(t float64, yT []precision.Big, dy_out []precision.Big) { dy_out[0] = yT[0].Neg() }
+	exact := precision.NewBig(-1.0, 200).Exp()
+	for j := 0; j < int(NumberOfAdamsMethods); j++ {
+		adams, _ := NewGenericAdams[precision.Big](AdamsMethod(j))
+		y := []precision.Big{precision.NewBig(1.0, 200)}
+		stat, err := adams.Integrate(0, 1, y, decay, &Config{AbsoluteTolerance: 1e-24, RelativeTolerance: 1e-24})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", adams.Info().Name, err.Error())
+		}
+		if e := math.Abs(y[0].Sub(exact).Real()); e > 1e-22 || y[0].Precision() != 200 {
+			t.Errorf("%s: error %g with precision %d", adams.Info().Name, e, y[0].Precision())
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations", adams.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestGenericAdamsFloat(t *testing.T) {
+	// the float64 API and the generic integrator over ad.Float with the generic right hand side agree
+	mbody := problems.NewMBody(4)
+	masses := mbody.(problems.ParametricProblem).Parameters()
+	for j := 0; j < int(NumberOfAdamsMethods); j++ {
+		adams, _ := NewAdams(AdamsMethod(j))
+		generic, _ := NewGenericAdams[ad.Float](AdamsMethod(j))
+
+		y := mbody.Initialize()
+		stat, err := adams.Integrate(0, 1, y, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-8, RelativeTolerance: 1e-8})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", adams.Info().Name, err.Error())
+		}
+		yGeneric := precision.Float64s(mbody.Initialize())
+		genericStat, err := generic.Integrate(0, 1, yGeneric, ad.Fix(problems.MBodyFcn[ad.Float](4), masses), &Config{AbsoluteTolerance: 1e-8, RelativeTolerance: 1e-8})
+		if err != nil {
+			t.Fatalf("%s: Generic integration failed - %s", adams.Info().Name, err.Error())
+		}
+		if stat.StepCount != genericStat.StepCount || stat.EvaluationCount != genericStat.EvaluationCount {
+			t.Errorf("%s: Generic integration took %d steps, %d evaluations instead of %d, %d", adams.Info().Name,
+				genericStat.StepCount, genericStat.EvaluationCount, stat.StepCount, stat.EvaluationCount)
+		}
+		for i := range y {
+			if math.Abs(y[i]-float64(yGeneric[i])) > 1e-10 {
+				t.Fatalf("%s: result[%d] = %g, generic %g", adams.Info().Name, i, y[i], yGeneric[i])
+			}
+		}
+	}
+
+	if _, err := NewGenericAdams[ad.Float](AdamsMethod(NumberOfAdamsMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown method not reported, got %v", err)
+	}
+}
+
+func TestGaussRule(t *testing.T) {
+	// the refined rule integrates x^11 over [0, 1] exactly
+	rule := newGaussRule(precision.NewBig(0.0, 300))
+	sum := precision.NewBig(0.0, 300)
+	for q, x := range rule.nodes {
+		sum = sum.Add(rule.weights[q].Mul(x.Pow(11)))
+	}
+	if e := math.Abs(sum.Sub(precision.NewBig(1.0, 300).Div(precision.NewBig(12.0, 300))).Real()); e > 1e-85 {
+		t.Errorf("Integral of x^11 has error %g", e)
+	}
+}
@@ -0,0 +1,359 @@
+package bdf
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/internal/linear"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type bdf struct {
+	IntegratorInfo
+	method BDFMethod
+
+	kappa, gamma, alpha []float64
+	errorConst          []float64
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	// Jacobian and decomposition of I - c J, the Jacobian is kept until the Newton iteration fails
+	system        *linear.System
+	factorized    bool
+	jacobianFresh bool
+
+	order, equalSteps int
+	// backward differences of the solution scaled with the step size, the first
+	// order+1 rows define the interpolation polynomial of the last steps
+	differences [][]float64
+	changed     [][]float64
+
+	f0, predict, psi, z, correction, f, delta, yError []float64
+}
+
+// performs variable step size, variable order integration with the backward difference form of
+// the BDF or NDF, the implicit equations are solved by a simplified Newton iteration
+func (s *bdf) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+	newtonTolerance := math.Max(10.0*1.1e-16/in.RelativeTolerance, math.Min(0.03, math.Sqrt(in.RelativeTolerance)))
+
+	in.EvaluateBlocked(t, yT, in.f0)
+	in.EvaluationCount = 1
+
+	// compute initial step size if not set
+	step := in.InitialStepSize
+	if step <= 0.0 {
+		step = EstimateStepSize(t, yT, in.f0, &in.Config, 1)
+	}
+	step = math.Min(step, in.MaxStepSize)
+
+	in.order = 1
+	copy(in.differences[0], yT)
+	for id := range yT {
+		in.differences[1][id] = step * in.f0[id]
+	}
+
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		if t+step > tEnd {
+			s.changeDifferences(&in, (tEnd-t)/step)
+			step = tEnd - t
+			in.factorized, in.equalSteps = false, 0
+		}
+		stepNext = step
+		tNew := t + step
+		in.StepCount++
+
+		s.predict(&in, step)
+		if in.system == nil {
+			in.system = linear.NewSystem(&in.Config, n)
+			in.EvaluationCount += in.system.ComputeJacobian(&in.Config, t, yT, in.f0)
+			in.jacobianFresh = true
+		}
+
+		// retry with a fresh Jacobian if the iteration fails with an old one
+		converged, iterations := s.newton(&in, tNew, step, newtonTolerance)
+		if !converged && !in.jacobianFresh {
+			in.EvaluateBlocked(tNew, in.predict, in.f)
+			in.EvaluationCount++
+			in.EvaluationCount += in.system.ComputeJacobian(&in.Config, tNew, in.predict, in.f)
+			in.jacobianFresh, in.factorized = true, false
+			converged, iterations = s.newton(&in, tNew, step, newtonTolerance)
+		}
+
+		// the step size factors are reduced for slowly converging iterations
+		safety := 0.9 * float64(2*maxNewtonIterations+1) / float64(2*maxNewtonIterations+iterations)
+		errorEstimate := math.Inf(1)
+		factor := 0.5
+		if converged {
+			for id := range in.yError {
+				in.yError[id] = s.errorConst[in.order] * in.correction[id]
+			}
+			errorEstimate = in.ErrorNorm(in.yError, yT, in.z)
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: step, Index: util.FirstNonFinite(in.z)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			factor = math.Max(minFactor, safety*stepFactor(errorEstimate, in.order+1))
+		} else {
+			in.NewtonFailures++
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+			s.changeDifferences(&in, factor)
+			step *= factor
+			in.factorized, in.equalSteps = false, 0
+
+			// report failure, step size too small
+			if step < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: step}
+				break
+			}
+		} else {
+			// accept step
+			t = tNew
+			copy(yT, in.z)
+			s.updateDifferences(&in)
+			in.jacobianFresh = false
+			in.equalSteps++
+
+			// change step size and order after order+1 steps of equal size
+			if in.equalSteps > in.order {
+				factor = s.selectOrder(&in, errorEstimate, safety, yT)
+				factor = math.Min(factor, in.MaxStepSize/step)
+				s.changeDifferences(&in, factor)
+				step *= factor
+				in.factorized, in.equalSteps = false, 0
+			}
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = step
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *bdf) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp matrices
+	i.differences = util.MakeRectangular(maxOrder+3, i.n)
+	i.changed = util.MakeRectangular(maxOrder+1, i.n)
+	i.f0 = make([]float64, i.n)
+	i.predict = make([]float64, i.n)
+	i.psi = make([]float64, i.n)
+	i.z = make([]float64, i.n)
+	i.correction = make([]float64, i.n)
+	i.f = make([]float64, i.n)
+	i.delta = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+// computes the predictor and the known part psi of the formula of the current order
+func (s *bdf) predict(in *integration, step float64) {
+	k := in.order
+	for id := range in.predict {
+		predict, psi := 0.0, 0.0
+		for j := 0; j <= k; j++ {
+			predict += in.differences[j][id]
+		}
+		for j := 1; j <= k; j++ {
+			psi += s.gamma[j] * in.differences[j][id]
+		}
+		in.predict[id] = predict
+		in.psi[id] = psi / s.alpha[k]
+	}
+}
+
+// solves c f(t, z) - psi - (z - predict) = 0 with c = h / alpha_k starting at the predictor,
+// returns whether the iteration converged and the number of iterations
+func (s *bdf) newton(in *integration, t, step, tolerance float64) (converged bool, iterations int) {
+	c := step / s.alpha[in.order]
+	if !in.factorized {
+		if in.system.Factorize(c) != nil {
+			return false, maxNewtonIterations
+		}
+		in.factorized = true
+	}
+
+	copy(in.z, in.predict)
+	for id := range in.correction {
+		in.correction[id] = 0.0
+	}
+
+	deltaNorm := 0.0
+	for iterations = 1; iterations <= maxNewtonIterations; iterations++ {
+		in.NewtonIterations++
+
+		in.EvaluateBlocked(t, in.z, in.f)
+		in.EvaluationCount++
+		if util.FirstNonFinite(in.f) >= 0 {
+			return
+		}
+		for id := range in.delta {
+			in.delta[id] = c*in.f[id] - in.psi[id] - in.correction[id]
+		}
+		in.system.Solve(in.delta)
+
+		lastDeltaNorm := deltaNorm
+		deltaNorm = in.ErrorNorm(in.delta, in.z, in.z)
+		contraction := 0.0
+		if iterations > 1 {
+			contraction = deltaNorm / lastDeltaNorm
+			// the iteration diverges or won't reach the tolerance in the remaining iterations
+			remaining := float64(maxNewtonIterations - iterations + 1)
+			if contraction >= 1.0 || math.Pow(contraction, remaining)/(1.0-contraction)*deltaNorm > tolerance {
+				return
+			}
+		}
+
+		for id := range in.z {
+			in.z[id] += in.delta[id]
+			in.correction[id] += in.delta[id]
+		}
+
+		if deltaNorm == 0.0 || iterations > 1 && contraction/(1.0-contraction)*deltaNorm < tolerance {
+			converged = true
+			return
+		}
+	}
+	iterations = maxNewtonIterations
+	return
+}
+
+// adds the correction of the accepted step to the differences
+func (s *bdf) updateDifferences(in *integration) {
+	k := in.order
+	for id := range in.correction {
+		in.differences[k+2][id] = in.correction[id] - in.differences[k+1][id]
+		in.differences[k+1][id] = in.correction[id]
+	}
+	for j := k; j >= 0; j-- {
+		for id := range in.correction {
+			in.differences[j][id] += in.differences[j+1][id]
+		}
+	}
+}
+
+// chooses the order among order-1, order and order+1 that allows the largest step
+// and returns the step size factor
+func (s *bdf) selectOrder(in *integration, errorEstimate, safety float64, yT []float64) float64 {
+	k := in.order
+	norm := func(order int, differences []float64) float64 {
+		for id := range in.yError {
+			in.yError[id] = s.errorConst[order] * differences[id]
+		}
+		return in.ErrorNorm(in.yError, yT, yT)
+	}
+
+	factors := []float64{0.0, stepFactor(errorEstimate, k+1), 0.0}
+	if k > 1 {
+		factors[0] = stepFactor(norm(k-1, in.differences[k]), k)
+	}
+	if k < maxOrder {
+		factors[2] = stepFactor(norm(k+1, in.differences[k+2]), k+2)
+	}
+
+	best := 1
+	for j := range factors {
+		if factors[j] > factors[best] {
+			best = j
+		}
+	}
+	in.order += best - 1
+	return math.Min(maxFactor, safety*factors[best])
+}
+
+// rescales the differences of the current order to the step size factor * h
+func (s *bdf) changeDifferences(in *integration, factor float64) {
+	k := in.order
+	r := interpolationChange(k, factor)
+	u := interpolationChange(k, 1.0)
+
+	// RU = R U, differences = (RU)^T differences
+	ru := util.MakeSquare(uint(k + 1))
+	for i := 0; i <= k; i++ {
+		for j := 0; j <= k; j++ {
+			for l := 0; l <= k; l++ {
+				ru[i][j] += r[i][l] * u[l][j]
+			}
+		}
+	}
+	for i := 0; i <= k; i++ {
+		for id := range in.changed[i] {
+			sum := 0.0
+			for j := 0; j <= k; j++ {
+				sum += ru[j][i] * in.differences[j][id]
+			}
+			in.changed[i][id] = sum
+		}
+	}
+	for i := 0; i <= k; i++ {
+		copy(in.differences[i], in.changed[i])
+	}
+}
+
+// the matrix transforming the differences of order k to differences for the step size factor * h
+func interpolationChange(k int, factor float64) [][]float64 {
+	r := util.MakeSquare(uint(k + 1))
+	for j := 0; j <= k; j++ {
+		r[0][j] = 1.0
+	}
+	for i := 1; i <= k; i++ {
+		for j := 1; j <= k; j++ {
+			r[i][j] = r[i-1][j] * (float64(i) - 1.0 - factor*float64(j)) / float64(i)
+		}
+	}
+	return r
+}
+
+// step size factor for an error estimate of the given order
+func stepFactor(errorEstimate float64, order int) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return minFactor
+	}
+	return math.Pow(1.0e-10+errorEstimate, -1.0/float64(order))
+}
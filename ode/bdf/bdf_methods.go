@@ -0,0 +1,59 @@
+package bdf
+
+import "github.com/rollingthunder/differential/ode"
+
+type BDFMethod uint
+
+const (
+	BDF                = BDFMethod(iota) // backward differentiation formulas of orders 1 to 5
+	NDF                                  // numerical differentiation formulas of Klopfenstein and Shampine, orders 1 to 5
+	NumberOfBDFMethods = uint(iota)
+)
+
+const (
+	maxOrder = 5
+	// Newton iterations per step before the Jacobian is recomputed or the step rejected
+	maxNewtonIterations = 4
+	// bounds for the step size factor of a step size or order change
+	minFactor = 0.2
+	maxFactor = 10.0
+)
+
+func NewBDF(m BDFMethod) (i ode.Integrator, err error) {
+	var s bdf
+	s.method = m
+
+	switch m {
+	case BDF:
+		s.Name = "BDF"
+		s.kappa = []float64{0, 0, 0, 0, 0, 0}
+	case NDF:
+		s.Name = "NDF"
+		s.kappa = []float64{0, -0.1850, -1.0 / 9.0, -0.0823, -0.0415, 0}
+	default:
+		err = &ode.ConfigError{Field: "BDFMethod", Reason: "unknown bdf method"}
+	}
+	if err == nil {
+		s.Stages = 1
+		s.Order = maxOrder
+		setCoeffs(&s)
+	}
+
+	i = &s
+	return
+}
+
+// coefficients of the quasi-constant step size formulation of Shampine and Reichelt,
+// the NDF of order k modify the BDF by kappa_k gamma_k (y_n+1 - predictor)
+func setCoeffs(s *bdf) {
+	s.gamma = make([]float64, maxOrder+1)
+	s.alpha = make([]float64, maxOrder+1)
+	s.errorConst = make([]float64, maxOrder+1)
+	for k := 1; k <= maxOrder; k++ {
+		s.gamma[k] = s.gamma[k-1] + 1.0/float64(k)
+	}
+	for k := 0; k <= maxOrder; k++ {
+		s.alpha[k] = (1.0 - s.kappa[k]) * s.gamma[k]
+		s.errorConst[k] = s.kappa[k]*s.gamma[k] + 1.0/float64(k+1)
+	}
+}
@@ -0,0 +1,101 @@
+package bdf
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+func TestBDFConstructor(t *testing.T) {
+	for j := 0; j < int(NumberOfBDFMethods); j++ {
+		if _, err := NewBDF(BDFMethod(j)); err != nil {
+			t.Errorf("Couldn't create BDF Method %d: %s", j, err.Error())
+		}
+	}
+	if _, err := NewBDF(BDFMethod(NumberOfBDFMethods)); err == nil {
+		t.Errorf("Unknown method was accepted")
+	}
+}
+
+// the methods start with order 1, so polynomial solutions aren't reproduced exactly
+// and the generic integrator tests don't apply
+func TestBDFTolerance(t *testing.T) {
+	// y' = -(y - sin(t)) + cos(t), y = sin(t)
+	fcn := func(t float64, y []float64, dy []float64) {
+		dy[0] = -(y[0] - math.Sin(t)) + math.Cos(t)
+	}
+
+	for j := 0; j < int(NumberOfBDFMethods); j++ {
+		s, _ := NewBDF(BDFMethod(j))
+		errors := make([]float64, 0, 3)
+		for _, tolerance := range []float64{1e-4, 1e-6, 1e-8} {
+			y := []float64{0}
+			stat, err := s.Integrate(0, 10, y, &Config{Fcn: fcn, AbsoluteTolerance: tolerance})
+			if err != nil {
+				t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+			}
+			errors = append(errors, math.Abs(y[0]-math.Sin(10)))
+			if errors[len(errors)-1] > 20*tolerance {
+				t.Errorf("%s: error %g for tolerance %g", s.Info().Name, errors[len(errors)-1], tolerance)
+			}
+			if testing.Verbose() {
+				t.Logf("%s: tolerance %g, error %g, %d steps", s.Info().Name, tolerance, errors[len(errors)-1], stat.StepCount)
+			}
+		}
+		if errors[2] > 1e-2*errors[0] {
+			t.Errorf("%s: errors %v don't decrease with the tolerance", s.Info().Name, errors)
+		}
+	}
+}
+
+func TestBDFRobertson(t *testing.T) {
+	for j := 0; j < int(NumberOfBDFMethods); j++ {
+		s, _ := NewBDF(BDFMethod(j))
+		y := []float64{1, 0, 0}
+		config := Config{Fcn: Robertson, AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-6}
+
+		stat, err := s.Integrate(0, 40, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+
+		CheckRelative(t, s.Info().Name, y, RobertsonReference, 1e-4)
+		CheckStability(t, s.Info().Name, stat, 1000)
+		if testing.Verbose() {
+			t.Logf("Robertson %s: %d steps, %d rejected, %d evaluations, %d newton iterations",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.NewtonIterations)
+		}
+	}
+}
+
+func TestBDFBrussSparse(t *testing.T) {
+	// 2 * 32^2 unknowns with the analytic sparse Jacobian
+	bruss := problems.NewBruss2D(32)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+
+	reference, explicit := Reference(t, dopri, bruss, 1)
+
+	for j := 0; j < int(NumberOfBDFMethods); j++ {
+		s, _ := NewBDF(BDFMethod(j))
+		y := bruss.Initialize()
+		config := Config{
+			Fcn:               bruss.Fcn,
+			Sparsity:          bruss.Sparsity(),
+			SparseJacobian:    bruss.Jacobian,
+			AbsoluteTolerance: 1e-6,
+		}
+
+		stat, err := s.Integrate(0, 1, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		CheckReference(t, s.Info().Name, y, reference, ReferenceTolerance)
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations, %d newton iterations, %d explicit steps",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.NewtonIterations, explicit.StepCount)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package bvp
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"sync"
+)
+
+const (
+	defaultTolerance     = 1e-6
+	defaultMaxIterations = 20
+	// the initial value problems are solved this much more accurately than the Newton tolerance
+	integrationAccuracy = 1e-2
+	// smallest damping factor of a Newton correction
+	minDamping = 1.0 / 1024
+)
+
+// BoundaryFunction evaluates the n boundary conditions r(ya, yb) = 0
+// on the solution at the left end ya and the right end yb of the interval
+type BoundaryFunction func(ya, yb []float64, r_out []float64)
+
+// Config configures the solution of yT' = Fcn(t, yT) subject to Boundary(y(a), y(b)) = 0,
+// the embedded Config is used for the initial value problems on the shooting intervals.
+// Unset tolerances of the initial value problems default to Tolerance / 100
+type Config struct {
+	ode.Config
+
+	Boundary BoundaryFunction
+
+	// Tolerance bounds the last Newton correction relative to 1 + the maximum norm of the solution
+	Tolerance float64
+	// MaxIterations is the maximum number of Newton iterations
+	MaxIterations uint
+}
+
+// Shooting solves two point boundary value problems by multiple shooting.
+// The initial value problems of the shooting intervals are solved concurrently,
+// so Integrate of the integrator has to be safe for concurrent use
+type Shooting struct {
+	integrator ode.Integrator
+}
+
+func NewShooting(integrator ode.Integrator) *Shooting {
+	return &Shooting{integrator: integrator}
+}
+
+type solution struct {
+	Config
+	ode.Statistics
+	n, m  int
+	nodes []float64
+
+	// end values of the shooting intervals and their derivatives with respect to the start values
+	ends        [][]float64
+	sensitivity [][][]float64
+	// derivatives of the boundary conditions with respect to ya and yb
+	boundaryA, boundaryB [][]float64
+
+	// errors and statistics of the intervals
+	errors []error
+	stats  []ode.Statistics
+
+	residual, correction, trial []float64
+	jacobian                    [][]float64
+	lu                          linalg.LU
+}
+
+// Solve computes the solution at the shooting nodes a = nodes[0] < ... < nodes[m] = b by a damped
+// Newton iteration on the matching and boundary conditions, whose Jacobian is approximated by
+// finite differences. y holds initial guesses of the solution at the nodes and is overwritten
+func (s *Shooting) Solve(nodes []float64, y [][]float64, config *Config) (stat ode.Statistics, err error) {
+	sol, err := setupSolution(nodes, y, config)
+	if err != nil {
+		return
+	}
+
+	// unknowns are the values at the nodes, stacked
+	unknowns := make([]float64, (sol.m+1)*sol.n)
+	for j := range y {
+		copy(unknowns[j*sol.n:], y[j])
+	}
+
+	residualNorm := math.Inf(1)
+	for {
+		if sol.NewtonIterations >= sol.MaxIterations {
+			err = &ConvergenceError{Iterations: sol.NewtonIterations, Residual: residualNorm}
+			break
+		}
+		if sol.Cancelled() {
+			err = &ode.CancelledError{Time: nodes[0]}
+			break
+		}
+		sol.NewtonIterations++
+
+		if err = s.integrate(&sol, unknowns, true); err != nil {
+			break
+		}
+		residualNorm = sol.evaluateResidual(unknowns, sol.residual)
+		sol.assembleJacobian(unknowns)
+		if err = sol.lu.Factorize(sol.jacobian); err != nil {
+			break
+		}
+		for i, r := range sol.residual {
+			sol.correction[i] = -r
+		}
+		sol.lu.Solve(sol.correction)
+
+		if maxNorm(sol.correction) <= sol.Tolerance*(1.0+maxNorm(unknowns)) {
+			for i := range unknowns {
+				unknowns[i] += sol.correction[i]
+			}
+			break
+		}
+
+		// damp the correction until the residual decreases
+		damping := 1.0
+		for {
+			for i := range unknowns {
+				sol.trial[i] = unknowns[i] + damping*sol.correction[i]
+			}
+			trialNorm := math.Inf(1)
+			if s.integrate(&sol, sol.trial, false) == nil {
+				trialNorm = sol.evaluateResidual(sol.trial, make([]float64, len(sol.trial)))
+			}
+			if trialNorm < residualNorm {
+				copy(unknowns, sol.trial)
+				break
+			}
+			if damping *= 0.5; damping < minDamping {
+				err = &ConvergenceError{Iterations: sol.NewtonIterations, Residual: residualNorm}
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	for j := range y {
+		copy(y[j], unknowns[j*sol.n:(j+1)*sol.n])
+	}
+	sol.CurrentTime = nodes[sol.m]
+	stat = sol.Statistics
+	return
+}
+
+func setupSolution(nodes []float64, y [][]float64, config *Config) (sol solution, err error) {
+	if config == nil {
+		err = &ode.ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if config.Boundary == nil {
+		err = &ode.ConfigError{Field: "Boundary", Reason: "no boundary conditions specified"}
+		return
+	}
+	if config.DenseOutput != nil {
+		// the shooting intervals are integrated concurrently and repeatedly
+		err = &ode.ConfigError{Field: "DenseOutput", Reason: "no dense output for boundary value problems"}
+		return
+	}
+	if len(nodes) < 2 || len(y) != len(nodes) {
+		err = &ode.ConfigError{Field: "nodes", Reason: "at least two nodes with one initial guess each needed"}
+		return
+	}
+	for j := 1; j < len(nodes); j++ {
+		if !(nodes[j] > nodes[j-1]) {
+			err = &ode.ConfigError{Field: "nodes", Reason: "nodes have to be increasing"}
+			return
+		}
+	}
+	n := len(y[0])
+	for j := range y {
+		if len(y[j]) != n || n == 0 {
+			err = &ode.ConfigError{Field: "nodes", Reason: "initial guesses of different sizes"}
+			return
+		}
+	}
+
+	sol.Config = *config
+	if sol.Tolerance <= 0.0 {
+		sol.Tolerance = defaultTolerance
+	}
+	if sol.MaxIterations == 0 {
+		sol.MaxIterations = defaultMaxIterations
+	}
+	if sol.AbsoluteTolerance <= 0.0 {
+		sol.AbsoluteTolerance = integrationAccuracy * sol.Tolerance
+	}
+	if sol.Effective, err = sol.Config.Config.ValidateAndPrepare(uint(n), nodes[0], nodes[len(nodes)-1]); err != nil {
+		return
+	}
+
+	sol.n, sol.m = n, len(nodes)-1
+	sol.nodes = nodes
+	sol.ends = make([][]float64, sol.m)
+	sol.sensitivity = make([][][]float64, sol.m)
+	for j := range sol.ends {
+		sol.ends[j] = make([]float64, n)
+		sol.sensitivity[j] = util.MakeRectangular(uint(n), uint(n))
+	}
+	sol.boundaryA, sol.boundaryB = util.MakeRectangular(uint(n), uint(n)), util.MakeRectangular(uint(n), uint(n))
+	sol.errors, sol.stats = make([]error, sol.m), make([]ode.Statistics, sol.m)
+
+	size := (sol.m + 1) * n
+	sol.residual, sol.correction, sol.trial = make([]float64, size), make([]float64, size), make([]float64, size)
+	sol.jacobian = util.MakeRectangular(uint(size), uint(size))
+	return
+}
+
+// integrate solves the initial value problems of all intervals concurrently,
+// with sensitivities set their derivatives are approximated by finite differences
+func (s *Shooting) integrate(sol *solution, unknowns []float64, sensitivities bool) error {
+	var wg sync.WaitGroup
+	for j := 0; j < sol.m; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			sol.stats[j] = ode.Statistics{}
+			sol.errors[j] = s.integrateInterval(sol, j, unknowns[j*sol.n:(j+1)*sol.n], sensitivities)
+		}(j)
+	}
+	wg.Wait()
+
+	var err error
+	for j := range sol.errors {
+		sol.StepCount += sol.stats[j].StepCount
+		sol.RejectedCount += sol.stats[j].RejectedCount
+		sol.EvaluationCount += sol.stats[j].EvaluationCount
+		if err == nil {
+			err = sol.errors[j]
+		}
+	}
+	return err
+}
+
+func (s *Shooting) integrateInterval(sol *solution, j int, start []float64, sensitivities bool) error {
+	ivp := sol.Config.Config
+	t0, t1 := sol.nodes[j], sol.nodes[j+1]
+
+	solve := func(y []float64) error {
+		stat, err := s.integrator.Integrate(t0, t1, y, &ivp)
+		sol.stats[j].StepCount += stat.StepCount
+		sol.stats[j].RejectedCount += stat.RejectedCount
+		sol.stats[j].EvaluationCount += stat.EvaluationCount
+		return err
+	}
+
+	copy(sol.ends[j], start)
+	if err := solve(sol.ends[j]); err != nil || !sensitivities {
+		return err
+	}
+
+	// one column of the sensitivity matrix per perturbed component,
+	// the perturbations are well above the accuracy of the integration
+	y := make([]float64, sol.n)
+	for k := 0; k < sol.n; k++ {
+		copy(y, start)
+		delta := math.Sqrt(sol.AbsoluteTolerance) * math.Max(1.0, math.Abs(start[k]))
+		y[k] += delta
+		if err := solve(y); err != nil {
+			return err
+		}
+		for i := range y {
+			sol.sensitivity[j][i][k] = (y[i] - sol.ends[j][i]) / delta
+		}
+	}
+	return nil
+}
+
+// evaluateResidual computes the matching conditions y(t_j+1; t_j, s_j) - s_j+1 and
+// the boundary conditions r(s_0, s_m) for the last integration and returns their maximum norm
+func (sol *solution) evaluateResidual(unknowns, residual_out []float64) float64 {
+	n, m := sol.n, sol.m
+	for j := 0; j < m; j++ {
+		for i := 0; i < n; i++ {
+			residual_out[j*n+i] = sol.ends[j][i] - unknowns[(j+1)*n+i]
+		}
+	}
+	sol.Boundary(unknowns[:n], unknowns[m*n:], residual_out[m*n:])
+	return maxNorm(residual_out)
+}
+
+// assembleJacobian sets up the Jacobian of the residual, with the sensitivities
+// on the block diagonal, -I right of it and the boundary derivatives in the last block row
+func (sol *solution) assembleJacobian(unknowns []float64) {
+	n, m := sol.n, sol.m
+	for _, row := range sol.jacobian {
+		for i := range row {
+			row[i] = 0.0
+		}
+	}
+	for j := 0; j < m; j++ {
+		for i := 0; i < n; i++ {
+			copy(sol.jacobian[j*n+i][j*n:], sol.sensitivity[j][i])
+			sol.jacobian[j*n+i][(j+1)*n+i] = -1.0
+		}
+	}
+
+	sol.boundaryDerivatives(unknowns[:n], unknowns[m*n:])
+	for i := 0; i < n; i++ {
+		copy(sol.jacobian[m*n+i][:n], sol.boundaryA[i])
+		for k := 0; k < n; k++ {
+			sol.jacobian[m*n+i][m*n+k] += sol.boundaryB[i][k]
+		}
+	}
+}
+
+// boundaryDerivatives approximates the derivatives of the boundary conditions by finite differences
+func (sol *solution) boundaryDerivatives(ya, yb []float64) {
+	n := sol.n
+	r0, r := make([]float64, n), make([]float64, n)
+	yaPerturbed, ybPerturbed := append([]float64(nil), ya...), append([]float64(nil), yb...)
+	sol.Boundary(ya, yb, r0)
+
+	for k := 0; k < n; k++ {
+		delta := math.Sqrt(2.2e-16) * math.Max(1.0, math.Abs(ya[k]))
+		yaPerturbed[k] += delta
+		sol.Boundary(yaPerturbed, yb, r)
+		yaPerturbed[k] = ya[k]
+		for i := range r {
+			sol.boundaryA[i][k] = (r[i] - r0[i]) / delta
+		}
+
+		delta = math.Sqrt(2.2e-16) * math.Max(1.0, math.Abs(yb[k]))
+		ybPerturbed[k] += delta
+		sol.Boundary(ya, ybPerturbed, r)
+		ybPerturbed[k] = yb[k]
+		for i := range r {
+			sol.boundaryB[i][k] = (r[i] - r0[i]) / delta
+		}
+	}
+}
+
+func maxNorm(x []float64) (norm float64) {
+	for _, v := range x {
+		norm = math.Max(norm, math.Abs(v))
+	}
+	return
+}
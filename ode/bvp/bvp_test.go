@@ -0,0 +1,130 @@
+package bvp
+
+import (
+	"errors"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"math"
+	"testing"
+)
+
+func equidistant(a, b float64, intervals int) []float64 {
+	nodes := make([]float64, intervals+1)
+	for j := range nodes {
+		nodes[j] = a + (b-a)*float64(j)/float64(intervals)
+	}
+	return nodes
+}
+
+func TestTwoPoint(t *testing.T) {
+	// y'' = 3/2 y^2, y(0) = 4, y(1) = 1 with the solution 4 / (1 + x)^2
+	config := Config{
+		Config: ode.Config{
+			Fcn: func(t float64, y []float64, dy []float64) {
+				dy[0], dy[1] = y[1], 1.5*y[0]*y[0]
+			},
+		},
+		Boundary: func(ya, yb []float64, r []float64) {
+			r[0], r[1] = ya[0]-4.0, yb[0]-1.0
+		},
+		Tolerance: 1e-8,
+	}
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	shooting := NewShooting(dopri)
+
+	for _, intervals := range []int{1, 5} {
+		nodes := equidistant(0, 1, intervals)
+		// linear initial guess
+		y := make([][]float64, len(nodes))
+		for j, x := range nodes {
+			y[j] = []float64{4.0 - 3.0*x, -3.0}
+		}
+
+		stat, err := shooting.Solve(nodes, y, &config)
+		if err != nil {
+			t.Fatalf("%d intervals: Solve failed - %s", intervals, err.Error())
+		}
+		for j, x := range nodes {
+			exact := []float64{4.0 / ((1 + x) * (1 + x)), -8.0 / ((1 + x) * (1 + x) * (1 + x))}
+			if math.Abs(y[j][0]-exact[0]) > 1e-6 || math.Abs(y[j][1]-exact[1]) > 1e-6 {
+				t.Errorf("%d intervals: solution %v at %g differs from %v", intervals, y[j], x, exact)
+			}
+		}
+		if testing.Verbose() {
+			t.Logf("%d intervals: %d iterations, %d steps, %d evaluations", intervals, stat.NewtonIterations, stat.StepCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestPeriodicOrbit(t *testing.T) {
+	// limit cycle of the Van der Pol oscillator, the unknown period T is
+	// an additional component with T' = 0 and the time is scaled to [0, 1]
+	const period, amplitude = 6.6632868593231301897, 2.0086198608748431365
+	config := Config{
+		Config: ode.Config{
+			Fcn: func(t float64, y []float64, dy []float64) {
+				dy[0] = y[2] * y[1]
+				dy[1] = y[2] * ((1-y[0]*y[0])*y[1] - y[0])
+				dy[2] = 0.0
+			},
+		},
+		// periodicity and the phase condition y'(0) = 0
+		Boundary: func(ya, yb []float64, r []float64) {
+			r[0], r[1], r[2] = yb[0]-ya[0], yb[1]-ya[1], ya[1]
+		},
+		Tolerance: 1e-9,
+	}
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	shooting := NewShooting(dopri)
+
+	// circle as initial guess
+	nodes := equidistant(0, 1, 8)
+	y := make([][]float64, len(nodes))
+	for j, x := range nodes {
+		y[j] = []float64{2.0 * math.Cos(2*math.Pi*x), -2.0 * math.Sin(2*math.Pi*x), 6.0}
+	}
+
+	stat, err := shooting.Solve(nodes, y, &config)
+	if err != nil {
+		t.Fatalf("Solve failed - %s", err.Error())
+	}
+	if math.Abs(y[0][2]-period) > 1e-6 || math.Abs(y[0][0]-amplitude) > 1e-6 {
+		t.Errorf("Period %g and amplitude %g differ from %g and %g", y[0][2], y[0][0], period, amplitude)
+	}
+	if testing.Verbose() {
+		t.Logf("%d iterations, %d steps, %d evaluations", stat.NewtonIterations, stat.StepCount, stat.EvaluationCount)
+	}
+}
+
+func TestShootingErrors(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	shooting := NewShooting(dopri)
+	fcn := func(t float64, y []float64, dy []float64) { dy[0] = y[0] }
+	boundary := func(ya, yb []float64, r []float64) { r[0] = ya[0] - 1.0 }
+
+	invalid := []struct {
+		nodes  []float64
+		y      [][]float64
+		config *Config
+	}{
+		{[]float64{0, 1}, [][]float64{{1}, {1}}, nil},
+		{[]float64{0, 1}, [][]float64{{1}, {1}}, &Config{Config: ode.Config{Fcn: fcn}}},
+		{[]float64{0}, [][]float64{{1}}, &Config{Config: ode.Config{Fcn: fcn}, Boundary: boundary}},
+		{[]float64{0, 1, 0.5}, [][]float64{{1}, {1}, {1}}, &Config{Config: ode.Config{Fcn: fcn}, Boundary: boundary}},
+		{[]float64{0, 1}, [][]float64{{1}, {1, 1}}, &Config{Config: ode.Config{Fcn: fcn}, Boundary: boundary}},
+	}
+	for k, test := range invalid {
+		if _, err := shooting.Solve(test.nodes, test.y, test.config); !errors.Is(err, ode.ErrInvalidConfig) {
+			t.Errorf("Setup %d: expected configuration error, got %v", k, err)
+		}
+	}
+
+	// y' = 1 has no solution with y(0) = y(1)
+	config := Config{
+		Config:   ode.Config{Fcn: func(t float64, y []float64, dy []float64) { dy[0] = 1.0 }},
+		Boundary: func(ya, yb []float64, r []float64) { r[0] = yb[0] - ya[0] },
+	}
+	if _, err := shooting.Solve([]float64{0, 1}, [][]float64{{0}, {0}}, &config); err == nil {
+		t.Errorf("Expected an error for a problem without solution")
+	}
+}
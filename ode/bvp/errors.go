@@ -0,0 +1,24 @@
+package bvp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotConverged is the category of failures of the Newton iteration,
+// use errors.Is to test an error returned by Solve against it
+var ErrNotConverged = errors.New("newton iteration did not converge")
+
+// ConvergenceError reports that the Newton iteration on the matching conditions
+// did not reach the tolerance
+type ConvergenceError struct {
+	Iterations uint
+	// Residual is the maximum norm of the matching and boundary conditions
+	Residual float64
+}
+
+func (e *ConvergenceError) Error() string {
+	return fmt.Sprintf("newton iteration did not converge: residual %g after %d iterations", e.Residual, e.Iterations)
+}
+
+func (e *ConvergenceError) Is(target error) bool { return target == ErrNotConverged }
@@ -1,6 +1,9 @@
 package ode
 
-import "math"
+import (
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
 
 func EstimateStepSize(t float64, yT, fcnValue []float64, c *Config, order uint) float64 {
 	n := len(yT)
@@ -76,3 +79,110 @@ func VanderMonde(pc []float64, pm [][]float64) {
 		}
 	}
 }
+
+// ErrorNorm computes the weighted root mean square norm of the local error estimate yError,
+// each component is scaled by AbsoluteTolerance + RelativeTolerance * max(|yOld|, |yNew|)
+func (c *Config) ErrorNorm(yError, yOld, yNew []float64) float64 {
+	n := c.ErrorCount(len(yError))
+	errorNorm := 0.0
+	for id := 0; id < n; id++ {
+		currentTolerance := c.AbsoluteTolerance + c.RelativeTolerance*math.Max(math.Abs(yOld[id]), math.Abs(yNew[id]))
+		errorNorm = errorNorm + math.Pow(yError[id]/currentTolerance, 2.0)
+	}
+	return math.Sqrt(errorNorm / float64(n))
+}
+
+// ErrorCount returns the number of components of a system of size n that enter the error estimate
+func (c *Config) ErrorCount(n int) int {
+	if m := int(c.ErrorComponents); m > 0 && m < n {
+		return m
+	}
+	return n
+}
+
+// EvaluateBlocked evaluates the right hand side at (t, yT) block by block using FcnBlocked
+func (c *Config) EvaluateBlocked(t float64, yT, dy_out []float64) {
+	var block, n uint = 0, uint(len(yT))
+	for block = 0; block < n; block += c.BlockSize {
+		c.FcnBlocked(block, c.BlockSize, t, yT, dy_out)
+	}
+}
+
+// SpectralRadius estimates the spectral radius of the Jacobian of the right hand side at (t, yT)
+// by nonlinear power iteration, f0 has to hold the evaluation at (t, yT).
+// v is the start vector and holds the estimated dominant eigenvector on return,
+// so it can be reused to speed up later estimates
+func (c *Config) SpectralRadius(t float64, yT, f0, v []float64) (radius float64, evaluations uint) {
+	const maxIterations = 50
+	n := len(yT)
+	fv := make([]float64, n)
+
+	yNorm, vNorm := util.EuclideanNorm(yT), util.EuclideanNorm(v)
+	if vNorm == 0.0 {
+		// no start vector, use the evaluation or a unit vector
+		copy(v, f0)
+		vNorm = util.EuclideanNorm(v)
+		if vNorm == 0.0 {
+			for id := range v {
+				v[id] = 1.0
+			}
+			vNorm = math.Sqrt(float64(n))
+		}
+	}
+
+	// size of the perturbation relative to the solution
+	perturbation := math.Sqrt(uround)
+	if yNorm != 0.0 {
+		perturbation *= yNorm
+	}
+
+	for id := range v {
+		v[id] = yT[id] + v[id]*perturbation/vNorm
+	}
+
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		c.EvaluateBlocked(t, v, fv)
+		evaluations++
+
+		for id := range fv {
+			fv[id] -= f0[id]
+		}
+		difference := util.EuclideanNorm(fv)
+		lastRadius := radius
+		radius = difference / perturbation
+
+		if iteration >= 2 && math.Abs(radius-lastRadius) <= 0.01*math.Max(radius, 1.0/c.MaxStepSize) {
+			break
+		}
+
+		if difference == 0.0 {
+			// linear in the perturbed direction, try another one
+			for id := range v {
+				v[id] = yT[id]
+			}
+			v[iteration%n] += perturbation
+			continue
+		}
+		for id := range v {
+			v[id] = yT[id] + fv[id]*perturbation/difference
+		}
+	}
+
+	// return the direction of the dominant eigenvector
+	for id := range v {
+		v[id] -= yT[id]
+	}
+	// safety factor, the iteration approaches the radius from below
+	radius *= 1.2
+	return
+}
+
+// unit roundoff of float64
+const uround = 1.1e-16
+
+func maxNorm(v []float64) (norm float64) {
+	for _, x := range v {
+		norm = math.Max(norm, math.Abs(x))
+	}
+	return
+}
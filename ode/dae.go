@@ -0,0 +1,85 @@
+package ode
+
+// MassFunction computes the mass matrix M(t, yT) of a differential-algebraic system
+type MassFunction func(t float64, yT []float64, m_out [][]float64)
+
+// DAEConfig configures the integration of M(t, yT) yT' = Fcn(t, yT) with a possibly
+// singular mass matrix M. The Jacobian settings of the embedded Config refer to Fcn
+type DAEConfig struct {
+	Config
+
+	// Mass is a constant mass matrix, nil means the identity
+	Mass [][]float64
+	// MassFunction if set computes a time or state dependent mass matrix, Mass is ignored
+	MassFunction MassFunction
+}
+
+// DAEIntegrator integrates differential-algebraic systems of index 1
+type DAEIntegrator interface {
+	Info() IntegratorInfo
+	IntegrateDAE(t, tEnd float64, yT []float64, config *DAEConfig) (stat Statistics, err error)
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components
+// and returns the effective configuration
+func (c *DAEConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e DAEConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.MassFunction == nil && c.Mass != nil {
+		if uint(len(c.Mass)) != n {
+			err = &ConfigError{Field: "Mass", Reason: "mass matrix does not match the number of components"}
+			return
+		}
+		for _, row := range c.Mass {
+			if uint(len(row)) != n {
+				err = &ConfigError{Field: "Mass", Reason: "mass matrix is not square"}
+				return
+			}
+		}
+	}
+
+	e = *c
+	e.Config, err = c.Config.ValidateAndPrepare(n, t, tEnd)
+	return
+}
+
+// EvaluateMass computes the mass matrix at (t, yT)
+func (c *DAEConfig) EvaluateMass(t float64, yT []float64, m_out [][]float64) {
+	if c.MassFunction != nil {
+		c.MassFunction(t, yT, m_out)
+		return
+	}
+	for i := range m_out {
+		for j := range m_out[i] {
+			if c.Mass != nil {
+				m_out[i][j] = c.Mass[i][j]
+			} else if i == j {
+				m_out[i][j] = 1.0
+			} else {
+				m_out[i][j] = 0.0
+			}
+		}
+	}
+}
+
+// Algebraic returns the zero rows and zero columns of the mass matrix m. For a semi-explicit
+// system of index 1 the rows are the algebraic equations and the columns the algebraic variables
+func Algebraic(m [][]float64) (equations, variables []int) {
+	n := len(m)
+	for i := 0; i < n; i++ {
+		rowZero, columnZero := true, true
+		for j := 0; j < n; j++ {
+			rowZero = rowZero && m[i][j] == 0.0
+			columnZero = columnZero && m[j][i] == 0.0
+		}
+		if rowZero {
+			equations = append(equations, i)
+		}
+		if columnZero {
+			variables = append(variables, i)
+		}
+	}
+	return
+}
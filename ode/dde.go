@@ -0,0 +1,83 @@
+package ode
+
+// DelayedFunction evaluates the right hand side yT'(t) = f(t, yT(t), yDelayed) of a delay
+// differential equation, where yDelayed[j] is the solution at t minus the j-th delay
+type DelayedFunction func(t float64, yT []float64, yDelayed [][]float64, dy_out []float64)
+
+// DelayFunction computes state dependent delays at (t, yT)
+type DelayFunction func(t float64, yT []float64, delays_out []float64)
+
+// HistoryFunction gives the solution before the initial time
+type HistoryFunction func(t float64, y_out []float64)
+
+// DDEConfig configures the integration of a delay differential equation,
+// Fcn and FcnBlocked of the embedded Config are ignored
+type DDEConfig struct {
+	Config
+
+	Delayed DelayedFunction
+	// History is the solution for t < t0, the initial value may differ from History(t0)
+	History HistoryFunction
+
+	// Delays are the constant delays, they come first in yDelayed
+	Delays []float64
+	// VariableDelays, if set, computes VariableDelayCount state dependent delays,
+	// which follow the constant delays in yDelayed
+	VariableDelays     DelayFunction
+	VariableDelayCount uint
+}
+
+// DDEIntegrator integrates delay differential equations given the history before t
+type DDEIntegrator interface {
+	Info() IntegratorInfo
+	IntegrateDDE(t, tEnd float64, yT []float64, config *DDEConfig) (stat Statistics, err error)
+}
+
+// DelayCount returns the number of constant and state dependent delays
+func (c *DDEConfig) DelayCount() int {
+	count := len(c.Delays)
+	if c.VariableDelays != nil {
+		count += int(c.VariableDelayCount)
+	}
+	return count
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components
+// and returns the effective configuration, Fcn is left unset
+func (c *DDEConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e DDEConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Delayed == nil {
+		err = &ConfigError{Field: "Delayed", Reason: "no evaluation function specified"}
+		return
+	}
+	if c.History == nil {
+		err = &ConfigError{Field: "History", Reason: "no history function specified"}
+		return
+	}
+	for _, delay := range c.Delays {
+		if !(delay > 0.0) {
+			err = &ConfigError{Field: "Delays", Reason: "constant delays have to be positive"}
+			return
+		}
+	}
+	if c.VariableDelays != nil && c.VariableDelayCount == 0 {
+		err = &ConfigError{Field: "VariableDelayCount", Reason: "number of state dependent delays not specified"}
+		return
+	}
+
+	// validate the remaining settings with a placeholder for the right hand side
+	placeholder := c.Config
+	placeholder.Fcn, placeholder.FcnBlocked = func(t float64, yT []float64, dy_out []float64) {}, nil
+	if e.Config, err = placeholder.ValidateAndPrepare(n, t, tEnd); err != nil {
+		return
+	}
+	e.Config.Fcn, e.Config.FcnBlocked = nil, nil
+
+	e.Delayed, e.History = c.Delayed, c.History
+	e.Delays = append([]float64(nil), c.Delays...)
+	e.VariableDelays, e.VariableDelayCount = c.VariableDelays, c.VariableDelayCount
+	return
+}
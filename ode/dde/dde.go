@@ -0,0 +1,284 @@
+package dde
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// bisection steps to locate discontinuities caused by state dependent delays
+	maxBisections = 60
+)
+
+type dde struct {
+	IntegratorInfo
+	base Integrator
+}
+
+// a discontinuity of the level-th derivative of the solution
+type discontinuity struct {
+	t     float64
+	level uint
+}
+
+type integration struct {
+	DDEConfig
+	Statistics
+	n       uint
+	history *history
+
+	// discontinuities ahead of and behind the current time
+	pending, reached []discontinuity
+	maxLevel         uint
+
+	yStart, yCheck, delays []float64
+}
+
+// delayed states for one evaluation of the right hand side
+type delayedState struct {
+	delays   []float64
+	yDelayed [][]float64
+}
+
+// NewDDE creates an integrator for delay differential equations on top of base, which has to
+// provide dense output through Config.DenseOutput. The step sizes are bounded by the smallest
+// constant delay, discontinuities are propagated along the delays up to the order of base
+func NewDDE(base Integrator) (i DDEIntegrator, err error) {
+	var d dde
+	if base == nil {
+		err = &ConfigError{Field: "Integrator", Reason: "no integrator specified"}
+	} else if info := base.Info(); !info.DenseOutput {
+		err = &ConfigError{Field: "Integrator", Reason: "integrator " + info.Name + " provides no dense output"}
+	} else {
+		d.base = base
+		d.Name = "DDE-" + info.Name
+		d.Stages, d.Order = info.Stages, info.Order
+		d.DenseOutput = true
+	}
+
+	i = &d
+	return
+}
+
+// integrates the solution from discontinuity to discontinuity with the base integrator, with
+// state dependent delays it proceeds step by step to locate the discontinuities they cause
+func (d *dde) IntegrateDDE(t, tEnd float64, yT []float64, config *DDEConfig) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+
+	in := d.setupIntegration(t, yT, &effective)
+	inner := d.innerConfig(&in)
+	variable := in.VariableDelays != nil
+
+	in.reach(discontinuity{t: t, level: 0}, tEnd)
+	stepEstimate := in.InitialStepSize
+	var last Statistics
+
+	for t < tEnd {
+		segmentEnd := tEnd
+		if len(in.pending) > 0 && in.pending[0].t < segmentEnd {
+			segmentEnd = in.pending[0].t
+		}
+
+		inner.InitialStepSize = stepEstimate
+		inner.OneStepOnly = variable
+		count := len(in.history.steps)
+		copy(in.yStart, yT)
+
+		last, err = d.base.Integrate(t, segmentEnd, yT, &inner)
+		in.add(&last)
+		if err != nil {
+			break
+		}
+		tNew := last.CurrentTime
+
+		// repeat the step up to a discontinuity caused by a state dependent delay
+		if variable {
+			if crossing, found := in.locate(t, tNew, yT); found {
+				in.history.truncate(count)
+				copy(yT, in.yStart)
+				inner.OneStepOnly = false
+				inner.InitialStepSize = crossing.t - t
+				last, err = d.base.Integrate(t, crossing.t, yT, &inner)
+				in.add(&last)
+				if err != nil {
+					break
+				}
+				tNew = crossing.t
+				in.reach(crossing, tEnd)
+			}
+		}
+		stepEstimate = last.NextStepSize
+
+		if in.DenseOutput != nil {
+			for _, step := range in.history.steps[count:] {
+				in.DenseOutput(step)
+			}
+		}
+
+		t = tNew
+		for len(in.pending) > 0 && in.pending[0].t <= t+1e-12*math.Max(1.0, math.Abs(t)) {
+			t = math.Max(t, in.pending[0].t)
+			reached := in.pending[0]
+			in.pending = in.pending[1:]
+			in.reach(reached, tEnd)
+		}
+
+		// constant delays never reach further back than the largest one
+		if !variable && len(in.Delays) > 0 {
+			in.history.drop(t - maxValue(in.Delays))
+		}
+
+		if in.Cancelled() && t < tEnd {
+			err = &CancelledError{Time: t}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = last.LastStepSize
+	in.NextStepSize = last.NextStepSize
+	in.Effective = inner
+
+	stat = in.Statistics
+	return
+}
+
+func (d *dde) setupIntegration(t float64, yT []float64, c *DDEConfig) (i integration) {
+	i.n = uint(len(yT))
+	i.DDEConfig = *c
+	i.maxLevel = d.Order + 1
+	i.history = &history{t0: t, y0: append([]float64(nil), yT...), function: c.History}
+
+	// allocate temp vectors
+	i.yStart = make([]float64, i.n)
+	i.yCheck = make([]float64, i.n)
+	i.delays = make([]float64, c.VariableDelayCount)
+
+	return
+}
+
+// the configuration of the base integrator, its right hand side looks up the delayed states
+// in the history, which grows with every accepted step
+func (d *dde) innerConfig(in *integration) (inner Config) {
+	n, count, constant := in.n, in.DelayCount(), in.Delays
+	states := sync.Pool{New: func() interface{} {
+		return &delayedState{delays: make([]float64, count), yDelayed: util.MakeRectangular(uint(count), n)}
+	}}
+	delayed, variable, history := in.Delayed, in.VariableDelays, in.history
+
+	inner = in.Config
+	inner.FcnBlocked, inner.BlockSize = nil, 0
+	inner.Fcn = func(t float64, yT []float64, dy_out []float64) {
+		state := states.Get().(*delayedState)
+		copy(state.delays, constant)
+		if variable != nil {
+			variable(t, yT, state.delays[len(constant):])
+		}
+		for j, delay := range state.delays {
+			history.evaluate(t-delay, state.yDelayed[j])
+		}
+		delayed(t, yT, state.yDelayed, dy_out)
+		states.Put(state)
+	}
+	inner.DenseOutput = history.add
+
+	// the steps must not need values from within themselves
+	if len(constant) > 0 {
+		inner.MaxStepSize = math.Min(inner.MaxStepSize, minValue(constant))
+	}
+	return
+}
+
+func (in *integration) add(stat *Statistics) {
+	in.StepCount += stat.StepCount
+	in.RejectedCount += stat.RejectedCount
+	in.EvaluationCount += stat.EvaluationCount
+}
+
+// reach records a discontinuity at the current time and schedules its successors along the constant delays
+func (in *integration) reach(point discontinuity, tEnd float64) {
+	in.reached = append(in.reached, point)
+	if point.level >= in.maxLevel {
+		return
+	}
+	for _, delay := range in.Delays {
+		next := discontinuity{t: point.t + delay, level: point.level + 1}
+		if next.t >= tEnd {
+			continue
+		}
+
+		index := sort.Search(len(in.pending), func(k int) bool { return in.pending[k].t >= next.t })
+		if index < len(in.pending) && math.Abs(in.pending[index].t-next.t) <= 1e-12*math.Max(1.0, math.Abs(next.t)) {
+			// the smaller level dominates
+			if next.level < in.pending[index].level {
+				in.pending[index].level = next.level
+			}
+			continue
+		}
+		in.pending = append(in.pending, discontinuity{})
+		copy(in.pending[index+1:], in.pending[index:])
+		in.pending[index] = next
+	}
+}
+
+// locate finds the first time in (t, tNew) at which a state dependent delayed argument
+// passes a reached discontinuity, using the interpolant of the last step
+func (in *integration) locate(t, tNew float64, yNew []float64) (crossing discontinuity, found bool) {
+	step := in.history.steps[len(in.history.steps)-1]
+	crossing.t = tNew
+
+	for j := 0; j < int(in.VariableDelayCount); j++ {
+		// delayed argument minus the discontinuity
+		argument := func(s float64, y []float64) float64 {
+			in.VariableDelays(s, y, in.delays)
+			return s - in.delays[j]
+		}
+		start, end := argument(t, in.yStart), argument(tNew, yNew)
+
+		for _, point := range in.reached {
+			if point.level >= in.maxLevel || (start-point.t)*(end-point.t) >= 0.0 {
+				continue
+			}
+
+			low, high := t, tNew
+			for k := 0; k < maxBisections && high-low > 1e-14*math.Max(1.0, math.Abs(high)); k++ {
+				middle := 0.5 * (low + high)
+				step.Evaluate(middle, in.yCheck)
+				if (argument(middle, in.yCheck)-point.t)*(start-point.t) > 0.0 {
+					low = middle
+				} else {
+					high = middle
+				}
+			}
+			if high < crossing.t {
+				crossing = discontinuity{t: high, level: point.level + 1}
+				found = true
+			}
+		}
+	}
+	return
+}
+
+func minValue(values []float64) float64 {
+	result := math.Inf(1)
+	for _, v := range values {
+		result = math.Min(result, v)
+	}
+	return result
+}
+
+func maxValue(values []float64) float64 {
+	result := math.Inf(-1)
+	for _, v := range values {
+		result = math.Max(result, v)
+	}
+	return result
+}
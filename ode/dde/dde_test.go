@@ -0,0 +1,114 @@
+package dde
+
+import (
+	"errors"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/adams"
+	"github.com/rollingthunder/differential/ode/rk"
+	"math"
+	"testing"
+)
+
+func TestConstantDelay(t *testing.T) {
+	// y'(t) = -y(t - 1), y = 1 for t <= 0, the solution is a piecewise polynomial
+	// whose derivatives jump at t = 0, 1, 2
+	config := DDEConfig{
+		Config: Config{AbsoluteTolerance: 1e-8},
+		Delayed: func(t float64, y []float64, yDelayed [][]float64, dy []float64) {
+			dy[0] = -yDelayed[0][0]
+		},
+		History: func(t float64, y []float64) { y[0] = 1.0 },
+		Delays:  []float64{1.0},
+	}
+	exact := 1.0 - 3.0 + 4.0/2.0 - 1.0/6.0
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	d, err := NewDDE(dopri)
+	if err != nil {
+		t.Fatalf("Couldn't create DDE integrator - %s", err.Error())
+	}
+
+	var steps []Interpolant
+	config.DenseOutput = func(step Interpolant) { steps = append(steps, step) }
+
+	y := []float64{1.0}
+	stat, err := d.IntegrateDDE(0, 3, y, &config)
+	if err != nil {
+		t.Fatalf("%s: Integration failed - %s", d.Info().Name, err.Error())
+	}
+	if math.Abs(y[0]-exact) > 1e-7 {
+		t.Errorf("%s: result %g differs from solution %g", d.Info().Name, y[0], exact)
+	}
+
+	// the discontinuities are step boundaries
+	for _, point := range []float64{1, 2} {
+		hit := false
+		for _, step := range steps {
+			if t0, _ := step.Interval(); math.Abs(t0-point) < 1e-12 {
+				hit = true
+			}
+		}
+		if !hit {
+			t.Errorf("%s: no step starts at the discontinuity %g", d.Info().Name, point)
+		}
+	}
+	if testing.Verbose() {
+		t.Logf("%s: %d steps, %d rejected, %d evaluations", d.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+	}
+}
+
+func TestStateDependentDelay(t *testing.T) {
+	// y'(t) = y(t) y(ln(y(t))) / t for t >= 1, y = 1 for t <= 1
+	// the solution is t on [1, e] and exp(t / e) on [e, e^2]
+	config := DDEConfig{
+		Config: Config{AbsoluteTolerance: 1e-9, RelativeTolerance: 1e-9},
+		Delayed: func(t float64, y []float64, yDelayed [][]float64, dy []float64) {
+			dy[0] = y[0] * yDelayed[0][0] / t
+		},
+		History: func(t float64, y []float64) { y[0] = 1.0 },
+		VariableDelays: func(t float64, y []float64, delays []float64) {
+			delays[0] = t - math.Log(y[0])
+		},
+		VariableDelayCount: 1,
+	}
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	d, _ := NewDDE(dopri)
+
+	y := []float64{1.0}
+	stat, err := d.IntegrateDDE(1, math.E*math.E, y, &config)
+	if err != nil {
+		t.Fatalf("%s: Integration failed - %s", d.Info().Name, err.Error())
+	}
+	if exact := math.Exp(math.E); math.Abs(y[0]-exact) > 1e-6*exact {
+		t.Errorf("%s: result %g differs from solution %g", d.Info().Name, y[0], exact)
+	}
+	if testing.Verbose() {
+		t.Logf("%s: %d steps, %d rejected, %d evaluations", d.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+	}
+}
+
+func TestDDEConfig(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	d, _ := NewDDE(dopri)
+	delayed := func(t float64, y []float64, yDelayed [][]float64, dy []float64) { dy[0] = -yDelayed[0][0] }
+	history := func(t float64, y []float64) { y[0] = 1.0 }
+
+	invalid := []DDEConfig{
+		{History: history, Delays: []float64{1}},
+		{Delayed: delayed, Delays: []float64{1}},
+		{Delayed: delayed, History: history, Delays: []float64{0}},
+		{Delayed: delayed, History: history, VariableDelays: func(t float64, y []float64, delays []float64) {}},
+	}
+	for k, config := range invalid {
+		if _, err := d.IntegrateDDE(0, 1, []float64{1}, &config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Configuration %d: expected configuration error, got %v", k, err)
+		}
+	}
+
+	// the base integrator has to provide dense output
+	a, _ := adams.NewAdams(adams.PECE)
+	if _, err := NewDDE(a); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error without dense output, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package dde
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"sort"
+)
+
+// history of the solution: the history function before t0 and
+// the interpolants of the accepted steps after t0
+type history struct {
+	t0       float64
+	y0       []float64
+	function HistoryFunction
+
+	steps []Interpolant
+	// ends of the steps for the binary search
+	ends []float64
+}
+
+func (h *history) add(step Interpolant) {
+	_, t1 := step.Interval()
+	h.steps = append(h.steps, step)
+	h.ends = append(h.ends, t1)
+}
+
+// truncate removes all steps after the first count
+func (h *history) truncate(count int) {
+	h.steps, h.ends = h.steps[:count], h.ends[:count]
+}
+
+// drop removes the steps that end before t
+func (h *history) drop(t float64) {
+	count := sort.SearchFloat64s(h.ends, t)
+	if count == 0 {
+		return
+	}
+	h.steps = append(h.steps[:0], h.steps[count:]...)
+	h.ends = append(h.ends[:0], h.ends[count:]...)
+}
+
+// evaluate computes the solution at t, times after the last step are extrapolated
+func (h *history) evaluate(t float64, y_out []float64) {
+	if t < h.t0 {
+		h.function(t, y_out)
+		return
+	}
+	if len(h.steps) == 0 {
+		copy(y_out, h.y0)
+		return
+	}
+
+	index := sort.SearchFloat64s(h.ends, t)
+	if index == len(h.steps) {
+		index--
+	}
+	h.steps[index].Evaluate(t, y_out)
+}
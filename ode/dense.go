@@ -0,0 +1,46 @@
+package ode
+
+// Interpolant approximates the solution within one accepted step,
+// it remains valid after the step
+type Interpolant interface {
+	// Interval returns the step [t0, t1]
+	Interval() (t0, t1 float64)
+	// Evaluate computes the approximation at t, which should lie within the step
+	Evaluate(t float64, y_out []float64)
+}
+
+// HermiteInterpolant is the cubic Hermite interpolant of the solution values and
+// derivatives at both ends of a step, it is of third order
+type HermiteInterpolant struct {
+	T0, T1 float64
+	Y0, Y1 []float64
+	F0, F1 []float64
+}
+
+// NewHermiteInterpolant copies the values and derivatives of the step [t0, t1]
+func NewHermiteInterpolant(t0, t1 float64, y0, y1, f0, f1 []float64) *HermiteInterpolant {
+	h := &HermiteInterpolant{T0: t0, T1: t1}
+	h.Y0 = append([]float64(nil), y0...)
+	h.Y1 = append([]float64(nil), y1...)
+	h.F0 = append([]float64(nil), f0...)
+	h.F1 = append([]float64(nil), f1...)
+	return h
+}
+
+func (h *HermiteInterpolant) Interval() (t0, t1 float64) {
+	return h.T0, h.T1
+}
+
+func (h *HermiteInterpolant) Evaluate(t float64, y_out []float64) {
+	step := h.T1 - h.T0
+	theta := (t - h.T0) / step
+
+	// Hermite basis polynomials
+	h00 := (1.0 + 2.0*theta) * (1.0 - theta) * (1.0 - theta)
+	h10 := theta * (1.0 - theta) * (1.0 - theta)
+	h01 := theta * theta * (3.0 - 2.0*theta)
+	h11 := theta * theta * (theta - 1.0)
+	for id := range y_out {
+		y_out[id] = h00*h.Y0[id] + step*h10*h.F0[id] + h01*h.Y1[id] + step*h11*h.F1[id]
+	}
+}
@@ -1,19 +1,32 @@
 package epp
 
 import (
-	"errors"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
 	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/util"
 	"math"
 )
 
-type peer struct {
+// GenericPeer integrates with an explicit peer method over the floating point type T.
+// The matrices derived from the nodes and B are computed at the precision of the initial values.
+// Time and the error control stay float64 as in rk.GenericRK
+type GenericPeer[T precision.Real[T]] struct {
 	IntegratorInfo
 	method PeerMethod
 	peerCoefficients
+	// coefficients at the precision of the zero value of T
+	coefficients coefficients[T]
+}
+
+// peer is the float64 integrator, it computes with ad.Float on the memory of the caller
+type peer struct {
+	generic *GenericPeer[ad.Float]
 }
 
+// peerCoefficients holds a method as given: the nodes c and the matrix B,
+// whose float64 values are taken as exact
 type peerCoefficients struct {
 	indexMinNode, indexMaxNode uint
 
@@ -24,40 +37,174 @@ type peerCoefficients struct {
 	// ((1+a)^p-a^p)/est+a^p)^(1/p)-a
 	errorModelA float64
 	// a0 = a^p
-	errorModelA0      float64
-	errorModelWeights []float64
+	errorModelA0 float64
+
+	c []float64
+	b [][]float64
+}
 
-	c             []float64
-	b, a0, cv, pv [][]float64
+// coefficients holds B with exact row sums and the matrices derived from it at the precision of T
+type coefficients[T precision.Real[T]] struct {
+	errorModelWeights []T
+
+	b, a0, cv, pv [][]T
+}
+
+// evaluation provides the right hand side and the starting values to the integration,
+// the stages may be evaluated in blocks
+type evaluation[T precision.Real[T]] struct {
+	fcn, blocked ad.Fcn[T]
+	// start computes the solution at each of the times, which are t plus the offsets, from yT at t
+	start func(t float64, yT []T, times []float64, offsets []T, values [][]T, c *Config) (Statistics, error)
 }
 
-type integration struct {
+type integration[T precision.Real[T]] struct {
 	Config
 	Statistics
-	fOld, fNew, yOld, yNew, pa                                                 [][]float64
+	evaluation[T]
+	*coefficients[T]
+	fOld, fNew, yOld, yNew, pa                                                 [][]T
 	errorFactors                                                               []float64
 	tCurrent, stepRatioMin, stepRatio, stepEstimate, stepCurrent, stepPrevious float64
-	n                                                                          uint
+	// the time and the step sizes at the precision of T
+	tCurrentT, stepRatioT, stepCurrentT, stepPreviousT T
+	n                                                  uint
 }
 
-type computationStep func(*peer, *integration)
+type computationStep[T precision.Real[T]] func(*GenericPeer[T], *integration[T])
+
+func (p *peer) Info() IntegratorInfo {
+	return p.generic.Info()
+}
 
 func (p *peer) Integrate(t, tEnd float64, yT []float64, cfg *Config) (s Statistics, err error) {
-	err = cfg.ValidateAndPrepare(uint(len(yT)))
+	return p.generic.integrate(t, tEnd, precision.Float64s(yT), cfg, p.evaluator)
+}
+
+// evaluator provides the right hand side of c and its Starter, DOPRI by default
+func (p *peer) evaluator(c *Config) evaluation[ad.Float] {
+	fcn := func(t float64, yT, dy_out []ad.Float) {
+		c.Fcn(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+	}
+	blocked := func(t float64, yT, dy_out []ad.Float) {
+		c.EvaluateBlocked(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+	}
+	start := func(t float64, yT []ad.Float, times []float64, offsets []ad.Float, values [][]ad.Float, c *Config) (Statistics, error) {
+		starter := c.Starter
+		if starter == nil {
+			// startup with DOPRI
+			dopri, err := rk.NewRK(rk.DoPri5)
+			if err != nil {
+				return Statistics{}, err
+			}
+			starter = &IntegratorStarter{Integrator: dopri}
+		}
+		rows := make([][]float64, len(values))
+		for i := range rows {
+			rows[i] = precision.ToFloat64(values[i])
+		}
+		return starter.Start(t, precision.ToFloat64(yT), times, rows, c)
+	}
+	return evaluation[ad.Float]{fcn: fcn, blocked: blocked, start: start}
+}
+
+// Integrate advances yT from t to tEnd with the right hand side fcn, the starting values
+// are computed with rk.GenericRK, Fcn, FcnBlocked, BlockSize and Starter of config are ignored
+func (p *GenericPeer[T]) Integrate(t, tEnd float64, yT []T, fcn ad.Fcn[T], cfg *Config) (s Statistics, err error) {
+	if fcn == nil {
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+	if cfg == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+
+	var prototype T
+	if len(yT) > 0 {
+		prototype = yT[0]
+	}
+	adapted := *cfg
+	adapted.Fcn, adapted.FcnBlocked, adapted.BlockSize = ad.Function(fcn, prototype), nil, 0
+	adapted.Starter = nil
+	return p.integrate(t, tEnd, yT, &adapted, func(c *Config) evaluation[T] {
+		return evaluation[T]{fcn: fcn, blocked: fcn, start: startFunction(fcn)}
+	})
+}
+
+// startFunction integrates from t to t + offset over s in [0, 1] with the right hand side
+// offset fcn(t + s offset, y), so the starting values are exactly at the offsets of the nodes
+func startFunction[T precision.Real[T]](fcn ad.Fcn[T]) func(float64, []T, []float64, []T, [][]T, *Config) (Statistics, error) {
+	return func(t float64, yT []T, times []float64, offsets []T, values [][]T, c *Config) (stat Statistics, err error) {
+		dopri, err := rk.NewGenericRK[T](rk.DoPri5)
+		if err != nil {
+			return
+		}
+		// the tolerances of IntegratorStarter without its float64 limit
+		startConfig := Config{RelativeTolerance: 0.1 * c.RelativeTolerance, AbsoluteTolerance: 0.1 * c.AbsoluteTolerance, NonFinitePolicy: c.NonFinitePolicy,
+			MaxStepCount: c.MaxStepCount, Cancel: c.Cancel}
+		for i, offset := range offsets {
+			copy(values[i], yT)
+			if offset.Real() == 0.0 {
+				continue
+			}
+			// the step size limits in units of s
+			startConfig.MinStepSize = c.MinStepSize / math.Abs(offset.Real())
+			startConfig.MaxStepSize = c.MaxStepSize / math.Abs(offset.Real())
+			startConfig.InitialStepSize = math.Min(1.0, startConfig.MaxStepSize)
+			scaled := func(s float64, yT, dy_out []T) {
+				fcn(t+s*offset.Real(), yT, dy_out)
+				for id := range dy_out {
+					dy_out[id] = dy_out[id].Mul(offset)
+				}
+			}
+			var startStat Statistics
+			startStat, err = dopri.Integrate(0.0, 1.0, values[i], scaled, &startConfig)
+			stat.StepCount += startStat.StepCount
+			stat.RejectedCount += startStat.RejectedCount
+			stat.EvaluationCount += startStat.EvaluationCount
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+}
+
+func (p *GenericPeer[T]) integrate(t, tEnd float64, yT []T, cfg *Config, evaluator func(c *Config) evaluation[T]) (s Statistics, err error) {
+	effective, err := cfg.ValidateAndPrepare(uint(len(yT)), t, tEnd)
 
 	if err != nil {
 		return
 	}
+	if err = p.Supports(&effective); err != nil {
+		return
+	}
 
-	in := p.setupIntegration(t, tEnd, yT, cfg)
+	in := p.setupIntegration(yT, &effective)
+	in.evaluation = evaluator(&in.Config)
 
-	in.tCurrent, in.stepPrevious = p.startupIntegration(&in, t)
+	in.tCurrent, in.stepPrevious, err = p.startupIntegration(&in, t)
+	if err != nil {
+		in.Effective = in.Config
+		s = in.Statistics
+		return
+	}
 	in.stepEstimate = in.stepPrevious // continue with stepsize stepPrevious
+	tEndT := yT[0].Const(tEnd)
 
 	// repeat until tend
 	for in.tCurrent < (tEnd - in.AbsoluteTolerance) {
+		if in.Cancelled() {
+			err = &CancelledError{Time: in.tCurrent}
+			break
+		}
+
 		if in.tCurrent+in.stepEstimate > tEnd {
 			in.stepEstimate = tEnd - in.tCurrent
+			in.stepCurrentT = tEndT.Sub(in.tCurrentT)
+		} else {
+			in.stepCurrentT = yT[0].Const(in.stepEstimate)
 		}
 		in.stepCurrent = in.stepEstimate
 		in.StepCount++
@@ -70,6 +217,16 @@ func (p *peer) Integrate(t, tEnd float64, yT []float64, cfg *Config) (s Statisti
 
 		errorEstimate := p.computeErrorModel(&in)
 
+		if !util.IsFinite(errorEstimate) {
+			// NaN or Inf in the stages
+			if in.NonFinitePolicy == AbortOnNonFinite {
+				err = &NonFiniteError{Time: in.tCurrent, StepSize: in.stepCurrent, Index: p.firstNonFinite(&in)}
+				break
+			}
+			errorEstimate = math.Inf(1)
+			in.stepEstimate = in.stepPrevious * in.stepRatioMin
+		}
+
 		if errorEstimate > 1.0 {
 			// reject step
 			// decrease minimal stepsize ratio
@@ -78,7 +235,7 @@ func (p *peer) Integrate(t, tEnd float64, yT []float64, cfg *Config) (s Statisti
 
 			// report failure
 			if in.stepEstimate < in.MinStepSize {
-				err = errors.New("step size too small")
+				err = &StepSizeError{Time: in.tCurrent, StepSize: in.stepEstimate}
 				break
 			}
 		} else {
@@ -96,11 +253,13 @@ func (p *peer) Integrate(t, tEnd float64, yT []float64, cfg *Config) (s Statisti
 
 			in.tCurrent += in.stepCurrent
 			in.stepPrevious = in.stepCurrent
+			in.tCurrentT = in.tCurrentT.Add(in.stepCurrentT)
+			in.stepPreviousT = in.stepCurrentT
 		}
 
 		// failure, too many steps
 		if in.StepCount > in.MaxStepCount {
-			err = errors.New("maximum step count exceeded")
+			err = &MaxStepsError{Time: in.tCurrent, StepCount: in.StepCount}
 			break
 		}
 	}
@@ -110,41 +269,31 @@ func (p *peer) Integrate(t, tEnd float64, yT []float64, cfg *Config) (s Statisti
 	in.CurrentTime = in.tCurrent
 	in.LastStepSize = in.stepPrevious
 	in.NextStepSize = in.stepEstimate
+	in.Effective = in.Config
 
 	s = in.Statistics
 	return
 }
 
-func (p *peer) setupIntegration(t, tEnd float64, yT []float64, c *Config) (i integration) {
+func (p *GenericPeer[T]) setupIntegration(yT []T, c *Config) (i integration[T]) {
 	i.n = uint(len(yT))
+	i.Config = *c
 
-	// set default parameters if necessary
-	if c.MaxStepSize <= 0.0 {
-		c.MaxStepSize = tEnd - t
-	}
-	if c.MinStepSize <= 0.0 {
-		c.MinStepSize = 1e-10
-	}
-	if c.MaxStepCount == 0 {
-		c.MaxStepCount = 1000000
+	// round the coefficients again if the initial values differ in precision from the zero value
+	i.coefficients = &p.coefficients
+	if yT[0].Precision() != i.b[0][0].Precision() {
+		rounded := roundCoefficients(&p.peerCoefficients, p.Stages, yT[0])
+		i.coefficients = &rounded
 	}
-	if c.AbsoluteTolerance <= 0.0 {
-		c.AbsoluteTolerance = 1e-4
-	}
-	if c.RelativeTolerance <= 0.0 {
-		c.RelativeTolerance = c.AbsoluteTolerance
-	}
-
-	i.Config = *c
 
 	// allocate temp matrices
 	i.errorFactors = make([]float64, i.n)
-	i.pa = util.MakeSquare(p.Stages)
+	i.pa = makeMatrix[T](p.Stages, p.Stages)
 
-	i.yNew = util.MakeRectangular(p.Stages, i.n)
-	i.yOld = util.MakeRectangular(p.Stages, i.n)
-	i.fNew = util.MakeRectangular(p.Stages, i.n)
-	i.fOld = util.MakeRectangular(p.Stages, i.n)
+	i.yNew = makeMatrix[T](p.Stages, i.n)
+	i.yOld = makeMatrix[T](p.Stages, i.n)
+	i.fNew = makeMatrix[T](p.Stages, i.n)
+	i.fOld = makeMatrix[T](p.Stages, i.n)
 
 	copy(i.yOld[p.indexMinNode], yT)
 
@@ -153,75 +302,77 @@ func (p *peer) setupIntegration(t, tEnd float64, yT []float64, c *Config) (i int
 	return
 }
 
-func (p *peer) startupIntegration(in *integration, t0 float64) (tCurrent, stepRelative float64) {
-	// startup with DOPRI
-	dopri, err := rk.NewRK(rk.DoPri5)
-	if err != nil {
-		err = errors.New("error during startup: " + err.Error())
-		return
-	}
-
-	in.Fcn(t0, in.yOld[p.indexMinNode], in.fOld[p.indexMinNode])
+func (p *GenericPeer[T]) startupIntegration(in *integration[T], t0 float64) (tCurrent, stepRelative float64, err error) {
+	yStart := in.yOld[p.indexMinNode]
+	in.fcn(t0, yStart, in.fOld[p.indexMinNode])
 	in.EvaluationCount = 1
 
 	// guess initial step size if unspecified
 	in.stepEstimate = in.InitialStepSize
 	if in.stepEstimate <= 0.0 {
-		in.stepEstimate = EstimateStepSize(t0, in.yOld[p.indexMinNode], in.fOld[p.indexMinNode], &in.Config, p.Order)
-	}
-
-	copy(in.yOld[p.indexMaxNode], in.yOld[p.indexMinNode])
-	tCurrent = t0
-
-	//  higher accuracy for starting proc
-	rkConfig := Config{
-		InitialStepSize:   in.stepEstimate,
-		RelativeTolerance: math.Max(1e-1*in.RelativeTolerance, 1e-14),
-		AbsoluteTolerance: math.Max(1e-1*in.AbsoluteTolerance, 1e-14),
-		OneStepOnly:       true,
-		Fcn:               in.Fcn,
-		FcnBlocked:        in.FcnBlocked,
-	}
-
-	rkStat, err := dopri.Integrate(tCurrent, t0+in.stepEstimate, in.yOld[p.indexMaxNode], &rkConfig)
-	if err != nil {
-		err = errors.New("error during startup: " + err.Error())
-		return
+		in.stepEstimate = EstimateStepSize(t0, precision.ToFloat64(yStart), precision.ToFloat64(in.fOld[p.indexMinNode]), &in.Config, p.Order)
 	}
-	tCurrent = rkStat.CurrentTime
-
-	in.EvaluationCount += rkStat.EvaluationCount
-	in.Fcn(tCurrent, in.yOld[p.indexMaxNode], in.fOld[p.indexMaxNode])
-	in.EvaluationCount++
 
 	// adjusted step size, relative to interval [0,1]:
-	stepRelative = (tCurrent - t0) / (p.c[p.indexMaxNode] - p.c[p.indexMinNode])
+	stepRelative = in.stepEstimate / (p.c[p.indexMaxNode] - p.c[p.indexMinNode])
 
 	tBase := t0 - stepRelative*p.c[p.indexMinNode] // corresponds to node pc=0
 
+	// the same at the precision of T
+	in.stepPreviousT = yStart[0].Const(stepRelative)
+	cMin := yStart[0].Const(p.c[p.indexMinNode])
+	tBaseT := yStart[0].Const(t0).Sub(in.stepPreviousT.Mul(cMin))
+
 	// startup procedure
+	times := make([]float64, 0, p.Stages-1)
+	offsets := make([]T, 0, p.Stages-1)
+	values := make([][]T, 0, p.Stages-1)
 	var stg uint
 	for stg = 0; stg < p.Stages; stg++ {
-		if stg != p.indexMinNode && stg != p.indexMaxNode {
-			copy(in.yOld[stg], in.yOld[p.indexMinNode])
-			rkConfig.InitialStepSize = stepRelative * (p.c[stg] - p.c[p.indexMinNode])
-			tStage := tBase + stepRelative*p.c[stg]
-			rkStat, err = dopri.Integrate(t0, tStage, in.yOld[stg], &rkConfig)
-			if err != nil {
-				err = errors.New("error during startup: " + err.Error())
-				return
-			}
-			in.Fcn(tStage, in.yOld[stg], in.fOld[stg])
-			in.EvaluationCount += rkStat.EvaluationCount + 1
+		if stg != p.indexMinNode {
+			times = append(times, tBase+stepRelative*p.c[stg])
+			offsets = append(offsets, in.stepPreviousT.Mul(yStart[0].Const(p.c[stg]).Sub(cMin)))
+			values = append(values, in.yOld[stg])
+		}
+	}
+
+	startStat, err := in.start(t0, yStart, times, offsets, values, &in.Config)
+	in.EvaluationCount += startStat.EvaluationCount
+	if err != nil {
+		err = &StartupError{Err: err}
+		return
+	}
+
+	for stg = 0; stg < p.Stages; stg++ {
+		if stg != p.indexMinNode {
+			in.fcn(tBase+stepRelative*p.c[stg], in.yOld[stg], in.fOld[stg])
+			in.EvaluationCount++
 		}
 	}
+	in.StartupEvaluationCount = in.EvaluationCount
 
 	tCurrent = tBase + stepRelative
+	in.tCurrentT = tBaseT.Add(in.stepPreviousT)
 	return
 }
 
-func (p *peer) computeCoefficients(in *integration) {
+// Finds the first component with a non-finite stage value or evaluation
+func (p *GenericPeer[T]) firstNonFinite(in *integration[T]) int {
+	var stg uint
+	for stg = 0; stg < p.Stages; stg++ {
+		if index := util.FirstNonFinite(precision.ToFloat64(in.yNew[stg])); index >= 0 {
+			return index
+		}
+		if index := util.FirstNonFinite(precision.ToFloat64(in.fNew[stg])); index >= 0 {
+			return index
+		}
+	}
+	return -1
+}
+
+func (p *GenericPeer[T]) computeCoefficients(in *integration[T]) {
 	in.stepRatio = in.stepCurrent / in.stepPrevious
+	in.stepRatioT = in.stepCurrentT.Div(in.stepPreviousT)
 
 	// COMPUTE COEFFS -> "Co" Prefix
 	// stepPrevious*A row-wise
@@ -231,21 +382,21 @@ func (p *peer) computeCoefficients(in *integration) {
 	for stg = 0; stg < p.Stages; stg++ {
 		/*@; BEGIN(CoA0=Nest) @*/
 		for ic = 0; ic < p.Stages; ic++ {
-			in.pa[stg][ic] = in.stepPrevious * p.a0[stg][ic]
+			in.pa[stg][ic] = in.stepPreviousT.Mul(in.a0[stg][ic])
 		}
 
-		stepStage := in.stepPrevious
+		stepStage := in.stepPreviousT
 		/*@; BEGIN(CoA1=Nest) @*/
 		for ic = 0; ic < p.Stages; ic++ {
-			stepStage *= in.stepRatio
+			stepStage = stepStage.Mul(in.stepRatioT)
 			for id = 0; id < p.Stages; id++ {
-				in.pa[stg][id] += p.cv[stg][ic] * stepStage * p.pv[ic][id]
+				in.pa[stg][id] = in.pa[stg][id].Add(in.cv[stg][ic].Mul(stepStage).Mul(in.pv[ic][id]))
 			}
 		}
 	}
 }
 
-func (p *peer) computeStages(in *integration) {
+func (p *GenericPeer[T]) computeStages(in *integration[T]) {
 	// STAGE SOLUTIONS -> "St" Prefix
 	var j_stg, k_stg, i_n uint
 	// Loops: StA, StB
@@ -254,9 +405,9 @@ func (p *peer) computeStages(in *integration) {
 	for i_n = 0; i_n < in.n; i_n++ {
 		for j_stg = 0; j_stg < p.Stages; j_stg++ {
 			// Init once
-			in.yNew[j_stg][i_n] = 0.0
-			for k_stg = 0; k_stg < p.Stages; k_stg++ {
-				in.yNew[j_stg][i_n] += p.b[j_stg][k_stg] * in.yOld[k_stg][i_n]
+			in.yNew[j_stg][i_n] = in.b[j_stg][0].Mul(in.yOld[0][i_n])
+			for k_stg = 1; k_stg < p.Stages; k_stg++ {
+				in.yNew[j_stg][i_n] = in.yNew[j_stg][i_n].Add(in.b[j_stg][k_stg].Mul(in.yOld[k_stg][i_n]))
 			}
 		}
 	}
@@ -265,47 +416,46 @@ func (p *peer) computeStages(in *integration) {
 	for i_n = 0; i_n < in.n; i_n++ {
 		for j_stg = 0; j_stg < p.Stages; j_stg++ {			
 			for k_stg = 0; k_stg < p.Stages; k_stg++ {
-				in.yNew[j_stg][i_n] += in.pa[j_stg][k_stg] * in.fOld[k_stg][i_n]
+				in.yNew[j_stg][i_n] = in.yNew[j_stg][i_n].Add(in.pa[j_stg][k_stg].Mul(in.fOld[k_stg][i_n]))
 			}
 		}
 	}
 }
 
-func (p *peer) computeEvaluations(in *integration) {
+func (p *GenericPeer[T]) computeEvaluations(in *integration[T]) {
 	// FUNCTION EVALUATIONS
 	// Fn=fcn(Yn)
-	var stg, block uint
+	var stg uint
 	// Candidate for Parallelisation
 	for stg = 0; stg < p.Stages; stg++ {
-		for block = 0; block < in.n; block += in.BlockSize {
-			in.FcnBlocked(block, in.BlockSize, in.tCurrent+in.stepEstimate*p.c[stg], in.yNew[stg], in.fNew[stg])
-		}
+		in.blocked(in.tCurrent+in.stepEstimate*p.c[stg], in.yNew[stg], in.fNew[stg])
 	}
 
 	in.EvaluationCount += p.Stages
 }
 
 // Computes the error estimate based on fNew:
-func (p *peer) computeErrorModel(in *integration) (errorEstimate float64) {
+func (p *GenericPeer[T]) computeErrorModel(in *integration[T]) (errorEstimate float64) {
 	var i_n, j_stg uint
 
 	// Loop: EmFactors
 	for i_n = 0; i_n < in.n; i_n++ {
-		var factor float64 = 0.0
-		for j_stg = 0; j_stg < p.Stages; j_stg++ {
-			factor += p.errorModelWeights[j_stg] * in.fNew[j_stg][i_n]
+		factor := in.errorModelWeights[0].Mul(in.fNew[0][i_n])
+		for j_stg = 1; j_stg < p.Stages; j_stg++ {
+			factor = factor.Add(in.errorModelWeights[j_stg].Mul(in.fNew[j_stg][i_n]))
 		}
-		in.errorFactors[i_n] = math.Pow(factor/(in.AbsoluteTolerance+in.RelativeTolerance*math.Abs(in.yOld[p.Stages-1][i_n])), 2.0)
+		in.errorFactors[i_n] = math.Pow(factor.Real()/(in.AbsoluteTolerance+in.RelativeTolerance*math.Abs(in.yOld[p.Stages-1][i_n].Real())), 2.0)
 	}
 
 	// compute error quotient/20070803
 	// step ratio from error model ((1+a)^p-a^p)/est+a^p)^(1/p)-a, p=order/2:
 	errorRelative := 0.0
-	for i_n = 0; i_n < in.n; i_n++ {
+	errorCount := uint(in.ErrorCount(int(in.n)))
+	for i_n = 0; i_n < errorCount; i_n++ {
 		errorRelative += in.errorFactors[i_n]
 	}
 
-	errorEstimate = in.stepEstimate*math.Sqrt(errorRelative/float64(in.n)) + 1e-8
+	errorEstimate = in.stepEstimate*math.Sqrt(errorRelative/float64(errorCount)) + 1e-8
 	errorModelDenom := math.Pow(math.Pow(in.stepRatio, 2.0)+p.errorModelA, float64(p.Order)/2.0) - p.errorModelA0
 	errorStepRatio := math.Pow(errorModelDenom/errorEstimate+p.errorModelA0, 2.0/float64(p.Order)) - p.errorModelA
 	in.stepEstimate = in.stepPrevious * math.Max(in.stepRatioMin, math.Min(0.95*math.Sqrt(errorStepRatio), p.stepRatioMax)) // safety interval
@@ -316,7 +466,7 @@ func (p *peer) computeErrorModel(in *integration) (errorEstimate float64) {
 
 // Manually transformed Parts
 
-func (p *peer) computeStages_FuseAB(in *integration) {
+func (p *GenericPeer[T]) computeStages_FuseAB(in *integration[T]) {
 	// STAGE SOLUTIONS -> "St" Prefix
 	var j_stg, k_stg, i_n uint
 	// Loops: StA, StB
@@ -325,18 +475,19 @@ func (p *peer) computeStages_FuseAB(in *integration) {
 	for i_n = 0; i_n < in.n; i_n++ {
 		for j_stg = 0; j_stg < p.Stages; j_stg++ {
 			// Init once
-			in.yNew[j_stg][i_n] = 0.0
+			y := in.yNew[j_stg][i_n].Const(0.0)
 
 			// Accumulation (Reduction) -> parallelization?
 			for k_stg = 0; k_stg < p.Stages; k_stg++ {
-				in.yNew[j_stg][i_n] += p.b[j_stg][k_stg] * in.yOld[k_stg][i_n]
-				in.yNew[j_stg][i_n] += in.pa[j_stg][k_stg] * in.fOld[k_stg][i_n]
+				y = y.Add(in.b[j_stg][k_stg].Mul(in.yOld[k_stg][i_n]))
+				y = y.Add(in.pa[j_stg][k_stg].Mul(in.fOld[k_stg][i_n]))
 			}
+			in.yNew[j_stg][i_n] = y
 		}
 	}
 }
 
-func (p *peer) computeStages_FuseAB_ExchangeIJ(in *integration) {
+func (p *GenericPeer[T]) computeStages_FuseAB_ExchangeIJ(in *integration[T]) {
 	// STAGE SOLUTIONS -> "St" Prefix
 	var j_stg, k_stg, i_n uint
 	// Loops: StA, StB
@@ -345,13 +496,14 @@ func (p *peer) computeStages_FuseAB_ExchangeIJ(in *integration) {
 	for j_stg = 0; j_stg < p.Stages; j_stg++ {
 		for i_n = 0; i_n < in.n; i_n++ {
 			// Init once
-			in.yNew[j_stg][i_n] = 0.0
+			y := in.yNew[j_stg][i_n].Const(0.0)
 
 			// Accumulation (Reduction) -> parallelization?
 			for k_stg = 0; k_stg < p.Stages; k_stg++ {
-				in.yNew[j_stg][i_n] += p.b[j_stg][k_stg] * in.yOld[k_stg][i_n]
-				in.yNew[j_stg][i_n] += in.pa[j_stg][k_stg] * in.fOld[k_stg][i_n]
+				y = y.Add(in.b[j_stg][k_stg].Mul(in.yOld[k_stg][i_n]))
+				y = y.Add(in.pa[j_stg][k_stg].Mul(in.fOld[k_stg][i_n]))
 			}
+			in.yNew[j_stg][i_n] = y
 		}
 	}
 }
@@ -2,20 +2,23 @@ package epp
 
 import (
 	"fmt"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/problems"
 	"github.com/rollingthunder/differential/util"
 	"strconv"
 	"testing"
 )
 
-func setupBruss() (p *peer, in integration, y0 []float64) {
+func setupBruss() (p *GenericPeer[ad.Float], in integration[ad.Float], y0 []float64) {
 	return setupBenchmark(problems.NewBruss2D(200))
 }
 
-func setupBenchmark(prob problems.TiledProblem) (p *peer, in integration, y0 []float64) {
+func setupBenchmark(prob problems.TiledProblem) (p *GenericPeer[ad.Float], in integration[ad.Float], y0 []float64) {
 	integrator, _ := NewPeer(EPP4y3)
-	p = integrator.(*peer)
+	wrapper := integrator.(*peer)
+	p = wrapper.generic
 
 	y0 = prob.Initialize()
 
@@ -23,10 +26,11 @@ func setupBenchmark(prob problems.TiledProblem) (p *peer, in integration, y0 []f
 		Fcn: prob.Fcn,
 	}
 
-	cfg.ValidateAndPrepare(uint(len(y0)))
+	effective, _ := cfg.ValidateAndPrepare(uint(len(y0)), 0.0, 1.0)
 
-	in = p.setupIntegration(0.0, 1.0, y0, &cfg)
-	in.tCurrent, in.stepPrevious = p.startupIntegration(&in, 0.0)
+	in = p.setupIntegration(precision.Float64s(y0), &effective)
+	in.evaluation = wrapper.evaluator(&in.Config)
+	in.tCurrent, in.stepPrevious, _ = p.startupIntegration(&in, 0.0)
 	in.stepEstimate = in.stepPrevious
 	return
 }
@@ -45,7 +49,7 @@ var problemVariants = []struct {
 	Name        string
 	Constructor func(uint) problems.TiledProblem
 }{
-	{"Bruss2D", problems.NewBruss2D},
+	{"Bruss2D", func(n uint) problems.TiledProblem { return problems.NewBruss2D(n) }},
 }
 
 var sizeVariants = []uint{
@@ -57,10 +61,10 @@ var sizeVariants = []uint{
 
 type namedImplementation struct {
 	Name string
-	Impl computationStep
+	Impl computationStep[ad.Float]
 }
 
-func benchmarkComputationStep(stepName string, prepareIntegration computationStep, implementations []namedImplementation) {
+func benchmarkComputationStep(stepName string, prepareIntegration computationStep[ad.Float], implementations []namedImplementation) {
 	const TIME string = "Time"
 	const NORMALIZED_TIME string = "Time/n"
 
@@ -109,18 +113,18 @@ func benchmarkComputationStep(stepName string, prepareIntegration computationSte
 		}
 		resultTables = append(resultTables, result)
 	}
-	util.WriteTablesFile(resultTables, fmt.Sprintf("%s.html", stepName))
+	util.WriteTablesHTML(resultTables, fmt.Sprintf("%s.html", stepName))
 }
 
 func TestBenchmarkStages(t *testing.T) {
-	var prepare computationStep = func(p *peer, in *integration) {
+	var prepare computationStep[ad.Float] = func(p *GenericPeer[ad.Float], in *integration[ad.Float]) {
 		p.computeStages(in)
 	}
 
 	var stagesVariants = []namedImplementation{
-		{"Vanilla", (*peer).computeStages},
-		{"FuseAB", (*peer).computeStages_FuseAB},
-		{"FuseAB_ExchangeIJ", (*peer).computeStages_FuseAB_ExchangeIJ},
+		{"Vanilla", (*GenericPeer[ad.Float]).computeStages},
+		{"FuseAB", (*GenericPeer[ad.Float]).computeStages_FuseAB},
+		{"FuseAB_ExchangeIJ", (*GenericPeer[ad.Float]).computeStages_FuseAB_ExchangeIJ},
 	}
 
 	benchmarkComputationStep(
@@ -1,8 +1,9 @@
 package epp
 
 import (
-	"errors"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/util"
 	"math"
 )
@@ -24,19 +25,26 @@ const (
 	NumberOfPeerMethods = uint(iota)
 )
 
-func NewPeer(m PeerMethod) (i Integrator, err error) {
-	var p peer
-	p.method = m
+// NewGenericPeer creates the integrator of method m over the floating point type T
+func NewGenericPeer[T precision.Real[T]](m PeerMethod) (p *GenericPeer[T], err error) {
+	p = &GenericPeer[T]{method: m}
 	err = p.setCoeffs()
 	if err != nil {
-		p = peer{}
+		p = &GenericPeer[T]{}
 	}
-	i = &p
 
 	return
 }
 
-func (p *peer) setCoeffs() (err error) {
+// NewPeer creates the integrator of method m for float64 values
+func NewPeer(m PeerMethod) (i Integrator, err error) {
+	generic, err := NewGenericPeer[ad.Float](m)
+	i = &peer{generic: generic}
+
+	return
+}
+
+func (p *GenericPeer[T]) setCoeffs() (err error) {
 	switch p.method {
 	case EPP2:
 		p.setEPP2Coeffs()
@@ -92,104 +100,161 @@ func (p *peer) setCoeffs() (err error) {
 		p.allocateCoeffs()
 		p.setEPP_x2Coeffs()
 	default:
-		err = errors.New("unknown peer method")
+		err = &ConfigError{Field: "PeerMethod", Reason: "unknown peer method"}
 		return
 	}
 
 	p.findMinMaxNodes()
 
-	p.ensureOneRowSums()
-
 	// auxiliary parameters for local error model
 	p.errorModelA = 0.0 //ausschalten
 	p.errorModelA0 = math.Pow(p.errorModelA, float64(p.Order)/2.0)
 
+	var zero T
+	p.coefficients = roundCoefficients(&p.peerCoefficients, p.Stages, zero)
+
+	return
+}
+
+// roundCoefficients computes B with exact row sums and the matrices derived from the nodes at the precision of prototype
+func roundCoefficients[T precision.Real[T]](pc *peerCoefficients, stages uint, prototype T) (co coefficients[T]) {
+	co.errorModelWeights = make([]T, stages)
+	co.b = makeMatrix[T](stages, stages)
+	co.a0 = makeMatrix[T](stages, stages)
+	co.cv = makeMatrix[T](stages, stages)
+	co.pv = makeMatrix[T](stages, stages)
+
 	var i, j, k uint
 
+	c := make([]T, stages)
+	for i = 0; i < stages; i++ {
+		c[i] = prototype.Const(pc.c[i])
+		for j = 0; j < stages; j++ {
+			co.b[i][j] = prototype.Const(pc.b[i][j])
+		}
+	}
+
+	co.ensureOneRowSums(prototype)
+
 	//compute matrix PCV = diag(C)*VdM
-	for i = 0; i < p.Stages; i++ {
-		p.cv[i][0] = p.c[i]
-		for j = 1; j < p.Stages; j++ {
-			p.cv[i][j] = p.c[i] * p.cv[i][j-1]
+	for i = 0; i < stages; i++ {
+		co.cv[i][0] = c[i]
+		for j = 1; j < stages; j++ {
+			co.cv[i][j] = c[i].Mul(co.cv[i][j-1])
 		}
 	}
 
 	// compute matrix PA0, ppv used as temporary memory
-	for i = 0; i < p.Stages; i++ {
-		for j = 0; j < p.Stages; j++ {
-			p.pv[i][j] = -p.b[i][j]
+	for i = 0; i < stages; i++ {
+		for j = 0; j < stages; j++ {
+			co.pv[i][j] = co.b[i][j].Neg()
 		}
 	}
 
 	// now \ones*e_s^T-B
-	for i = 0; i < p.Stages; i++ {
-		p.pv[i][p.Stages-1] = p.pv[i][p.Stages-1] + 1.0
+	for i = 0; i < stages; i++ {
+		co.pv[i][stages-1] = co.pv[i][stages-1].Add(prototype.Const(1.0))
 	}
 
 	//pa0 = matmul(ppv,pcv)
-	for i = 0; i < p.Stages; i++ {
-		for j = 0; j < p.Stages; j++ {
-			// unnecessary because of go memory initializer
-			// p.a0[i][j] = 0.0
-			for k = 0; k < p.Stages; k++ {
-				p.a0[i][j] = p.a0[i][j] + p.pv[i][k]*p.cv[k][j]
+	for i = 0; i < stages; i++ {
+		for j = 0; j < stages; j++ {
+			co.a0[i][j] = prototype.Const(0.0)
+			for k = 0; k < stages; k++ {
+				co.a0[i][j] = co.a0[i][j].Add(co.pv[i][k].Mul(co.cv[k][j]))
 			}
 		}
 	}
 
 	// scale columns
-	for j = 1; j < p.Stages; j++ {
-		for i = 0; i < p.Stages; i++ {
-			p.a0[i][j] = p.a0[i][j] / (float64(j) + 1.0)
+	for j = 1; j < stages; j++ {
+		for i = 0; i < stages; i++ {
+			co.a0[i][j] = co.a0[i][j].Div(prototype.Const(float64(j) + 1.0))
 		}
 	}
 
-	VanderMonde(p.c, p.a0) // now PA0=(\ones*e_s^T-B)*C*V*(V*D)^(-1)
+	vanderMonde(c, co.a0) // now PA0=(\ones*e_s^T-B)*C*V*(V*D)^(-1)
 
 	// compute matrix PPV:
-	for j = 0; j < p.Stages; j++ {
-		p.pv[0][j] = 1.0
+	for j = 0; j < stages; j++ {
+		co.pv[0][j] = prototype.Const(1.0)
 	}
 
-	for i = 1; i < p.Stages; i++ {
-		p.pv[i][0] = 0.0
-		for j = 0; j < p.Stages-1; j++ {
-			p.pv[i][j+1] = p.pv[i][j] + p.pv[i-1][j]
+	for i = 1; i < stages; i++ {
+		co.pv[i][0] = prototype.Const(0.0)
+		for j = 0; j < stages-1; j++ {
+			co.pv[i][j+1] = co.pv[i][j].Add(co.pv[i-1][j])
 		}
 	}
 
 	// scale rows
-	for i = 0; i < p.Stages; i++ {
-		for j = 0; j < p.Stages; j++ {
-			p.pv[i][j] = p.pv[i][j] / (float64(i) + 1.0)
+	for i = 0; i < stages; i++ {
+		for j = 0; j < stages; j++ {
+			co.pv[i][j] = co.pv[i][j].Div(prototype.Const(float64(i) + 1.0))
 		}
 	}
 
-	VanderMonde(p.c, p.pv) // now PPV=D^(-1)*P*V^(-1)
+	vanderMonde(c, co.pv) // now PPV=D^(-1)*P*V^(-1)
 
 	// error estimate with last row of PPV
-	copy(p.errorModelWeights, p.pv[p.Stages-1])
+	copy(co.errorModelWeights, co.pv[stages-1])
 
 	return
 }
 
+// vanderMonde is ode.VanderMonde at the precision of T
+func vanderMonde[T precision.Real[T]](pc []T, pm [][]T) {
+	var i, j, k int
+	n := len(pc)
+
+	for k = 0; k < n-1; k++ {
+		for j = n - 1; j >= k+1; j-- {
+			for i = 0; i < n; i++ {
+				pm[i][j] = pm[i][j].Sub(pm[i][j-1].Mul(pc[k]))
+			}
+		}
+	}
+
+	for k = n - 2; k >= 0; k-- {
+		for j = k + 1; j < n; j++ {
+			for i = 0; i < n; i++ {
+				pm[i][j] = pm[i][j].Div(pc[j].Sub(pc[j-k-1]))
+			}
+		}
+
+		for j = k; j < n-1; j++ {
+			for i = 0; i < n; i++ {
+				pm[i][j] = pm[i][j].Sub(pm[i][j+1])
+			}
+		}
+	}
+}
+
+func makeMatrix[T any](rows, columns uint) [][]T {
+	m := make([][]T, rows)
+	for i := range m {
+		m[i] = make([]T, columns)
+	}
+	return m
+}
+
 func stagesOf(m PeerMethod) uint {
 	return uint(m) % 10
 }
 
-func (p *peer) ensureOneRowSums() {
+func (co *coefficients[T]) ensureOneRowSums(prototype T) {
 	// row sums of B must be 1 exactly
-	var i, j uint
-	for i = 0; i < p.Stages; i++ {
-		s := 1.0
-		for j = 0; j < p.Stages; j++ {
-			s = s - p.b[i][j]
+	stages := len(co.b)
+	for i := 0; i < stages; i++ {
+		s := prototype.Const(1.0)
+		for j := 0; j < stages; j++ {
+			s = s.Sub(co.b[i][j])
 		}
-		p.b[i][p.Stages-1] = p.b[i][p.Stages-1] + s
+		co.b[i][stages-1] = co.b[i][stages-1].Add(s)
 	}
 }
 
-func (p *peer) findMinMaxNodes() {
+func (p *GenericPeer[T]) findMinMaxNodes() {
 	// ! minimal and maximal nodes:
 	p.indexMinNode = 0
 	p.indexMaxNode = uint(p.Stages) - 1
@@ -205,15 +270,12 @@ func (p *peer) findMinMaxNodes() {
 	}
 }
 
-func (p *peer) allocateCoeffs() {
-	p.c, p.errorModelWeights = make([]float64, p.Stages), make([]float64, p.Stages)
+func (p *GenericPeer[T]) allocateCoeffs() {
+	p.c = make([]float64, p.Stages)
 	p.b = util.MakeSquare(p.Stages)
-	p.a0 = util.MakeSquare(p.Stages)
-	p.cv = util.MakeSquare(p.Stages)
-	p.pv = util.MakeSquare(p.Stages)
 }
 
-func (p *peer) setEPP2Coeffs() {
+func (p *GenericPeer[T]) setEPP2Coeffs() {
 	// Fortran Code says order = 4 ... really?
 	p.Order, p.Stages, p.stepRatioMax = 2, 2, 1.5
 	p.Name = "EPP2"
@@ -227,7 +289,7 @@ func (p *peer) setEPP2Coeffs() {
 	p.b[1][0], p.b[1][1] = 0.5, 0.5
 }
 
-func (p *peer) setEPP4Coeffs() {
+func (p *GenericPeer[T]) setEPP4Coeffs() {
 	p.Order, p.Stages, p.stepRatioMax = 4, 4, 1.4
 	p.Name = "EPP4"
 	p.allocateCoeffs()
@@ -256,7 +318,7 @@ func (p *peer) setEPP4Coeffs() {
 	p.b[3][3] = 2.388559647
 }
 
-func (p *peer) setEPP4y2Coeffs() {
+func (p *GenericPeer[T]) setEPP4y2Coeffs() {
 	// p.errorModelA = 0.0
 
 	p.c[0] = 0.44856672599000208
@@ -282,7 +344,7 @@ func (p *peer) setEPP4y2Coeffs() {
 	p.b[3][3] = 1.0
 }
 
-func (p *peer) setEPP4y3Coeffs() {
+func (p *GenericPeer[T]) setEPP4y3Coeffs() {
 
 	p.c[0] = 1.33880820864483004
 	p.c[1] = 1.70380840062134099
@@ -306,7 +368,7 @@ func (p *peer) setEPP4y3Coeffs() {
 	p.b[3][2] = 0.0
 	p.b[3][3] = 1.0
 }
-func (p *peer) setEPP4_06809Coeffs() {
+func (p *GenericPeer[T]) setEPP4_06809Coeffs() {
 
 	p.c[0] = -1.067193866512852
 	p.c[1] = -2.756684444690223e-1
@@ -331,7 +393,7 @@ func (p *peer) setEPP4_06809Coeffs() {
 	p.b[3][3] = -9.904358707154119e-2
 }
 
-func (p *peer) setEPP6p1Coeffs() {
+func (p *GenericPeer[T]) setEPP6p1Coeffs() {
 
 	p.c[0] = -1.31059599683912621
 	p.c[1] = 1.97665537290660046
@@ -377,7 +439,7 @@ func (p *peer) setEPP6p1Coeffs() {
 	p.b[5][4] = 0.0
 	p.b[5][5] = 1.0
 }
-func (p *peer) setEPP6j1Coeffs() {
+func (p *GenericPeer[T]) setEPP6j1Coeffs() {
 
 	p.c[0] = 6.1182488158460324e-1
 	p.c[1] = 1.0734784354567433
@@ -423,7 +485,7 @@ func (p *peer) setEPP6j1Coeffs() {
 	p.b[5][4] = 0.0
 	p.b[5][5] = 1.0
 }
-func (p *peer) setEPP8_dCoeffs() {
+func (p *GenericPeer[T]) setEPP8_dCoeffs() {
 	p.c[0] = 0.26041740957753135
 	p.c[1] = 0.52923626823623069
 	p.c[2] = 1.54653689839871537
@@ -498,7 +560,7 @@ func (p *peer) setEPP8_dCoeffs() {
 	p.b[7][6] = -0.01331088571679290
 	p.b[7][7] = 1.00013131148573434
 }
-func (p *peer) setEPP8sp8Coeffs() {
+func (p *GenericPeer[T]) setEPP8sp8Coeffs() {
 	p.c[0] = 0.70541387781778147
 	p.c[1] = 1.30641486071640562
 	p.c[2] = 0.31760983680370311
@@ -573,7 +635,7 @@ func (p *peer) setEPP8sp8Coeffs() {
 	p.b[7][6] = -0.89853483288304983
 	p.b[7][7] = 1.14012130048174894
 }
-func (p *peer) setEPP_x1Coeffs() {
+func (p *GenericPeer[T]) setEPP_x1Coeffs() {
 	p.c[0] = -1.020253410235809
 	p.c[1] = -7.973369854084624e-1
 	p.c[2] = -5.523527869930042e-1
@@ -649,7 +711,7 @@ func (p *peer) setEPP_x1Coeffs() {
 	p.b[7][6] = -1.947535937192549e-3
 	p.b[7][7] = 1.368588234988504e-3
 }
-func (p *peer) setEPP_x2Coeffs() {
+func (p *GenericPeer[T]) setEPP_x2Coeffs() {
 
 	p.c[0] = -1.514542417302030
 	p.c[1] = -1.003995798476134
@@ -1,9 +1,14 @@
 package epp
 
 import (
+	"errors"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
 	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
 	"testing"
 )
 
@@ -17,11 +22,41 @@ func TestParameterValidation(t *testing.T) {
 		Fcn:       bruss.Fcn,
 	}
 
-	peer.Integrate(0, 1, instance, &c)
+	stat, _ := peer.Integrate(0, 1, instance, &c)
 
-	if c.BlockSize == 0 {
+	if stat.Effective.BlockSize == 0 {
 		t.Errorf("Peer didn't correct block size.")
 	}
+	if c.BlockSize != 0 || c.FcnBlocked != nil || c.MaxStepSize != 0 {
+		t.Errorf("Peer modified the caller's configuration.")
+	}
+}
+
+func TestPeerConfigReuse(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+	mbody := problems.NewMBody(4)
+
+	shared := Config{Fcn: mbody.Fcn}
+	intervals := [][2]float64{{0, 0.1}, {0, 1}}
+
+	for _, interval := range intervals {
+		reused, fresh := mbody.Initialize(), mbody.Initialize()
+
+		reusedStat, err := peer.Integrate(interval[0], interval[1], reused, &shared)
+		if err != nil {
+			t.Fatalf("Integration failed - %s", err.Error())
+		}
+		freshStat, _ := peer.Integrate(interval[0], interval[1], fresh, &Config{Fcn: mbody.Fcn})
+
+		if reusedStat.StepCount != freshStat.StepCount || reusedStat.Effective.MaxStepSize != freshStat.Effective.MaxStepSize {
+			t.Errorf("Reused config behaves differently on [%v, %v]", interval[0], interval[1])
+		}
+		for i := range reused {
+			if reused[i] != fresh[i] {
+				t.Fatalf("Reused config gives different results on [%v, %v]", interval[0], interval[1])
+			}
+		}
+	}
 }
 
 func TestPeer(t *testing.T) {
@@ -83,3 +118,169 @@ func TestPeerMBody4h(t *testing.T) {
 		t.Logf("MBody: result[0..10] = %f", instance[:10])
 	}
 }
+
+func TestPeerHistoryStarter(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+
+	// y' = y, y(t) = exp(t)
+	exact := func(t float64, y_out []float64) {
+		y_out[0] = math.Exp(t)
+	}
+	y := []float64{1.0}
+	config := Config{
+		Fcn: func(t float64, yT []float64, dy_out []float64) {
+			dy_out[0] = yT[0]
+		},
+		AbsoluteTolerance: 1e-8,
+		Starter:           HistoryStarter(exact),
+	}
+
+	stat, err := peer.Integrate(0, 1, y, &config)
+
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	if !util.EpsEqual(y[0], math.E, 1e-5) {
+		t.Errorf("Expected %f but result was %f", math.E, y[0])
+	}
+	// one evaluation per stage, none for computing the starting values
+	if stat.StartupEvaluationCount != 4 {
+		t.Errorf("Expected 4 startup evaluations, got %d", stat.StartupEvaluationCount)
+	}
+}
+
+type failingStarter struct{}
+
+func (failingStarter) Start(t float64, yT []float64, times []float64, values [][]float64, c *Config) (Statistics, error) {
+	return Statistics{}, errors.New("no starting values")
+}
+
+func TestPeerStartupFailure(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+	bruss := problems.NewBruss2D(5)
+	instance := bruss.Initialize()
+
+	config := Config{
+		Fcn:     bruss.Fcn,
+		Starter: failingStarter{},
+	}
+
+	_, err := peer.Integrate(0, 1, instance, &config)
+
+	if !errors.Is(err, ErrStartup) {
+		t.Errorf("Startup failure was not reported, got %v", err)
+	}
+	var startupErr *StartupError
+	if !errors.As(err, &startupErr) || startupErr.Err.Error() != "no starting values" {
+		t.Errorf("Startup failure does not carry the cause, got %v", err)
+	}
+}
+
+func TestPeerStartupCancel(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+	bruss := problems.NewBruss2D(5)
+	cancel := make(chan struct{})
+	close(cancel)
+
+	// the starting phase is cancelled already
+	_, err := peer.Integrate(0, 1, bruss.Initialize(), &Config{Fcn: bruss.Fcn, Cancel: cancel})
+	if !errors.Is(err, ErrStartup) || !errors.Is(err, ErrCancelled) {
+		t.Errorf("Cancelled startup not reported, got %v", err)
+	}
+
+	generic, _ := NewGenericPeer[ad.Float](EPP4)
+	decay := func(t float64, yT []ad.Float, dy_out []ad.Float) { dy_out[0] = yT[0].Neg() }
+	_, err = generic.Integrate(0, 1, []ad.Float{1}, decay, &Config{Cancel: cancel})
+	if !errors.Is(err, ErrStartup) || !errors.Is(err, ErrCancelled) {
+		t.Errorf("Cancelled generic startup not reported, got %v", err)
+	}
+}
+
+func TestPeerStartupStatistics(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+	bruss := problems.NewBruss2D(5)
+	instance := bruss.Initialize()
+
+	config := Config{
+		Fcn: bruss.Fcn,
+	}
+
+	stat, err := peer.Integrate(0, 1, instance, &config)
+
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	if stat.StartupEvaluationCount == 0 || stat.StartupEvaluationCount >= stat.EvaluationCount {
+		t.Errorf("Implausible startup evaluation count %d of %d", stat.StartupEvaluationCount, stat.EvaluationCount)
+	}
+}
+
+func TestPeerNonFinite(t *testing.T) {
+	peer, _ := NewPeer(EPP4)
+
+	// the derivative is undefined beyond t = 0.5
+	fcn := func(t float64, yT []float64, dy_out []float64) {
+		dy_out[0] = 1.0
+		dy_out[1] = 1.0
+		if t > 0.5 {
+			dy_out[1] = math.NaN()
+		}
+	}
+
+	_, err := peer.Integrate(0, 1, []float64{0, 0}, &Config{Fcn: fcn, NonFinitePolicy: AbortOnNonFinite})
+	var nonFiniteErr *NonFiniteError
+	if !errors.As(err, &nonFiniteErr) || nonFiniteErr.Index != 1 || nonFiniteErr.Time > 0.5 {
+		t.Errorf("Non-finite stages not reported, got %v", err)
+	}
+
+	_, err = peer.Integrate(0, 1, []float64{0, 0}, &Config{Fcn: fcn})
+	if !errors.Is(err, ErrStepSizeUnderflow) {
+		t.Errorf("Rejecting non-finite stages should end in a step size underflow, got %v", err)
+	}
+}
+
+func TestGenericPeer(t *testing.T) {
+	// y' = -y, y(0) = 1 on [0, 1] beyond float64 precision
+	decay := func(t float64, yT []precision.Big, dy_out []precision.Big) { dy_out[0] = yT[0].Neg() }
+	exact := precision.NewBig(-1.0, 200).Exp()
+	peer, _ := NewGenericPeer[precision.Big](EPP8_d)
+	y := []precision.Big{precision.NewBig(1.0, 200)}
+	stat, err := peer.Integrate(0, 1, y, decay, &Config{AbsoluteTolerance: 1e-24, RelativeTolerance: 1e-24})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	if e := math.Abs(y[0].Sub(exact).Real()); e > 1e-20 || y[0].Precision() != 200 {
+		t.Errorf("Error %g with precision %d", e, y[0].Precision())
+	}
+	if testing.Verbose() {
+		t.Logf("%s: %d steps, %d rejected, %d evaluations", peer.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+	}
+}
+
+func TestGenericPeerFloat(t *testing.T) {
+	// the float64 API and the generic integrator over ad.Float differ only in the startup
+	mbody := problems.NewMBody(4)
+	masses := mbody.(problems.ParametricProblem).Parameters()
+	peer, _ := NewPeer(EPP4)
+	generic, _ := NewGenericPeer[ad.Float](EPP4)
+
+	y := mbody.Initialize()
+	_, err := peer.Integrate(0, 1, y, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-10})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	yGeneric := precision.Float64s(mbody.Initialize())
+	_, err = generic.Integrate(0, 1, yGeneric, ad.Fix(problems.MBodyFcn[ad.Float](4), masses), &Config{AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-10})
+	if err != nil {
+		t.Fatalf("Generic integration failed - %s", err.Error())
+	}
+	for i := range y {
+		if math.Abs(y[i]-float64(yGeneric[i])) > 1e-7*(1+math.Abs(y[i])) {
+			t.Fatalf("result[%d] = %g, generic %g", i, y[i], yGeneric[i])
+		}
+	}
+
+	if _, err := NewGenericPeer[ad.Float](PeerMethod(NumberOfPeerMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown method not reported, got %v", err)
+	}
+}
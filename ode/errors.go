@@ -0,0 +1,110 @@
+package ode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Categories of integration failures, use errors.Is to test an error
+// returned by an Integrator against them and errors.As to get the details
+var (
+	ErrStepSizeUnderflow = errors.New("step size too small")
+	ErrMaxStepsExceeded  = errors.New("maximum step count exceeded")
+	ErrInvalidConfig     = errors.New("invalid configuration")
+	ErrNonFinite         = errors.New("non-finite value")
+	ErrStartup           = errors.New("error during startup")
+	ErrCancelled         = errors.New("integration cancelled")
+	ErrInconsistent      = errors.New("inconsistent initial values")
+)
+
+// StepSizeError reports that the step size fell below MinStepSize
+type StepSizeError struct {
+	Time, StepSize float64
+}
+
+func (e *StepSizeError) Error() string {
+	return fmt.Sprintf("step size too small: h = %g at t = %g", e.StepSize, e.Time)
+}
+
+func (e *StepSizeError) Is(target error) bool { return target == ErrStepSizeUnderflow }
+
+// MaxStepsError reports that MaxStepCount steps were taken
+// without reaching the target time
+type MaxStepsError struct {
+	Time      float64
+	StepCount uint
+}
+
+func (e *MaxStepsError) Error() string {
+	return fmt.Sprintf("maximum step count exceeded: %d steps at t = %g", e.StepCount, e.Time)
+}
+
+func (e *MaxStepsError) Is(target error) bool { return target == ErrMaxStepsExceeded }
+
+// ConfigError reports an unusable Config or Integrator setup
+type ConfigError struct {
+	// Field is the name of the offending setting, if any
+	Field  string
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Field == "" {
+		return "invalid configuration: " + e.Reason
+	}
+	return fmt.Sprintf("invalid configuration: %s: %s", e.Field, e.Reason)
+}
+
+func (e *ConfigError) Is(target error) bool { return target == ErrInvalidConfig }
+
+// NonFiniteError reports a NaN or Inf in the state or derivative
+type NonFiniteError struct {
+	Time, StepSize float64
+	// Index is the first offending component of the system
+	Index int
+}
+
+func (e *NonFiniteError) Error() string {
+	return fmt.Sprintf("non-finite value in component %d: h = %g at t = %g", e.Index, e.StepSize, e.Time)
+}
+
+func (e *NonFiniteError) Is(target error) bool { return target == ErrNonFinite }
+
+// StartupError reports a failure while computing the starting values
+// the underlying error is available through errors.Unwrap
+type StartupError struct {
+	Err error
+}
+
+func (e *StartupError) Error() string {
+	return "error during startup: " + e.Err.Error()
+}
+
+func (e *StartupError) Unwrap() error { return e.Err }
+
+func (e *StartupError) Is(target error) bool { return target == ErrStartup }
+
+// CancelledError reports that the integration was stopped through Config.Cancel
+type CancelledError struct {
+	Time float64
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("integration cancelled at t = %g", e.Time)
+}
+
+func (e *CancelledError) Is(target error) bool { return target == ErrCancelled }
+
+// ConsistencyError reports initial values of a differential-algebraic system
+// for which the algebraic equations could not be solved
+type ConsistencyError struct {
+	Time float64
+	// Residual is the error norm of the last correction of the algebraic variables
+	Residual float64
+}
+
+func (e *ConsistencyError) Error() string {
+	return fmt.Sprintf("inconsistent initial values at t = %g: residual %g", e.Time, e.Residual)
+}
+
+func (e *ConsistencyError) Is(target error) bool { return target == ErrInconsistent }
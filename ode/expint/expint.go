@@ -0,0 +1,302 @@
+package expint
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type expint struct {
+	IntegratorInfo
+	method ExpIntMethod
+
+	// computes yNew and yError for one step, returns false if a Krylov approximation failed
+	step func(s *expint, in *integration, t, step float64, yT []float64) bool
+	// constant linear part of the right hand side for ETD methods
+	linear linalg.LinearOperator
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	krylov *krylov
+	// tolerance of the Krylov approximations in the current step
+	krylovTolerance float64
+
+	// f0 is the evaluation at the beginning of the step, fNew at yNew if newEvaluated
+	f0, fNew, fStage, fTime []float64
+	newEvaluated            bool
+	stage, work, phi        []float64
+	// perturbed point for the directional differences
+	yPerturbed, fPerturbed []float64
+	yNew, yError           []float64
+}
+
+// performs exponential integration, the actions of the phi-functions are approximated
+// in Krylov spaces of the linear operator
+func (s *expint) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+
+	in.EvaluateBlocked(t, yT, in.f0)
+	in.EvaluationCount = 1
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, yT, in.f0, &in.Config, s.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		// the phi-functions are multiplied by the step size
+		in.krylovTolerance = krylovTolerance * math.Sqrt(float64(n)) * (in.AbsoluteTolerance + in.RelativeTolerance*maxNorm(yT)) / stepNext
+
+		errorEstimate := math.Inf(1)
+		if s.step(s, &in, t, stepNext, yT) {
+			errorEstimate = in.ErrorNorm(in.yError, yT, in.yNew)
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.yNew)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			stepEstimate = stepNext * stepFactor(errorEstimate)
+		} else {
+			in.KrylovFailures++
+			stepEstimate = 0.5 * stepNext
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.yNew)
+			if in.newEvaluated {
+				copy(in.f0, in.fNew)
+			} else {
+				in.EvaluateBlocked(t, yT, in.f0)
+				in.EvaluationCount++
+			}
+
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *expint) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp vectors
+	i.krylov = newKrylov(len(yT))
+	i.f0 = make([]float64, i.n)
+	i.fStage = make([]float64, i.n)
+	i.fNew = make([]float64, i.n)
+	i.fTime = make([]float64, i.n)
+	i.stage = make([]float64, i.n)
+	i.work = make([]float64, i.n)
+	i.phi = make([]float64, i.n)
+	i.yPerturbed = make([]float64, i.n)
+	i.fPerturbed = make([]float64, i.n)
+	i.yNew = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+// computes phi_out = phi_p(h a) v and updates the statistics
+func (in *integration) phiAction(a linalg.LinearOperator, p int, h float64, v, phi_out []float64) bool {
+	dimension, converged := in.krylov.phi(a, p, h, v, in.krylovTolerance, phi_out)
+	in.KrylovIterations += uint(dimension)
+	return converged
+}
+
+// exponential Rosenbrock step with the Jacobian J and the time derivative v of Fcn at (t, yT):
+// U = yT + h phi_1(hJ) f0 + h^2 phi_2(hJ) v, D = f(t+h, U) - f0 - J (U - yT) - h v,
+// yNew = U + 2h phi_3(hJ) D and the exponential Rosenbrock-Euler method is embedded
+func stepExpRB32(s *expint, in *integration, t, step float64, yT []float64) bool {
+	var id uint
+
+	// J v by JacobianVector or a directional difference
+	jacobian := func(v, y_out []float64) {
+		if in.JacobianVector != nil {
+			in.JacobianVector(t, yT, v, y_out)
+			return
+		}
+		vNorm := util.EuclideanNorm(v)
+		if vNorm == 0.0 {
+			for id := range y_out {
+				y_out[id] = 0.0
+			}
+			return
+		}
+		epsilon := math.Sqrt(1.1e-16) * (1.0 + util.EuclideanNorm(yT)) / vNorm
+		for id := range v {
+			in.yPerturbed[id] = yT[id] + epsilon*v[id]
+		}
+		in.EvaluateBlocked(t, in.yPerturbed, in.fPerturbed)
+		in.EvaluationCount++
+		for id := range v {
+			y_out[id] = (in.fPerturbed[id] - in.f0[id]) / epsilon
+		}
+	}
+
+	// finite difference approximation of the time derivative
+	deltaTime := math.Sqrt(1e-16 * math.Max(1e-5, math.Abs(t)))
+	in.EvaluateBlocked(t+deltaTime, yT, in.fTime)
+	in.EvaluationCount++
+	for id = 0; id < in.n; id++ {
+		in.fTime[id] = (in.fTime[id] - in.f0[id]) / deltaTime
+	}
+
+	if !in.phiAction(jacobian, 1, step, in.f0, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.stage[id] = yT[id] + step*in.phi[id]
+	}
+	if !in.phiAction(jacobian, 2, step, in.fTime, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.stage[id] += step * step * in.phi[id]
+		in.work[id] = in.stage[id] - yT[id]
+	}
+
+	in.EvaluateBlocked(t+step, in.stage, in.fStage)
+	in.EvaluationCount++
+	jacobian(in.work, in.phi)
+	for id = 0; id < in.n; id++ {
+		in.work[id] = in.fStage[id] - in.f0[id] - in.phi[id] - step*in.fTime[id]
+	}
+
+	if !in.phiAction(jacobian, 3, step, in.work, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.yError[id] = 2.0 * step * in.phi[id]
+		in.yNew[id] = in.stage[id] + in.yError[id]
+	}
+	return true
+}
+
+// exponential time differencing step for Fcn(t, yT) = L yT + N(t, yT):
+// U = yT + h phi_1(hL) f0, yNew = U + h phi_2(hL) (N(t+h, U) - N(t, yT)).
+// The difference to the exponential Euler method does not vanish in the stiff limit,
+// so the error is estimated by h phi_2(hL) (N(t+h, yNew) - N(t+h, U)) instead
+func stepETD2RK(s *expint, in *integration, t, step float64, yT []float64) bool {
+	var id uint
+	in.newEvaluated = false
+
+	if !in.phiAction(s.linear, 1, step, in.f0, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.stage[id] = yT[id] + step*in.phi[id]
+	}
+
+	// N(t+h, U) - N(t, yT) = f(t+h, U) - f0 - L (U - yT)
+	in.EvaluateBlocked(t+step, in.stage, in.fStage)
+	in.EvaluationCount++
+	for id = 0; id < in.n; id++ {
+		in.work[id] = in.stage[id] - yT[id]
+	}
+	s.linear(in.work, in.phi)
+	for id = 0; id < in.n; id++ {
+		in.work[id] = in.fStage[id] - in.f0[id] - in.phi[id]
+	}
+
+	if !in.phiAction(s.linear, 2, step, in.work, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.yError[id] = step * in.phi[id]
+		in.yNew[id] = in.stage[id] + in.yError[id]
+	}
+
+	// N(t+h, yNew) - N(t+h, U) = f(t+h, yNew) - f(t+h, U) - L (yNew - U)
+	in.EvaluateBlocked(t+step, in.yNew, in.fNew)
+	in.EvaluationCount++
+	in.newEvaluated = true
+	s.linear(in.yError, in.phi)
+	for id = 0; id < in.n; id++ {
+		in.work[id] = in.fNew[id] - in.fStage[id] - in.phi[id]
+	}
+	if !in.phiAction(s.linear, 2, step, in.work, in.phi) {
+		return false
+	}
+	for id = 0; id < in.n; id++ {
+		in.yError[id] = step * in.phi[id]
+	}
+	return true
+}
+
+// step size factor for the error estimates, both are of third order
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -1.0/3.0)
+	return math.Max(0.2, math.Min(factor, 5.0)) // safety interval
+}
+
+func maxNorm(v []float64) float64 {
+	result := 0.0
+	for _, x := range v {
+		result = math.Max(result, math.Abs(x))
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package expint
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	"github.com/rollingthunder/differential/ode"
+)
+
+type ExpIntMethod uint
+
+const (
+	ExpRB32               = ExpIntMethod(iota) // exponential Rosenbrock method of Hochbruck et al., order 3(2)
+	ETD2RK                                     // exponential time differencing Runge-Kutta of Cox and Matthews, order 2(1)
+	NumberOfExpIntMethods = uint(iota)
+)
+
+const (
+	// maximal dimension of the Krylov spaces before the step is rejected
+	maxKrylovDimension = 40
+	// the Krylov approximations stop when their error estimate is below
+	// krylovTolerance times the tolerance of the integration
+	krylovTolerance = 0.1
+)
+
+// NewExpInt creates an exponential integrator for yT' = Fcn(t, yT).
+// Exponential Rosenbrock methods linearize Fcn in every step with directional differences
+// and ignore linear. Exponential time differencing methods split Fcn(t, yT) = linear yT + N(t, yT)
+// for a constant linear operator, which has to contain the stiff part of Fcn
+func NewExpInt(m ExpIntMethod, linear linalg.LinearOperator) (i ode.Integrator, err error) {
+	var s expint
+	s.method = m
+
+	switch m {
+	case ExpRB32:
+		s.Name = "ExpRB32"
+		s.Stages = 2
+		s.Order = 3
+		s.step = stepExpRB32
+	case ETD2RK:
+		s.Name = "ETD2RK"
+		s.Stages = 2
+		s.Order = 2
+		s.step = stepETD2RK
+		if linear == nil {
+			err = &ode.ConfigError{Field: "LinearOperator", Reason: "ETD methods require the linear part of the right hand side"}
+		}
+		s.linear = linear
+	default:
+		err = &ode.ConfigError{Field: "ExpIntMethod", Reason: "unknown exponential integration method"}
+	}
+
+	i = &s
+	return
+}
@@ -0,0 +1,76 @@
+package expint
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"testing"
+)
+
+// ETD methods without a linear part reduce to explicit Runge-Kutta methods
+func zeroOperator(v, y_out []float64) {
+	for id := range y_out {
+		y_out[id] = 0.0
+	}
+}
+
+func TestAllExpInt(t *testing.T) {
+	integrators := make([]Integrator, NumberOfExpIntMethods)
+	for j := 0; j < int(NumberOfExpIntMethods); j++ {
+		s, err := NewExpInt(ExpIntMethod(j), zeroOperator)
+		if err != nil {
+			t.Errorf("Couldn't create exponential integration method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = s
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+
+	if _, err := NewExpInt(ETD2RK, nil); err == nil {
+		t.Errorf("ETD method without linear operator was accepted")
+	}
+}
+
+func TestExpIntStiff(t *testing.T) {
+	// the Jacobian of StiffCosine is the linear part
+	linear := func(v, y_out []float64) {
+		y_out[0] = -StiffCosineRate * v[0]
+	}
+
+	for j := 0; j < int(NumberOfExpIntMethods); j++ {
+		s, _ := NewExpInt(ExpIntMethod(j), linear)
+		IntegrateStiffCosine(t, s, Config{AbsoluteTolerance: 1e-5})
+	}
+}
+
+func TestExpIntBruss(t *testing.T) {
+	// the diffusion is the linear part of the Brusselator
+	bruss := problems.NewBruss2D(32)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	var diffusion linalg.LinearOperator = func(v, y_out []float64) {
+		bruss.Diffusion(0, v, y_out)
+	}
+
+	reference, _ := Reference(t, dopri, bruss, 1)
+
+	for j := 0; j < int(NumberOfExpIntMethods); j++ {
+		s, _ := NewExpInt(ExpIntMethod(j), diffusion)
+		y := bruss.Initialize()
+
+		stat, err := s.Integrate(0, 1, y, &Config{Fcn: bruss.Fcn, AbsoluteTolerance: 1e-5})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		CheckReference(t, s.Info().Name, y, reference, ReferenceTolerance)
+		if stat.KrylovIterations == 0 {
+			t.Errorf("%s: no Krylov iterations reported", s.Info().Name)
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations, %d krylov iterations",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.KrylovIterations)
+		}
+	}
+}
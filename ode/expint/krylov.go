@@ -0,0 +1,121 @@
+package expint
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+// Arnoldi projection for the action of the phi-functions
+// phi_0(z) = exp(z), phi_p+1(z) = (phi_p(z) - 1/p!) / z
+type krylov struct {
+	n int
+	// orthonormal basis of the Krylov space and the projected operator
+	basis      [][]float64
+	hessenberg [][]float64
+	w          []float64
+}
+
+func newKrylov(n int) *krylov {
+	return &krylov{
+		n:          n,
+		basis:      util.MakeRectangular(maxKrylovDimension+1, uint(n)),
+		hessenberg: util.MakeRectangular(maxKrylovDimension+1, maxKrylovDimension),
+		w:          make([]float64, n),
+	}
+}
+
+// phi computes phi_out = phi_p(tau A) v for p >= 1, the Krylov space grows until the estimated
+// error in the euclidean norm is below tolerance. It returns the dimension of the space and
+// false if the tolerance was not reached with maxKrylovDimension
+func (k *krylov) phi(a linalg.LinearOperator, p int, tau float64, v []float64, tolerance float64, phi_out []float64) (dimension int, converged bool) {
+	beta := util.EuclideanNorm(v)
+	if beta == 0.0 {
+		for id := range phi_out {
+			phi_out[id] = 0.0
+		}
+		return 0, true
+	}
+	for id := range v {
+		k.basis[0][id] = v[id] / beta
+	}
+
+	var coefficients []float64
+	for j := 0; j < maxKrylovDimension; j++ {
+		// modified Gram-Schmidt
+		a(k.basis[j], k.w)
+		scale := 0.0
+		for i := 0; i <= j; i++ {
+			h := dot(k.w, k.basis[i])
+			k.hessenberg[i][j] = h
+			scale += math.Abs(h)
+			for id := range k.w {
+				k.w[id] -= h * k.basis[i][id]
+			}
+		}
+		next := util.EuclideanNorm(k.w)
+		k.hessenberg[j+1][j] = next
+
+		dimension = j + 1
+		var last float64
+		coefficients, last = k.projected(dimension, p, tau)
+
+		// the space is invariant or the next basis vector hardly contributes
+		breakdown := next <= 1e-12*scale
+		if breakdown || beta*tau*next*math.Abs(last) <= tolerance {
+			converged = true
+			break
+		}
+		if j+1 < maxKrylovDimension {
+			for id := range k.w {
+				k.basis[j+1][id] = k.w[id] / next
+			}
+		}
+	}
+
+	for id := range phi_out {
+		phi_out[id] = 0.0
+	}
+	for i, c := range coefficients {
+		for id := range phi_out {
+			phi_out[id] += beta * c * k.basis[i][id]
+		}
+	}
+	return
+}
+
+// computes phi_p(tau H) e1 of the projected operator H of dimension m and the last component
+// of phi_p+1(tau H) e1 for the error estimate, using the exponential of an augmented matrix
+func (k *krylov) projected(m, p int, tau float64) (coefficients []float64, last float64) {
+	size := m + p + 1
+	augmented := util.MakeRectangular(uint(size), uint(size))
+	for i := 0; i < m; i++ {
+		for j := 0; j < m; j++ {
+			augmented[i][j] = tau * k.hessenberg[i][j]
+		}
+	}
+	augmented[0][m] = 1.0
+	for i := m; i < size-1; i++ {
+		augmented[i][i+1] = 1.0
+	}
+
+	exp := util.MakeRectangular(uint(size), uint(size))
+	if linalg.Expm(augmented, exp) != nil {
+		return nil, math.Inf(1)
+	}
+
+	coefficients = make([]float64, m)
+	for i := 0; i < m; i++ {
+		coefficients[i] = exp[i][m+p-1]
+	}
+	last = exp[m-1][m+p]
+	return
+}
+
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
@@ -0,0 +1,355 @@
+package gbs
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"sync"
+)
+
+// GenericGBS integrates with Gragg-Bulirsch-Stoer extrapolation over the floating point
+// type T. Time and the error control stay float64 as in rk.GenericRK.
+// GBSParallel evaluates the right hand side concurrently
+type GenericGBS[T precision.Real[T]] struct {
+	IntegratorInfo
+	method GBSMethod
+
+	// number of midpoint steps of each row
+	sequence []int
+	// cumulative cost of the rows
+	work []float64
+	// Aitken-Neville coefficients at the precision of the zero value of T
+	coeffs [][]T
+}
+
+// gbs is the float64 integrator, it computes with ad.Float on the memory of the caller
+type gbs struct {
+	generic *GenericGBS[ad.Float]
+}
+
+// evaluation provides the right hand side to the integration, the midpoint steps
+// may be evaluated in blocks
+type evaluation[T precision.Real[T]] struct {
+	fcn, blocked ad.Fcn[T]
+}
+
+// row of the extrapolation table, the first column (the result of the
+// modified midpoint rule) can be computed independently of the other rows
+type row[T precision.Real[T]] struct {
+	zPrevious, zCurrent, f []T
+	evaluations            uint
+}
+
+type integration[T precision.Real[T]] struct {
+	Config
+	Statistics
+	evaluation[T]
+	n      uint
+	coeffs [][]T
+
+	// evaluation at the beginning of the step, shared by all rows
+	f0 []T
+	// first columns of the extrapolation table
+	rows []row[T]
+	// table[l] holds entry l of the row extrapolated last
+	table  [][]T
+	yError []T
+	// optimal step size for each row
+	steps []float64
+}
+
+func (g *gbs) Info() IntegratorInfo {
+	return g.generic.Info()
+}
+
+func (g *gbs) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	return g.generic.integrate(t, tEnd, precision.Float64s(yT), config, func(c *Config) evaluation[ad.Float] {
+		fcn := func(t float64, yT, dy_out []ad.Float) {
+			c.Fcn(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+		}
+		blocked := func(t float64, yT, dy_out []ad.Float) {
+			c.EvaluateBlocked(t, precision.ToFloat64(yT), precision.ToFloat64(dy_out))
+		}
+		return evaluation[ad.Float]{fcn: fcn, blocked: blocked}
+	})
+}
+
+// Integrate advances yT from t to tEnd with the right hand side fcn,
+// Fcn, FcnBlocked and BlockSize of config are ignored
+func (g *GenericGBS[T]) Integrate(t, tEnd float64, yT []T, fcn ad.Fcn[T], config *Config) (stat Statistics, err error) {
+	if fcn == nil {
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+	if config == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+
+	var prototype T
+	if len(yT) > 0 {
+		prototype = yT[0]
+	}
+	adapted := *config
+	adapted.Fcn, adapted.FcnBlocked, adapted.BlockSize = ad.Function(fcn, prototype), nil, 0
+	return g.integrate(t, tEnd, yT, &adapted, func(c *Config) evaluation[T] {
+		return evaluation[T]{fcn: fcn, blocked: fcn}
+	})
+}
+
+// performs Gragg-Bulirsch-Stoer extrapolation with adaptive order and step size
+func (g *GenericGBS[T]) integrate(t, tEnd float64, yT []T, config *Config, evaluator func(c *Config) evaluation[T]) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = g.Supports(&effective); err != nil {
+		return
+	}
+
+	in := g.setupIntegration(yT, &effective)
+	in.evaluation = evaluator(&in.Config)
+
+	// target row of the extrapolation table, chosen from the tolerance
+	target := int(-math.Log10(in.RelativeTolerance+1e-40)*0.6 + 0.5)
+	target = util.Max(1, util.Min(target, maxRows-2))
+
+	in.fcn(t, yT, in.f0)
+	in.EvaluationCount = 1
+	f0Valid := true
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, precision.ToFloat64(yT), precision.ToFloat64(in.f0), &in.Config, uint(2*target+2))
+	}
+	var stepNext float64
+	// the time and the step size at the precision of T
+	tT, tEndT := yT[0].Const(t), yT[0].Const(tEnd)
+	var step T
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+			step = tEndT.Sub(tT)
+		} else {
+			step = yT[0].Const(stepNext)
+		}
+		in.StepCount++
+
+		if !f0Valid {
+			in.blocked(t, yT, in.f0)
+			in.EvaluationCount++
+			f0Valid = true
+		}
+
+		lastRow := target + 1
+		if g.method == GBSParallel {
+			g.computeRows(&in, 0, lastRow, t, stepNext, step, yT)
+		}
+
+		// extrapolate until the error estimate of a row around the target row is small enough
+		converged := -1
+		for j := 0; j <= lastRow; j++ {
+			if g.method == GBS {
+				g.computeRows(&in, j, j, t, stepNext, step, yT)
+			}
+			errorEstimate := g.extrapolate(&in, j)
+			if j == 0 {
+				continue
+			}
+
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(precision.ToFloat64(in.yError))}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			in.steps[j] = stepNext * stepFactor(errorEstimate, j)
+
+			if j >= target-1 && errorEstimate <= 1.0 {
+				converged = j
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+
+		if converged < 0 {
+			// reject step
+			in.RejectedCount++
+			if target >= 2 && g.cost(&in, target-1) < 0.8*g.cost(&in, target) {
+				target--
+			}
+			stepEstimate = in.steps[target]
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			tT = tT.Add(step)
+			copy(yT, in.table[converged])
+			f0Valid = false
+
+			// order and step size selection by cost per unit step
+			k := converged
+			if k >= 2 && g.cost(&in, k-1) < 0.8*g.cost(&in, k) {
+				target = k - 1
+				stepEstimate = in.steps[k-1]
+			} else if k < maxRows-2 && (k == 1 || g.cost(&in, k) < 0.9*g.cost(&in, k-1)) {
+				target = k + 1
+				stepEstimate = in.steps[k] * g.work[k+1] / g.work[k]
+			} else {
+				target = k
+				stepEstimate = in.steps[k]
+			}
+			target = util.Max(1, util.Min(target, maxRows-2))
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (g *GenericGBS[T]) setupIntegration(yT []T, c *Config) (i integration[T]) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// round the coefficients again if the initial values differ in precision from the zero value
+	i.coeffs = g.coeffs
+	if yT[0].Precision() != i.coeffs[1][0].Precision() {
+		i.coeffs = roundCoeffs(g.sequence, yT[0])
+	}
+
+	// allocate temp matrices
+	i.f0 = make([]T, i.n)
+	i.yError = make([]T, i.n)
+	i.table = make([][]T, maxRows)
+	i.rows = make([]row[T], maxRows)
+	for j := range i.rows {
+		i.table[j] = make([]T, i.n)
+		i.rows[j].zPrevious = make([]T, i.n)
+		i.rows[j].zCurrent = make([]T, i.n)
+		i.rows[j].f = make([]T, i.n)
+	}
+	i.steps = make([]float64, maxRows)
+
+	return
+}
+
+// computes the first column of the rows first..last of the extrapolation table
+func (g *GenericGBS[T]) computeRows(in *integration[T], first, last int, t, step float64, stepT T, yT []T) {
+	if g.method == GBSParallel && last > first {
+		// Rows are independent of each other
+		var wg sync.WaitGroup
+		for j := first; j <= last; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				g.computeRow(in, j, t, step, stepT, yT)
+			}(j)
+		}
+		wg.Wait()
+	} else {
+		for j := first; j <= last; j++ {
+			g.computeRow(in, j, t, step, stepT, yT)
+		}
+	}
+
+	for j := first; j <= last; j++ {
+		in.EvaluationCount += in.rows[j].evaluations
+	}
+}
+
+// modified midpoint rule with sequence[j] steps
+func (g *GenericGBS[T]) computeRow(in *integration[T], j int, t, step float64, stepT T, yT []T) {
+	r := &in.rows[j]
+	steps := g.sequence[j]
+	h := step / float64(steps)
+	hT := stepT.Div(stepT.Const(float64(steps)))
+	twoH := hT.Scale(2.0)
+
+	for id := range yT {
+		r.zPrevious[id] = yT[id]
+		r.zCurrent[id] = yT[id].Add(hT.Mul(in.f0[id]))
+	}
+
+	for m := 1; m < steps; m++ {
+		in.blocked(t+float64(m)*h, r.zCurrent, r.f)
+		for id := range yT {
+			zNext := r.zPrevious[id].Add(twoH.Mul(r.f[id]))
+			r.zPrevious[id] = r.zCurrent[id]
+			r.zCurrent[id] = zNext
+		}
+	}
+	r.evaluations = uint(steps - 1)
+}
+
+// adds row j to the extrapolation table (Aitken-Neville scheme) and returns
+// the error estimate of the last extrapolated value
+func (g *GenericGBS[T]) extrapolate(in *integration[T], j int) (errorEstimate float64) {
+	current := in.rows[j].zCurrent
+
+	for l := 0; l < j; l++ {
+		for id := range current {
+			next := current[id].Add(current[id].Sub(in.table[l][id]).Mul(in.coeffs[j][l]))
+			in.table[l][id] = current[id]
+			current[id] = next
+		}
+	}
+	copy(in.table[j], current)
+
+	if j == 0 {
+		return
+	}
+
+	for id := range in.yError {
+		in.yError[id] = in.table[j][id].Sub(in.table[j-1][id])
+	}
+	return in.ErrorNorm(precision.ToFloat64(in.yError), precision.ToFloat64(in.table[j-1]), precision.ToFloat64(in.table[j]))
+}
+
+// cost per unit step for row j
+func (g *GenericGBS[T]) cost(in *integration[T], j int) float64 {
+	return g.work[j] / in.steps[j]
+}
+
+// step size factor for the error estimate of row j, whose next to last entry has order 2j
+func stepFactor(errorEstimate float64, j int) float64 {
+	factor := 0.94 * math.Pow(0.65/errorEstimate, 1.0/float64(2*j+1))
+	return math.Max(0.02, math.Min(factor, 4.0)) // safety interval
+}
@@ -0,0 +1,77 @@
+package gbs
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
+	"math/big"
+)
+
+type GBSMethod uint
+
+const (
+	GBS                = GBSMethod(iota) // rows of the extrapolation table computed one after another
+	GBSParallel                          // rows of the extrapolation table computed concurrently
+	NumberOfGBSMethods = uint(iota)
+)
+
+// number of rows of the extrapolation table
+const maxRows = 8
+
+// NewGenericGBS creates the integrator of method m over the floating point type T
+func NewGenericGBS[T precision.Real[T]](m GBSMethod) (g *GenericGBS[T], err error) {
+	g = &GenericGBS[T]{method: m}
+
+	switch m {
+	case GBS:
+		g.Name = "GBS"
+	case GBSParallel:
+		g.Name = "GBSParallel"
+	default:
+		err = &ode.ConfigError{Field: "GBSMethod", Reason: "unknown gbs method"}
+	}
+	g.Stages = maxRows
+	g.Order = 2 * maxRows
+
+	g.setCoeffs()
+	return
+}
+
+// NewGBS creates the integrator of method m for float64 values
+func NewGBS(m GBSMethod) (i ode.Integrator, err error) {
+	generic, err := NewGenericGBS[ad.Float](m)
+	i = &gbs{generic: generic}
+	return
+}
+
+func (g *GenericGBS[T]) setCoeffs() {
+	// harmonic step number sequence 2, 4, 6, 8, ...
+	g.sequence = make([]int, maxRows)
+	for j := range g.sequence {
+		g.sequence[j] = 2 * (j + 1)
+	}
+
+	// cumulative number of evaluations to compute rows 0..j
+	g.work = make([]float64, maxRows)
+	g.work[0] = float64(g.sequence[0]) + 1.0
+	for j := 1; j < maxRows; j++ {
+		g.work[j] = g.work[j-1] + float64(g.sequence[j]) - 1.0
+	}
+
+	var zero T
+	g.coeffs = roundCoeffs(g.sequence, zero)
+}
+
+// roundCoeffs computes the Aitken-Neville coefficients 1/((n_j/n_(j-l))^2 - 1)
+// at the precision of prototype
+func roundCoeffs[T precision.Real[T]](sequence []int, prototype T) [][]T {
+	coeffs := make([][]T, len(sequence))
+	for j := range coeffs {
+		coeffs[j] = make([]T, j)
+		for l := 1; l <= j; l++ {
+			nj, nl := int64(sequence[j]), int64(sequence[j-l])
+			coeffs[j][l-1] = prototype.Rat(big.NewRat(nl*nl, nj*nj-nl*nl))
+		}
+	}
+	return coeffs
+}
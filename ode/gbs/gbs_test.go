@@ -0,0 +1,176 @@
+package gbs
+
+import (
+	"errors"
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+func TestAllGBS(t *testing.T) {
+	integrators := make([]Integrator, NumberOfGBSMethods)
+	for j := 0; j < int(NumberOfGBSMethods); j++ {
+		g, err := NewGBS(GBSMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create GBS Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = g
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestGBSAccuracy(t *testing.T) {
+	integrators := make([]Integrator, NumberOfGBSMethods)
+	for j := range integrators {
+		integrators[j], _ = NewGBS(GBSMethod(j))
+	}
+
+	RunAccuracyTests(t, integrators, 1e-10, 1e-7)
+}
+
+func TestGBSDenseOutput(t *testing.T) {
+	// GBS provides no dense output, rk does
+	g, _ := NewGBS(GBSMethod(0))
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	fcn := func(t float64, yT []float64, dy_out []float64) { dy_out[0] = -yT[0] }
+	config := Config{Fcn: fcn, DenseOutput: func(step Interpolant) {}}
+
+	if g.Info().DenseOutput || !dopri.Info().DenseOutput {
+		t.Errorf("Wrong dense output capabilities")
+	}
+	if _, err := g.Integrate(0, 1, []float64{1}, &config); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error for dense output, got %v", err)
+	}
+	if _, err := dopri.Integrate(0, 1, []float64{1}, &config); err != nil {
+		t.Errorf("Integration with dense output failed - %s", err.Error())
+	}
+}
+
+func TestGBSMBody4h(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	mbody := problems.NewMBody(4)
+	reference := mbody.Initialize()
+
+	_, err := dopri.Integrate(0, 5, reference, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-12})
+	if err != nil {
+		t.Fatalf("Reference integration failed - %s", err.Error())
+	}
+
+	for j := 0; j < int(NumberOfGBSMethods); j++ {
+		gbs, _ := NewGBS(GBSMethod(j))
+		instance := mbody.Initialize()
+
+		config := Config{
+			Fcn:               mbody.Fcn,
+			AbsoluteTolerance: 1.e-12,
+			RelativeTolerance: 1.e-12,
+		}
+
+		stat, err := gbs.Integrate(0, 5, instance, &config)
+
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", gbs.Info().Name, err.Error())
+		}
+		for i := range instance {
+			if !util.EpsEqual(instance[i], reference[i], 1e-8) {
+				t.Fatalf("%s: result[%d] = %g differs from DoPri5 result %g", gbs.Info().Name, i, instance[i], reference[i])
+			}
+		}
+
+		if testing.Verbose() {
+			t.Logf("MBody %s: %d steps, %d rejected, %d evaluations", gbs.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestGBSBlockedParallel(t *testing.T) {
+	bruss := problems.NewBruss2D(10)
+	sequential, _ := NewGBS(GBS)
+	parallel, _ := NewGBS(GBSParallel)
+
+	ySequential, yParallel := bruss.Initialize(), bruss.Initialize()
+	config := Config{
+		FcnBlocked: bruss.FcnBlock,
+		BlockSize:  20,
+	}
+
+	statSequential, err := sequential.Integrate(0, 1, ySequential, &config)
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	statParallel, err := parallel.Integrate(0, 1, yParallel, &config)
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+
+	if statSequential.StepCount != statParallel.StepCount {
+		t.Errorf("Sequential and parallel variant took different steps: %d vs %d", statSequential.StepCount, statParallel.StepCount)
+	}
+	for i := range ySequential {
+		if ySequential[i] != yParallel[i] {
+			t.Fatalf("Sequential and parallel variant have different results at %d", i)
+		}
+	}
+}
+
+func TestGenericGBS(t *testing.T) {
+	// y' = -y, y(0) = 1 on [0, 1] beyond float64 precision
+	decay := func(t float64, yT []precision.Big, dy_out []precision.Big) { dy_out[0] = yT[0].Neg() }
+	exact := precision.NewBig(-1.0, 200).Exp()
+	for j := 0; j < int(NumberOfGBSMethods); j++ {
+		gbs, _ := NewGenericGBS[precision.Big](GBSMethod(j))
+		y := []precision.Big{precision.NewBig(1.0, 200)}
+		stat, err := gbs.Integrate(0, 1, y, decay, &Config{AbsoluteTolerance: 1e-30, RelativeTolerance: 1e-30})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", gbs.Info().Name, err.Error())
+		}
+		if e := math.Abs(y[0].Sub(exact).Real()); e > 1e-28 || y[0].Precision() != 200 {
+			t.Errorf("%s: error %g with precision %d", gbs.Info().Name, e, y[0].Precision())
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations", gbs.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestGenericGBSFloat(t *testing.T) {
+	// the float64 API and the generic integrator over ad.Float with the generic right hand side agree
+	mbody := problems.NewMBody(4)
+	masses := mbody.(problems.ParametricProblem).Parameters()
+	for j := 0; j < int(NumberOfGBSMethods); j++ {
+		gbs, _ := NewGBS(GBSMethod(j))
+		generic, _ := NewGenericGBS[ad.Float](GBSMethod(j))
+
+		y := mbody.Initialize()
+		stat, err := gbs.Integrate(0, 1, y, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", gbs.Info().Name, err.Error())
+		}
+		yGeneric := precision.Float64s(mbody.Initialize())
+		genericStat, err := generic.Integrate(0, 1, yGeneric, ad.Fix(problems.MBodyFcn[ad.Float](4), masses), &Config{AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-10})
+		if err != nil {
+			t.Fatalf("%s: Generic integration failed - %s", gbs.Info().Name, err.Error())
+		}
+		if stat.StepCount != genericStat.StepCount || stat.EvaluationCount != genericStat.EvaluationCount {
+			t.Errorf("%s: Generic integration took %d steps, %d evaluations instead of %d, %d", gbs.Info().Name,
+				genericStat.StepCount, genericStat.EvaluationCount, stat.StepCount, stat.EvaluationCount)
+		}
+		for i := range y {
+			if math.Abs(y[i]-float64(yGeneric[i])) > 1e-10 {
+				t.Fatalf("%s: result[%d] = %g, generic %g", gbs.Info().Name, i, y[i], yGeneric[i])
+			}
+		}
+	}
+
+	if _, err := NewGenericGBS[ad.Float](GBSMethod(NumberOfGBSMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown method not reported, got %v", err)
+	}
+}
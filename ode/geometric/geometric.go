@@ -0,0 +1,105 @@
+package geometric
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+// Config describes a separable Hamiltonian system in split form
+// q' = v, v' = Acceleration(t, q) and its fixed step integration
+type Config struct {
+	// StepSize is the size of the integration steps,
+	// it is reduced slightly so that a whole number of steps ends at tEnd
+	StepSize float64
+
+	// MaxStepCount if > 0 specifies the maximum number of steps the Integrator
+	// may take, integrations that would need more steps are not started
+	MaxStepCount uint
+
+	Acceleration ode.Function
+}
+
+// Integrator is a fixed step integrator operating on split position/velocity state
+type Integrator interface {
+	Info() ode.IntegratorInfo
+	Integrate(t, tEnd float64, q, v []float64, c *Config) (stat ode.Statistics, err error)
+}
+
+// symplectic is a partitioned Runge-Kutta method for separable systems,
+// given as a sequence of kick (velocity update) and drift (position update) stages
+type symplectic struct {
+	ode.IntegratorInfo
+	kick, drift []float64
+}
+
+func (s *symplectic) Integrate(t, tEnd float64, q, v []float64, c *Config) (stat ode.Statistics, err error) {
+	if c == nil {
+		err = &ode.ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Acceleration == nil {
+		err = &ode.ConfigError{Field: "Acceleration", Reason: "no acceleration function specified"}
+		return
+	}
+	if c.StepSize <= 0.0 {
+		err = &ode.ConfigError{Field: "StepSize", Reason: "step size must be positive"}
+		return
+	}
+	if len(q) != len(v) {
+		err = &ode.ConfigError{Reason: "positions and velocities differ in size"}
+		return
+	}
+	if !(tEnd >= t) {
+		err = &ode.ConfigError{Reason: "end time before start time"}
+		return
+	}
+
+	n := len(q)
+	stepCount := math.Ceil((tEnd - t) / c.StepSize * (1.0 - 1e-12))
+	if stepCount == 0.0 {
+		stat.CurrentTime = t
+		return
+	}
+	if c.MaxStepCount > 0 && stepCount > float64(c.MaxStepCount) {
+		err = &ode.MaxStepsError{Time: t, StepCount: c.MaxStepCount}
+		stat.CurrentTime = t
+		return
+	}
+	steps := int(stepCount)
+	h := (tEnd - t) / float64(steps)
+
+	// the last kick of a step is the first kick of the next one,
+	// if there is no drift in between
+	stages := len(s.kick)
+	firstSameAsLast := s.drift[stages-1] == 0.0
+	acceleration := make([]float64, n)
+	accelerationValid := false
+
+	for step := 0; step < steps; step++ {
+		tau := t + float64(step)*h
+		for stg := 0; stg < stages; stg++ {
+			if s.kick[stg] != 0.0 {
+				if !(stg == 0 && accelerationValid) {
+					c.Acceleration(tau, q, acceleration)
+					stat.EvaluationCount++
+				}
+				for id := 0; id < n; id++ {
+					v[id] += s.kick[stg] * h * acceleration[id]
+				}
+			}
+			if s.drift[stg] != 0.0 {
+				for id := 0; id < n; id++ {
+					q[id] += s.drift[stg] * h * v[id]
+				}
+				tau += s.drift[stg] * h
+			}
+		}
+		accelerationValid = firstSameAsLast
+		stat.StepCount++
+	}
+
+	stat.CurrentTime = tEnd
+	stat.LastStepSize = h
+	stat.NextStepSize = h
+	return
+}
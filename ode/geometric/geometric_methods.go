@@ -0,0 +1,61 @@
+package geometric
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+type SymplecticMethod uint
+
+const (
+	StormerVerlet             = SymplecticMethod(iota) // velocity Verlet, order 2
+	Ruth3                                              // Ruth's third order method
+	ForestRuth                                         // Forest-Ruth/Yoshida triple jump, order 4
+	Suzuki4                                            // Suzuki's fractal composition of five Verlet steps, order 4
+	Yoshida6                                           // Yoshida's composition of seven Verlet steps, order 6
+	NumberOfSymplecticMethods = uint(iota)
+)
+
+func NewSymplectic(m SymplecticMethod) (i Integrator, err error) {
+	var s symplectic
+	switch m {
+	case StormerVerlet:
+		s.Name, s.Order = "StormerVerlet", 2
+		s.compose([]float64{1.0})
+	case Ruth3:
+		s.Name, s.Order = "Ruth3", 3
+		s.kick = []float64{7.0 / 24.0, 3.0 / 4.0, -1.0 / 24.0}
+		s.drift = []float64{2.0 / 3.0, -2.0 / 3.0, 1.0}
+	case ForestRuth:
+		s.Name, s.Order = "ForestRuth", 4
+		w1 := 1.0 / (2.0 - math.Cbrt(2.0))
+		s.compose([]float64{w1, 1.0 - 2.0*w1, w1})
+	case Suzuki4:
+		s.Name, s.Order = "Suzuki4", 4
+		w1 := 1.0 / (4.0 - math.Cbrt(4.0))
+		s.compose([]float64{w1, w1, 1.0 - 4.0*w1, w1, w1})
+	case Yoshida6:
+		s.Name, s.Order = "Yoshida6", 6
+		w1, w2, w3 := -1.17767998417887, 0.235573213359357, 0.784513610477560
+		s.compose([]float64{w3, w2, w1, 1.0 - 2.0*(w1+w2+w3), w1, w2, w3})
+	default:
+		err = &ode.ConfigError{Field: "SymplecticMethod", Reason: "unknown symplectic method"}
+	}
+	s.Stages = uint(len(s.kick))
+
+	i = &s
+	return
+}
+
+// compose builds the kick-drift sequence of the composition of
+// velocity Verlet steps with the given relative step sizes,
+// consecutive half kicks are merged
+func (s *symplectic) compose(weights []float64) {
+	s.kick = make([]float64, len(weights)+1)
+	s.drift = make([]float64, len(weights)+1)
+	for i, w := range weights {
+		s.kick[i] += 0.5 * w
+		s.drift[i] = w
+		s.kick[i+1] += 0.5 * w
+	}
+}
@@ -0,0 +1,122 @@
+package geometric
+
+import (
+	"errors"
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+func harmonicOscillator(t float64, q []float64, a_out []float64) {
+	a_out[0] = -q[0]
+}
+
+func TestSymplecticOrder(t *testing.T) {
+	for j := 0; j < int(NumberOfSymplecticMethods); j++ {
+		method, err := NewSymplectic(SymplecticMethod(j))
+		if err != nil {
+			t.Fatalf("Couldn't create Symplectic Method %d: %s", j, err.Error())
+		}
+		info := method.Info()
+
+		// global error at t = 1 for two step sizes
+		errors := make([]float64, 2)
+		for i, h := range []float64{0.1, 0.05} {
+			q, v := []float64{1.0}, []float64{0.0}
+			_, err = method.Integrate(0, 1, q, v, &Config{StepSize: h, Acceleration: harmonicOscillator})
+			if err != nil {
+				t.Fatalf("%s: Integration failed - %s", info.Name, err.Error())
+			}
+			errors[i] = math.Hypot(q[0]-math.Cos(1.0), v[0]+math.Sin(1.0))
+		}
+
+		order := math.Log2(errors[0] / errors[1])
+		if order < float64(info.Order)-0.3 {
+			t.Errorf("%s: observed order %.2f, expected %d", info.Name, order, info.Order)
+		}
+		if testing.Verbose() {
+			t.Logf("%s: observed order %.2f", info.Name, order)
+		}
+	}
+}
+
+func TestSymplecticInterval(t *testing.T) {
+	method, _ := NewSymplectic(SymplecticMethod(0))
+	config := &Config{StepSize: 0.1, Acceleration: harmonicOscillator}
+
+	// an empty interval leaves the state unchanged
+	q, v := []float64{1.0}, []float64{0.0}
+	stat, err := method.Integrate(1, 1, q, v, config)
+	if err != nil || stat.StepCount != 0 || stat.CurrentTime != 1 || q[0] != 1.0 || v[0] != 0.0 {
+		t.Errorf("Empty interval changed the state to %v, %v: %+v, %v", q, v, stat, err)
+	}
+
+	// backward integration is not supported
+	stat, err = method.Integrate(1, 0, q, v, config)
+	if !errors.Is(err, ode.ErrInvalidConfig) || stat.StepCount != 0 {
+		t.Errorf("End time before start time not rejected, got %+v, %v", stat, err)
+	}
+}
+
+// the energy error of a symplectic method oscillates, but does not drift
+func TestSymplecticEnergyBounded(t *testing.T) {
+	// the sampling interval is incommensurate with the period
+	const tEnd, samples = 5000.0, 1900
+
+	for j := 0; j < int(NumberOfSymplecticMethods); j++ {
+		method, _ := NewSymplectic(SymplecticMethod(j))
+		info := method.Info()
+		q, v := []float64{1.0}, []float64{0.0}
+		config := Config{StepSize: 0.3, Acceleration: harmonicOscillator}
+
+		// maximal energy error in the first and the last tenth of the run
+		var early, late float64
+		for i := 0; i < samples; i++ {
+			t0, t1 := tEnd*float64(i)/samples, tEnd*float64(i+1)/samples
+			if _, err := method.Integrate(t0, t1, q, v, &config); err != nil {
+				t.Fatalf("%s: Integration failed - %s", info.Name, err.Error())
+			}
+			energyError := math.Abs(0.5*(q[0]*q[0]+v[0]*v[0]) - 0.5)
+			if i < samples/10 {
+				early = math.Max(early, energyError)
+			} else if i >= samples-samples/10 {
+				late = math.Max(late, energyError)
+			}
+		}
+
+		if late > 1.5*early+1e-14 {
+			t.Errorf("%s: energy error grows from %g to %g", info.Name, early, late)
+		}
+	}
+}
+
+func TestSymplecticMBodyEnergy(t *testing.T) {
+	mbody := problems.NewMBody(4)
+	const tEnd, samples = 400.0, 400
+
+	for j := 0; j < int(NumberOfSymplecticMethods); j++ {
+		method, _ := NewSymplectic(SymplecticMethod(j))
+		info := method.Info()
+		q, v := mbody.InitializeSplit()
+		energy0 := mbody.Energy(q, v)
+		config := Config{StepSize: 0.01, Acceleration: mbody.Acceleration}
+
+		// the bodies pass each other closely several times during the run
+		var maxError float64
+		for i := 0; i < samples; i++ {
+			t0, t1 := tEnd*float64(i)/samples, tEnd*float64(i+1)/samples
+			if _, err := method.Integrate(t0, t1, q, v, &config); err != nil {
+				t.Fatalf("%s: Integration failed - %s", info.Name, err.Error())
+			}
+			maxError = math.Max(maxError, math.Abs(mbody.Energy(q, v)-energy0))
+		}
+
+		if maxError > 1e-3*math.Abs(energy0) {
+			t.Errorf("%s: energy error %g exceeds bound", info.Name, maxError)
+		}
+		if testing.Verbose() {
+			t.Logf("%s: relative energy error at most %g", info.Name, maxError/math.Abs(energy0))
+		}
+	}
+}
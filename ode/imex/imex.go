@@ -0,0 +1,291 @@
+package imex
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/internal/linear"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type imex struct {
+	IntegratorInfo
+	method IMEXMethod
+
+	// diagonal entry of the implicit stages
+	gamma float64
+	// strictly lower parts of the coefficient matrices
+	aExplicit, aImplicit [][]float64
+	c                    []float64
+	// weights and differences to the weights of the embedded method
+	bExplicit, bImplicit []float64
+	eExplicit, eImplicit []float64
+}
+
+type integration struct {
+	SplitConfig
+	Statistics
+	n uint
+
+	// Jacobian of the implicit part and decomposition of I - gamma h J
+	system      *linear.System
+	shift       float64
+	factorized  bool
+	jacobianAge int
+
+	// stage derivatives of both parts, index 0 holds the evaluations at the beginning of the step
+	ksExplicit, ksImplicit           [][]float64
+	base, z, fz, delta, yNew, yError []float64
+}
+
+// performs implicit-explicit Runge-Kutta integration, the Implicit part is solved by a simplified
+// Newton iteration with its (possibly sparse) Jacobian, which is kept as long as the iteration converges
+func (s *imex) IntegrateSplit(t, tEnd float64, yT []float64, config *SplitConfig) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective.Config); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+	implicit := in.ImplicitConfig()
+
+	s.evaluate(&in, t, yT, 0)
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		f0 := make([]float64, n)
+		for id := range f0 {
+			f0[id] = in.ksExplicit[0][id] + in.ksImplicit[0][id]
+		}
+		stepEstimate = EstimateStepSize(t, yT, f0, &in.Config, s.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		if in.system == nil {
+			in.system = linear.NewSystem(implicit, n)
+			in.EvaluationCount += in.system.ComputeJacobian(implicit, t, yT, in.ksImplicit[0])
+		}
+
+		// retry with a fresh Jacobian if the iteration fails with an old one
+		converged := s.stages(&in, t, stepNext, yT)
+		if !converged && in.jacobianAge > 0 {
+			in.EvaluationCount += in.system.ComputeJacobian(implicit, t, yT, in.ksImplicit[0])
+			in.jacobianAge, in.factorized = 0, false
+			converged = s.stages(&in, t, stepNext, yT)
+		}
+
+		errorEstimate := math.Inf(1)
+		if converged {
+			errorEstimate = s.estimateError(&in, stepNext, yT)
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.yNew)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			stepEstimate = stepNext * stepFactor(errorEstimate)
+		} else {
+			in.NewtonFailures++
+			stepEstimate = 0.25 * stepNext
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.yNew)
+			s.evaluate(&in, t, yT, 0)
+			in.jacobianAge++
+
+			// keep the factorization for small changes of the step size
+			if ratio := stepEstimate / stepNext; ratio >= 1.0 && ratio <= 1.2 {
+				stepEstimate = stepNext
+			}
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *imex) setupIntegration(yT []float64, c *SplitConfig) (i integration) {
+	i.n = uint(len(yT))
+	i.SplitConfig = *c
+
+	// allocate temp matrices
+	i.ksExplicit = util.MakeRectangular(s.Stages, i.n)
+	i.ksImplicit = util.MakeRectangular(s.Stages, i.n)
+	i.base = make([]float64, i.n)
+	i.z = make([]float64, i.n)
+	i.fz = make([]float64, i.n)
+	i.delta = make([]float64, i.n)
+	i.yNew = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+// evaluates both parts at (t, yT) into stage stg
+func (s *imex) evaluate(in *integration, t float64, yT []float64, stg uint) {
+	in.Explicit(t, yT, in.ksExplicit[stg])
+	in.Implicit(t, yT, in.ksImplicit[stg])
+	in.EvaluationCount += 2
+}
+
+// computes the stages and the new solution yNew
+// returns false if a Newton iteration did not converge
+func (s *imex) stages(in *integration, t, step float64, yT []float64) bool {
+	shift := step * s.gamma
+	if !in.factorized || shift != in.shift {
+		if in.system.Factorize(shift) != nil {
+			in.factorized = false
+			return false
+		}
+		in.shift, in.factorized = shift, true
+	}
+
+	var stg, j, id uint
+	for stg = 1; stg < s.Stages; stg++ {
+		for id = 0; id < in.n; id++ {
+			in.base[id] = yT[id]
+		}
+		for j = 0; j < stg; j++ {
+			for id = 0; id < in.n; id++ {
+				in.base[id] += step * (s.aExplicit[stg][j]*in.ksExplicit[j][id] + s.aImplicit[stg][j]*in.ksImplicit[j][id])
+			}
+		}
+
+		// solve z = base + h gamma Implicit(z), starting at the explicit prediction
+		tStage := t + s.c[stg]*step
+		for id = 0; id < in.n; id++ {
+			in.z[id] = in.base[id] + shift*in.ksImplicit[stg-1][id]
+		}
+		if !s.newton(in, tStage, shift, yT) {
+			return false
+		}
+
+		for id = 0; id < in.n; id++ {
+			in.ksImplicit[stg][id] = (in.z[id] - in.base[id]) / shift
+		}
+		in.Explicit(tStage, in.z, in.ksExplicit[stg])
+		in.EvaluationCount++
+	}
+
+	for id = 0; id < in.n; id++ {
+		in.yNew[id] = yT[id]
+	}
+	for stg = 0; stg < s.Stages; stg++ {
+		for id = 0; id < in.n; id++ {
+			in.yNew[id] += step * (s.bExplicit[stg]*in.ksExplicit[stg][id] + s.bImplicit[stg]*in.ksImplicit[stg][id])
+		}
+	}
+	return true
+}
+
+// simplified Newton iteration for z = base + shift Implicit(t, z) with the factorized system
+func (s *imex) newton(in *integration, t, shift float64, yT []float64) bool {
+	var id uint
+	deltaNorm := 0.0
+	for iteration := 0; iteration < maxNewtonIterations; iteration++ {
+		in.NewtonIterations++
+
+		in.Implicit(t, in.z, in.fz)
+		in.EvaluationCount++
+		for id = 0; id < in.n; id++ {
+			in.delta[id] = in.base[id] + shift*in.fz[id] - in.z[id]
+		}
+		in.system.Solve(in.delta)
+		for id = 0; id < in.n; id++ {
+			in.z[id] += in.delta[id]
+		}
+
+		lastDeltaNorm := deltaNorm
+		deltaNorm = in.ErrorNorm(in.delta, yT, in.z)
+		if !util.IsFinite(deltaNorm) {
+			return false
+		}
+		if deltaNorm <= 0.01*newtonTolerance {
+			return true
+		}
+		if iteration == 0 {
+			continue
+		}
+		contraction := deltaNorm / lastDeltaNorm
+		if contraction >= 1.0 {
+			return false
+		}
+		if contraction/(1.0-contraction)*deltaNorm <= newtonTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// estimates the error against the embedded solution, filtered with (I - gamma h J)^-1
+// to avoid overestimating the stiff components
+func (s *imex) estimateError(in *integration, step float64, yT []float64) float64 {
+	var stg, id uint
+	for id = 0; id < in.n; id++ {
+		in.yError[id] = 0.0
+	}
+	for stg = 0; stg < s.Stages; stg++ {
+		for id = 0; id < in.n; id++ {
+			in.yError[id] += step * (s.eExplicit[stg]*in.ksExplicit[stg][id] + s.eImplicit[stg]*in.ksImplicit[stg][id])
+		}
+	}
+	in.system.Solve(in.yError)
+	return in.ErrorNorm(in.yError, yT, in.yNew)
+}
+
+// step size factor for the error estimate of the embedded first order solution
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -0.5)
+	return math.Max(0.2, math.Min(factor, 5.0)) // safety interval
+}
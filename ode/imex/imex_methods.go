@@ -0,0 +1,63 @@
+package imex
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+type IMEXMethod uint
+
+const (
+	ARS222              = IMEXMethod(iota) // ARS(2,2,2) of Ascher, Ruuth and Spiteri, L-stable, order 2(1)
+	NumberOfIMEXMethods = uint(iota)
+)
+
+const (
+	// Newton iterations per stage before the Jacobian is recomputed or the step rejected
+	maxNewtonIterations = 7
+	// the Newton iteration stops when the estimated remaining error is below
+	// newtonTolerance times the tolerance of the integration
+	newtonTolerance = 0.05
+)
+
+func NewIMEX(m IMEXMethod) (i ode.SplitIntegrator, err error) {
+	var s imex
+	s.method = m
+
+	switch m {
+	case ARS222:
+		s.Name = "ARS222"
+		s.Stages = 3
+		s.Order = 2
+		setCoeffsARS222(&s)
+	default:
+		err = &ode.ConfigError{Field: "IMEXMethod", Reason: "unknown imex method"}
+	}
+
+	i = &s
+	return
+}
+
+// the first stage is explicit in both parts, the embedded first order solution
+// uses the derivatives of the second stage only
+func setCoeffsARS222(s *imex) {
+	gamma := 1.0 - 1.0/math.Sqrt2
+	delta := 1.0 - 1.0/(2.0*gamma)
+
+	s.gamma = gamma
+	s.c = []float64{0.0, gamma, 1.0}
+	s.aExplicit = [][]float64{
+		{},
+		{gamma},
+		{delta, 1.0 - delta},
+	}
+	s.aImplicit = [][]float64{
+		{},
+		{0.0},
+		{0.0, 1.0 - gamma},
+	}
+	s.bExplicit = []float64{delta, 1.0 - delta, 0.0}
+	s.bImplicit = []float64{0.0, 1.0 - gamma, gamma}
+	s.eExplicit = []float64{delta, -delta, 0.0}
+	s.eImplicit = []float64{0.0, -gamma, gamma}
+}
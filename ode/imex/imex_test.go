@@ -0,0 +1,118 @@
+package imex
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+func TestIMEXConstructor(t *testing.T) {
+	for j := 0; j < int(NumberOfIMEXMethods); j++ {
+		if _, err := NewIMEX(IMEXMethod(j)); err != nil {
+			t.Errorf("Couldn't create IMEX Method %d: %s", j, err.Error())
+		}
+	}
+	if _, err := NewIMEX(IMEXMethod(NumberOfIMEXMethods)); err == nil {
+		t.Errorf("Unknown method was accepted")
+	}
+}
+
+func TestIMEXOrder(t *testing.T) {
+	// y' = sin(t) - lambda (y - cos(t)), the stiff part is treated implicitly
+	const lambda = 10.0
+	explicit := func(t float64, y []float64, dy []float64) {
+		dy[0] = math.Sin(t)
+	}
+	implicit := func(t float64, y []float64, dy []float64) {
+		dy[0] = -lambda * (y[0] - math.Cos(t))
+	}
+	// smooth solution without transient
+	a, b := 2.0*lambda/(1.0+lambda*lambda), -2.0/(1.0+lambda*lambda)
+	exact := func(t float64) float64 {
+		return math.Cos(t) + a*math.Sin(t) + b*math.Cos(t)
+	}
+
+	for j := 0; j < int(NumberOfIMEXMethods); j++ {
+		s, _ := NewIMEX(IMEXMethod(j))
+		info := s.Info()
+
+		// fixed steps, the error has to decrease with the order of the method
+		errors := make([]float64, 2)
+		for k, step := range []float64{0.1, 0.05} {
+			y := []float64{exact(1)}
+			config := SplitConfig{
+				Config:   Config{InitialStepSize: step, MaxStepSize: step, AbsoluteTolerance: 1, RelativeTolerance: 1},
+				Explicit: explicit,
+				Implicit: implicit,
+			}
+			if _, err := s.IntegrateSplit(1, 3, y, &config); err != nil {
+				t.Fatalf("%s: Integration failed - %s", info.Name, err.Error())
+			}
+			errors[k] = math.Abs(y[0] - exact(3))
+		}
+
+		order := math.Log2(errors[0] / errors[1])
+		if order < float64(info.Order)-0.3 {
+			t.Errorf("%s: observed order %.2f, expected %d", info.Name, order, info.Order)
+		}
+	}
+}
+
+func TestIMEXBrussSplit(t *testing.T) {
+	// reaction explicitly, diffusion implicitly with its sparse Jacobian
+	bruss := problems.NewBruss2D(32)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+
+	reference, _ := Reference(t, dopri, bruss, 1)
+
+	for j := 0; j < int(NumberOfIMEXMethods); j++ {
+		s, _ := NewIMEX(IMEXMethod(j))
+		y := bruss.Initialize()
+		config := SplitConfig{
+			Config:                 Config{AbsoluteTolerance: 1e-5},
+			Explicit:               bruss.Reaction,
+			Implicit:               bruss.Diffusion,
+			ImplicitSparsity:       bruss.DiffusionSparsity(),
+			ImplicitSparseJacobian: bruss.DiffusionJacobian,
+		}
+
+		stat, err := s.IntegrateSplit(0, 1, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		CheckReference(t, s.Info().Name, y, reference, ReferenceTolerance)
+		// the implicit part is linear, so one iteration solves a stage and one confirms it
+		if stat.NewtonIterations > 2*(s.Info().Stages-1)*(stat.StepCount+1) {
+			t.Errorf("%s: %d newton iterations in %d steps", s.Info().Name, stat.NewtonIterations, stat.StepCount)
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d rejected, %d evaluations, %d newton iterations",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.NewtonIterations)
+		}
+	}
+}
+
+func TestSplitAdapter(t *testing.T) {
+	bruss := problems.NewBruss2D(16)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+
+	reference := bruss.Initialize()
+	if _, err := dopri.Integrate(0, 1, reference, &Config{Fcn: bruss.Fcn, AbsoluteTolerance: 1e-6}); err != nil {
+		t.Fatalf("Reference integration failed - %s", err.Error())
+	}
+
+	y := bruss.Initialize()
+	adapter := SplitAdapter{Integrator: dopri}
+	config := SplitConfig{Config: Config{AbsoluteTolerance: 1e-6}, Explicit: bruss.Reaction, Implicit: bruss.Diffusion}
+	if _, err := adapter.IntegrateSplit(0, 1, y, &config); err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	for i := range y {
+		if math.Abs(y[i]-reference[i]) > 1e-10 {
+			t.Fatalf("result[%d] = %g differs from reference %g", i, y[i], reference[i])
+		}
+	}
+}
@@ -1,12 +1,19 @@
 package ode
 
-import (
-	"errors"
-)
-
 type Function func(t float64, yT []float64, dy_out []float64)
 type BlockFunction func(startIdx, blockSize uint, t float64, yT []float64, dy_out []float64)
 
+// NonFinitePolicy determines how an Integrator reacts to NaN or Inf values
+// showing up in the stages or the error estimate of a step
+type NonFinitePolicy int
+
+const (
+	// RejectNonFinite rejects the step and retries with a smaller step size
+	RejectNonFinite NonFinitePolicy = iota
+	// AbortOnNonFinite stops the integration with a NonFiniteError
+	AbortOnNonFinite
+)
+
 type Config struct {
 	// InitialStepSize, if > 0.0 specifies the step size
 	// to be used in the first integration step
@@ -47,6 +54,47 @@ type Config struct {
 	// Fcn
 	Fcn        Function
 	FcnBlocked BlockFunction
+
+	// Jacobian, if set, computes the Jacobian of the right hand side for implicit integrators,
+	// with Sparsity but without SparseJacobian only its entries on the pattern are used
+	// Else, it is approximated by finite differences
+	Jacobian JacobianFunction
+
+	// Sparsity, if set, is the pattern of the Jacobian
+	// It is used to reduce the cost of finite difference approximations
+	Sparsity *Sparsity
+
+	// SparseJacobian, if set, computes the Jacobian on the pattern given by Sparsity
+	SparseJacobian SparseJacobianFunction
+
+	// JacobianVector, if set, computes the Jacobian-vector products of matrix-free integrators
+	// Else, they are approximated by directional differences
+	JacobianVector JacobianVectorFunction
+
+	// Preconditioner, if set, is applied by the Krylov solvers of matrix-free implicit integrators
+	// Else, the linear systems are solved without preconditioning
+	Preconditioner PreconditionerFunction
+
+	// Starter, if set, computes the starting values for integrators
+	// that need more than the initial value, e.g. peer methods
+	// If nil, the implementation uses its own default starting procedure
+	Starter Starter
+
+	// NonFinitePolicy determines what happens if a step produces NaN or Inf values
+	NonFinitePolicy NonFinitePolicy
+
+	// Cancel, if set, stops the integration with a CancelledError
+	// once the channel is closed. It is checked once per step
+	Cancel <-chan struct{}
+
+	// DenseOutput, if set, is called after every accepted step with an interpolant
+	// of the solution on the step. Integrators without dense output reject it with a ConfigError,
+	// see IntegratorInfo.DenseOutput
+	DenseOutput func(step Interpolant)
+
+	// ErrorComponents, if > 0, restricts the error estimate to the first ErrorComponents
+	// components of the system, the remaining ones are integrated without error control
+	ErrorComponents uint
 }
 
 type Statistics struct {
@@ -57,6 +105,24 @@ type Statistics struct {
 	// EvaluationCount is the number of times the right hand side expression
 	// of the differential equation was evaluated during processing
 	EvaluationCount uint
+	// StartupEvaluationCount is the part of EvaluationCount that was spent
+	// computing the starting values (if the Integrator needs any)
+	StartupEvaluationCount uint
+	// SwitchCount is the number of times a switching Integrator changed
+	// between its explicit and its implicit method
+	SwitchCount uint
+	// StiffStepCount is the part of StepCount performed by the implicit method
+	// of a switching Integrator
+	StiffStepCount uint
+	// NewtonIterations is the number of Newton iterations spent solving the stage equations
+	// of implicit integrators
+	NewtonIterations uint
+	// KrylovIterations is the number of iterations of the linear solver inside the Newton iterations
+	KrylovIterations uint
+	// NewtonFailures is the number of steps rejected because the Newton iteration did not converge
+	NewtonFailures uint
+	// KrylovFailures is the number of linear solves that stopped before reaching their tolerance
+	KrylovFailures uint
 
 	// LastStepSize is the size of the last integration step performed
 	LastStepSize float64
@@ -64,6 +130,24 @@ type Statistics struct {
 	NextStepSize float64
 	// CurrentTime is the value of t up to which the integration was performed
 	CurrentTime float64
+
+	// Effective is the configuration the Integrator actually used,
+	// i.e. the given Config with all defaults resolved
+	Effective Config
+}
+
+// Add accumulates the counts of other, e.g. of the inner integrations of a composite Integrator
+func (s *Statistics) Add(other Statistics) {
+	s.StepCount += other.StepCount
+	s.RejectedCount += other.RejectedCount
+	s.EvaluationCount += other.EvaluationCount
+	s.StartupEvaluationCount += other.StartupEvaluationCount
+	s.SwitchCount += other.SwitchCount
+	s.StiffStepCount += other.StiffStepCount
+	s.NewtonIterations += other.NewtonIterations
+	s.KrylovIterations += other.KrylovIterations
+	s.NewtonFailures += other.NewtonFailures
+	s.KrylovFailures += other.KrylovFailures
 }
 
 type Integrator interface {
@@ -74,37 +158,95 @@ type Integrator interface {
 type IntegratorInfo struct {
 	Name          string
 	Stages, Order uint
+	// DenseOutput reports whether the integrator calls Config.DenseOutput after every accepted step
+	DenseOutput bool
+}
+
+// Supports returns a ConfigError if c uses a setting the integrator does not provide
+func (i *IntegratorInfo) Supports(c *Config) error {
+	if c != nil && c.DenseOutput != nil && !i.DenseOutput {
+		return &ConfigError{Field: "DenseOutput", Reason: "integrator " + i.Name + " provides no dense output"}
+	}
+	return nil
 }
 
-func (c *Config) ValidateAndPrepare(maxBlockSize uint) error {
+// ValidateAndPrepare checks the configuration for an integration of a system
+// with maxBlockSize components over [t, tEnd] and returns the effective configuration,
+// with unset parameters replaced by their defaults. The receiver is not modified
+func (c *Config) ValidateAndPrepare(maxBlockSize uint, t, tEnd float64) (e Config, err error) {
 	if c == nil {
-		return errors.New("nil configuration")
+		err = &ConfigError{Reason: "nil configuration"}
+		return
 	}
 
 	if maxBlockSize == 0 {
-		return errors.New("max block size may not be 0")
+		err = &ConfigError{Field: "BlockSize", Reason: "max block size may not be 0"}
+		return
 	}
 
 	if c.FcnBlocked == nil && c.Fcn == nil {
-		return errors.New("no evalution function specified")
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+
+	if c.SparseJacobian != nil && c.Sparsity == nil {
+		err = &ConfigError{Field: "Sparsity", Reason: "sparse jacobian requires a sparsity pattern"}
+		return
 	}
+	if c.Sparsity != nil {
+		if err = c.Sparsity.validate(maxBlockSize); err != nil {
+			return
+		}
+	}
+
+	e = *c
 
-	if c.BlockSize == 0 || c.BlockSize > maxBlockSize {
-		c.BlockSize = maxBlockSize
+	if e.BlockSize == 0 || e.BlockSize > maxBlockSize {
+		e.BlockSize = maxBlockSize
 	}
 
-	if c.FcnBlocked == nil {
-		c.BlockSize = maxBlockSize
-		c.FcnBlocked = func(startIdx, blockSize uint, t float64, yT []float64, dy_out []float64) {
-			c.Fcn(t, yT, dy_out)
+	if fcn := c.Fcn; c.FcnBlocked == nil {
+		e.BlockSize = maxBlockSize
+		e.FcnBlocked = func(startIdx, blockSize uint, t float64, yT []float64, dy_out []float64) {
+			fcn(t, yT, dy_out)
 		}
-	} else if c.Fcn == nil {
-		c.Fcn = func(t float64, yT []float64, dy_out []float64) {
-			c.FcnBlocked(0, uint(len(yT)), t, yT, dy_out)
+	} else if fcnBlocked := c.FcnBlocked; c.Fcn == nil {
+		e.Fcn = func(t float64, yT []float64, dy_out []float64) {
+			fcnBlocked(0, uint(len(yT)), t, yT, dy_out)
 		}
 	}
 
-	return nil
+	// set default parameters if necessary
+	if e.MaxStepSize <= 0.0 {
+		e.MaxStepSize = tEnd - t
+	}
+	if e.MinStepSize <= 0.0 {
+		e.MinStepSize = 1e-10
+	}
+	if e.MaxStepCount == 0 {
+		e.MaxStepCount = 1000000
+	}
+	if e.AbsoluteTolerance <= 0.0 {
+		e.AbsoluteTolerance = 1e-4
+	}
+	if e.RelativeTolerance <= 0.0 {
+		e.RelativeTolerance = e.AbsoluteTolerance
+	}
+
+	return
+}
+
+// Cancelled reports whether the integration should be stopped
+func (c *Config) Cancelled() bool {
+	if c.Cancel == nil {
+		return false
+	}
+	select {
+	case <-c.Cancel:
+		return true
+	default:
+		return false
+	}
 }
 
 func (i *IntegratorInfo) Info() IntegratorInfo {
@@ -0,0 +1,146 @@
+// Package linear provides the linear systems of implicit integrators
+package linear
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+)
+
+// System is the linear system with the matrix I - shift J of the Jacobian J
+// of the right hand side, stored dense or, if the Config has a Sparsity pattern,
+// banded for patterns with a narrow band and sparse otherwise
+type System struct {
+	n      uint
+	sparse bool
+	banded bool
+
+	denseJacobian [][]float64
+	denseMatrix   [][]float64
+	denseLU       linalg.LU
+
+	sparseJacobian *SparseMatrix
+	// pattern of the Jacobian with the diagonal added
+	sparseMatrix linalg.CSR
+	// position of each entry of the Jacobian and of the diagonal in the matrix
+	jacobianEntries, diagonalEntries []int
+	sparseLU                         linalg.SparseLU
+
+	bandedMatrix *linalg.Banded
+	bandedLU     linalg.BandedLU
+}
+
+// NewSystem allocates the system for the right hand side of c with n components
+func NewSystem(c *Config, n uint) (s *System) {
+	s = &System{n: n, sparse: c.Sparsity != nil}
+	if !s.sparse {
+		s.denseJacobian = util.MakeRectangular(n, n)
+		s.denseMatrix = util.MakeRectangular(n, n)
+		return
+	}
+
+	s.sparseJacobian = NewSparseMatrix(c.Sparsity)
+
+	// the banded factorization needs n (2 lower + upper + 1) entries, without the
+	// overhead of the sparse elimination
+	lower, upper := 0, 0
+	for i := 0; i < int(n); i++ {
+		for _, j := range c.Sparsity.Columns[c.Sparsity.RowStart[i]:c.Sparsity.RowStart[i+1]] {
+			if i-j > lower {
+				lower = i - j
+			}
+			if j-i > upper {
+				upper = j - i
+			}
+		}
+	}
+	if 2*(2*lower+upper+1) < int(n) {
+		s.banded = true
+		s.bandedMatrix = linalg.NewBanded(int(n), lower, upper)
+		return
+	}
+
+	rows := make([][]int, n)
+	for i := range rows {
+		rows[i] = append([]int{i}, c.Sparsity.Columns[c.Sparsity.RowStart[i]:c.Sparsity.RowStart[i+1]]...)
+	}
+	pattern := NewSparsity(rows)
+	s.sparseMatrix = linalg.CSR{
+		N:        int(n),
+		RowStart: pattern.RowStart,
+		Columns:  pattern.Columns,
+		Values:   make([]float64, pattern.NonZeros()),
+	}
+
+	s.jacobianEntries = make([]int, c.Sparsity.NonZeros())
+	s.diagonalEntries = make([]int, n)
+	for i := 0; i < int(n); i++ {
+		for k := c.Sparsity.RowStart[i]; k < c.Sparsity.RowStart[i+1]; k++ {
+			s.jacobianEntries[k] = pattern.Index(i, c.Sparsity.Columns[k])
+		}
+		s.diagonalEntries[i] = pattern.Index(i, i)
+	}
+	return
+}
+
+// ComputeJacobian computes the Jacobian at (t, yT), f0 has to hold the evaluation at (t, yT)
+// Returns the number of evaluations of the right hand side
+func (s *System) ComputeJacobian(c *Config, t float64, yT, f0 []float64) uint {
+	if s.sparse {
+		return c.EvaluateSparseJacobian(t, yT, f0, s.sparseJacobian)
+	}
+	return c.EvaluateJacobian(t, yT, f0, s.denseJacobian)
+}
+
+// Factorize computes the LU decomposition of I - shift J
+func (s *System) Factorize(shift float64) error {
+	if s.banded {
+		var id uint
+		for id = 0; id < s.n; id++ {
+			s.bandedMatrix.Set(int(id), int(id), 0.0)
+		}
+		pattern := s.sparseJacobian.Sparsity
+		for i := 0; i < int(s.n); i++ {
+			for k := pattern.RowStart[i]; k < pattern.RowStart[i+1]; k++ {
+				s.bandedMatrix.Set(i, pattern.Columns[k], -shift*s.sparseJacobian.Values[k])
+			}
+		}
+		for id = 0; id < s.n; id++ {
+			s.bandedMatrix.Set(int(id), int(id), s.bandedMatrix.At(int(id), int(id))+1.0)
+		}
+		return s.bandedLU.Factorize(s.bandedMatrix)
+	}
+	if s.sparse {
+		values := s.sparseMatrix.Values
+		for k := range values {
+			values[k] = 0.0
+		}
+		for k, entry := range s.jacobianEntries {
+			values[entry] = -shift * s.sparseJacobian.Values[k]
+		}
+		for _, entry := range s.diagonalEntries {
+			values[entry] += 1.0
+		}
+		return s.sparseLU.Factorize(&s.sparseMatrix)
+	}
+
+	var id, col uint
+	for id = 0; id < s.n; id++ {
+		for col = 0; col < s.n; col++ {
+			s.denseMatrix[id][col] = -shift * s.denseJacobian[id][col]
+		}
+		s.denseMatrix[id][id] += 1.0
+	}
+	return s.denseLU.Factorize(s.denseMatrix)
+}
+
+// Solve overwrites b with the solution of (I - shift J) x = b
+func (s *System) Solve(b []float64) {
+	if s.banded {
+		s.bandedLU.Solve(b)
+	} else if s.sparse {
+		s.sparseLU.Solve(b)
+	} else {
+		s.denseLU.Solve(b)
+	}
+}
@@ -0,0 +1,268 @@
+package ode
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// JacobianFunction computes the Jacobian of the right hand side at (t, yT),
+// jac_out[i][j] is the derivative of component i with respect to y_j
+type JacobianFunction func(t float64, yT []float64, jac_out [][]float64)
+
+// JacobianVectorFunction computes the product jv_out of the Jacobian of the right hand side at (t, yT) with v
+type JacobianVectorFunction func(t float64, yT, v []float64, jv_out []float64)
+
+// PreconditionerFunction computes z_out as an approximation of the solution of (I - shift J) z = r
+// with the Jacobian J of the right hand side at (t, yT)
+type PreconditionerFunction func(t float64, yT []float64, shift float64, r, z_out []float64)
+
+// SparseJacobianFunction computes the Jacobian of the right hand side at (t, yT)
+// into the values of jac_out, whose pattern is the Sparsity of the Config
+type SparseJacobianFunction func(t float64, yT []float64, jac_out *SparseMatrix)
+
+// Sparsity is the pattern of the nonzero entries of a square matrix in compressed
+// sparse row format: the columns of row i are Columns[RowStart[i]:RowStart[i+1]] in ascending order
+type Sparsity struct {
+	RowStart []int
+	Columns  []int
+
+	// groups of structurally orthogonal columns, computed when first needed
+	coloring   sync.Once
+	colors     []int
+	colorCount int
+}
+
+// NewSparsity creates the pattern with the given columns in each row,
+// duplicate columns are removed
+func NewSparsity(rows [][]int) *Sparsity {
+	s := &Sparsity{RowStart: make([]int, len(rows)+1)}
+	for i, columns := range rows {
+		sorted := append([]int(nil), columns...)
+		sort.Ints(sorted)
+		for k, col := range sorted {
+			if k == 0 || col != sorted[k-1] {
+				s.Columns = append(s.Columns, col)
+			}
+		}
+		s.RowStart[i+1] = len(s.Columns)
+	}
+	return s
+}
+
+// Size returns the number of rows and columns of the matrix
+func (s *Sparsity) Size() int {
+	return len(s.RowStart) - 1
+}
+
+// NonZeros returns the number of entries in the pattern
+func (s *Sparsity) NonZeros() int {
+	return len(s.Columns)
+}
+
+// Index returns the position of entry (row, col) in the values of a SparseMatrix,
+// or -1 if it is not part of the pattern
+func (s *Sparsity) Index(row, col int) int {
+	columns := s.Columns[s.RowStart[row]:s.RowStart[row+1]]
+	k := sort.SearchInts(columns, col)
+	if k < len(columns) && columns[k] == col {
+		return s.RowStart[row] + k
+	}
+	return -1
+}
+
+// Coloring partitions the columns into groups, such that no two columns of a group
+// have an entry in the same row. All columns of a group can be perturbed at once
+// in a finite difference approximation. colors[j] is the group of column j
+func (s *Sparsity) Coloring() (colors []int, count int) {
+	s.coloring.Do(func() {
+		n := s.Size()
+
+		// rows with an entry in each column
+		columnStart := make([]int, n+1)
+		for _, col := range s.Columns {
+			columnStart[col+1]++
+		}
+		for j := 0; j < n; j++ {
+			columnStart[j+1] += columnStart[j]
+		}
+		rows := make([]int, len(s.Columns))
+		fill := append([]int(nil), columnStart[:n]...)
+		for i := 0; i < n; i++ {
+			for _, col := range s.Columns[s.RowStart[i]:s.RowStart[i+1]] {
+				rows[fill[col]] = i
+				fill[col]++
+			}
+		}
+
+		// greedy coloring, usedBy[c] == j marks color c as taken by a neighbour of column j
+		s.colors = make([]int, n)
+		usedBy := make([]int, n)
+		for c := range usedBy {
+			usedBy[c] = -1
+		}
+		for j := 0; j < n; j++ {
+			for _, i := range rows[columnStart[j]:columnStart[j+1]] {
+				for _, neighbour := range s.Columns[s.RowStart[i]:s.RowStart[i+1]] {
+					if neighbour < j {
+						usedBy[s.colors[neighbour]] = j
+					}
+				}
+			}
+			color := 0
+			for usedBy[color] == j {
+				color++
+			}
+			s.colors[j] = color
+			if color+1 > s.colorCount {
+				s.colorCount = color + 1
+			}
+		}
+	})
+	return s.colors, s.colorCount
+}
+
+func (s *Sparsity) validate(n uint) error {
+	if s.Size() != int(n) || len(s.RowStart) == 0 || s.RowStart[0] != 0 || s.RowStart[n] != len(s.Columns) {
+		return &ConfigError{Field: "Sparsity", Reason: "pattern does not match the system size"}
+	}
+	for i := 0; i < int(n); i++ {
+		for k := s.RowStart[i]; k < s.RowStart[i+1]; k++ {
+			if s.Columns[k] < 0 || s.Columns[k] >= int(n) || (k > s.RowStart[i] && s.Columns[k] <= s.Columns[k-1]) {
+				return &ConfigError{Field: "Sparsity", Reason: "columns out of range or not ascending"}
+			}
+		}
+	}
+	return nil
+}
+
+// SparseMatrix is a square matrix with the entries given by its pattern
+type SparseMatrix struct {
+	*Sparsity
+	Values []float64
+}
+
+// NewSparseMatrix allocates a zero matrix with pattern s
+func NewSparseMatrix(s *Sparsity) *SparseMatrix {
+	return &SparseMatrix{Sparsity: s, Values: make([]float64, s.NonZeros())}
+}
+
+// MulVec computes y_out = m x
+func (m *SparseMatrix) MulVec(x, y_out []float64) {
+	for i := 0; i < m.Size(); i++ {
+		sum := 0.0
+		for k := m.RowStart[i]; k < m.RowStart[i+1]; k++ {
+			sum += m.Values[k] * x[m.Columns[k]]
+		}
+		y_out[i] = sum
+	}
+}
+
+// ToDense writes m into the zeroed dense matrix a_out
+func (m *SparseMatrix) ToDense(a_out [][]float64) {
+	for i := 0; i < m.Size(); i++ {
+		for j := range a_out[i] {
+			a_out[i][j] = 0.0
+		}
+		for k := m.RowStart[i]; k < m.RowStart[i+1]; k++ {
+			a_out[i][m.Columns[k]] = m.Values[k]
+		}
+	}
+}
+
+// EvaluateJacobian computes the dense Jacobian at (t, yT) into jac_out using Jacobian or
+// SparseJacobian if set, and finite differences otherwise. f0 has to hold the evaluation
+// at (t, yT). Returns the number of evaluations of the right hand side
+func (c *Config) EvaluateJacobian(t float64, yT, f0 []float64, jac_out [][]float64) (evaluations uint) {
+	if c.Jacobian != nil {
+		c.Jacobian(t, yT, jac_out)
+		return
+	}
+
+	if c.Sparsity != nil {
+		sparse := NewSparseMatrix(c.Sparsity)
+		evaluations = c.EvaluateSparseJacobian(t, yT, f0, sparse)
+		sparse.ToDense(jac_out)
+		return
+	}
+
+	// one column at a time
+	n := len(yT)
+	yPerturbed, fPerturbed := append([]float64(nil), yT...), make([]float64, n)
+	for col := 0; col < n; col++ {
+		delta := c.jacobianIncrement(yT[col])
+		yPerturbed[col] = yT[col] + delta
+		c.EvaluateBlocked(t, yPerturbed, fPerturbed)
+		yPerturbed[col] = yT[col]
+
+		for id := 0; id < n; id++ {
+			jac_out[id][col] = (fPerturbed[id] - f0[id]) / delta
+		}
+	}
+	return uint(n)
+}
+
+// EvaluateSparseJacobian computes the Jacobian at (t, yT) on the pattern Sparsity into jac_out
+// using SparseJacobian or the entries of the dense Jacobian on the pattern if set, and finite
+// differences otherwise. Columns without common rows are perturbed together, so the number of
+// evaluations is the number of colors of the pattern.
+// f0 has to hold the evaluation at (t, yT). Returns the number of evaluations of the right hand side
+func (c *Config) EvaluateSparseJacobian(t float64, yT, f0 []float64, jac_out *SparseMatrix) (evaluations uint) {
+	if c.SparseJacobian != nil {
+		c.SparseJacobian(t, yT, jac_out)
+		return
+	}
+
+	n := len(yT)
+	if c.Jacobian != nil {
+		dense := make([][]float64, n)
+		for i := range dense {
+			dense[i] = make([]float64, n)
+		}
+		c.Jacobian(t, yT, dense)
+		for i := 0; i < n; i++ {
+			for k := jac_out.RowStart[i]; k < jac_out.RowStart[i+1]; k++ {
+				jac_out.Values[k] = dense[i][jac_out.Columns[k]]
+			}
+		}
+		return
+	}
+
+	colors, count := c.Sparsity.Coloring()
+	yPerturbed, fPerturbed := append([]float64(nil), yT...), make([]float64, n)
+	deltas := make([]float64, n)
+
+	for color := 0; color < count; color++ {
+		for col := 0; col < n; col++ {
+			if colors[col] == color {
+				deltas[col] = c.jacobianIncrement(yT[col])
+				yPerturbed[col] = yT[col] + deltas[col]
+			}
+		}
+		c.EvaluateBlocked(t, yPerturbed, fPerturbed)
+		evaluations++
+
+		// each row has at most one entry in a column of this color
+		for i := 0; i < n; i++ {
+			for k := jac_out.RowStart[i]; k < jac_out.RowStart[i+1]; k++ {
+				if col := jac_out.Columns[k]; colors[col] == color {
+					jac_out.Values[k] = (fPerturbed[i] - f0[i]) / deltas[col]
+				}
+			}
+		}
+		copy(yPerturbed, yT)
+	}
+	return
+}
+
+// increment for the finite difference approximation of the derivative with respect to
+// a component with value y, scaled with the solution and bounded below by the tolerances
+// and chosen such that y + increment is exactly representable
+func (c *Config) jacobianIncrement(y float64) float64 {
+	scale := math.Max(math.Abs(y), c.AbsoluteTolerance/math.Max(c.RelativeTolerance, uround))
+	delta := math.Sqrt(uround) * math.Max(scale, math.Sqrt(uround))
+	if y < 0.0 {
+		delta = -delta
+	}
+	return (y + delta) - y
+}
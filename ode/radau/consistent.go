@@ -0,0 +1,78 @@
+package radau
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+// ConsistentInitialValues solves the algebraic equations of a semi-explicit system of index 1
+// for the algebraic variables, the differential variables of yT are kept. The algebraic
+// equations and variables are the zero rows and columns of the mass matrix at (t, yT)
+func ConsistentInitialValues(t float64, yT []float64, config *DAEConfig) (evaluations uint, err error) {
+	n := uint(len(yT))
+	effective, err := config.ValidateAndPrepare(n, t, t)
+	if err != nil {
+		return
+	}
+
+	mass := util.MakeSquare(n)
+	effective.EvaluateMass(t, yT, mass)
+	equations, variables := Algebraic(mass)
+	if len(equations) != len(variables) {
+		err = &ConfigError{Field: "Mass", Reason: "algebraic equations and variables don't match, the system is not semi-explicit"}
+		return
+	}
+	m := len(equations)
+	if m == 0 {
+		return
+	}
+
+	f, fPerturbed := make([]float64, n), make([]float64, n)
+	jacobian := util.MakeRectangular(uint(m), uint(m))
+	delta := make([]float64, m)
+	var lu linalg.LU
+
+	correction := math.Inf(1)
+	for iteration := 0; iteration < maxConsistencyIterations; iteration++ {
+		effective.EvaluateBlocked(t, yT, f)
+		evaluations++
+
+		// Jacobian of the algebraic equations with respect to the algebraic variables
+		for col, variable := range variables {
+			y := yT[variable]
+			increment := math.Sqrt(1e-16 * math.Max(1e-5, math.Abs(y)))
+			yT[variable] = y + increment
+			effective.EvaluateBlocked(t, yT, fPerturbed)
+			evaluations++
+			yT[variable] = y
+
+			for row, equation := range equations {
+				jacobian[row][col] = (fPerturbed[equation] - f[equation]) / increment
+			}
+		}
+		if lu.Factorize(jacobian) != nil {
+			break
+		}
+
+		for row, equation := range equations {
+			delta[row] = -f[equation]
+		}
+		lu.Solve(delta)
+
+		correction = 0.0
+		for col, variable := range variables {
+			yT[variable] += delta[col]
+			tolerance := effective.AbsoluteTolerance + effective.RelativeTolerance*math.Abs(yT[variable])
+			correction += math.Pow(delta[col]/tolerance, 2.0)
+		}
+		correction = math.Sqrt(correction / float64(m))
+		if correction <= 1e-3 {
+			return
+		}
+	}
+
+	err = &ConsistencyError{Time: t, Residual: correction}
+	return
+}
@@ -0,0 +1,394 @@
+package radau
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type radau struct {
+	IntegratorInfo
+	method RadauMethod
+
+	a    [][]float64
+	c, e []float64
+	// the error estimate is filtered with (gamma0/h M - J)^-1
+	gamma0 float64
+}
+
+type integration struct {
+	DAEConfig
+	Statistics
+	n uint
+
+	// mass matrix at the beginning of the step and at the stages
+	mass, stageMass [][]float64
+	jacobian        [][]float64
+	jacobianFresh   bool
+	lastContraction float64
+
+	// I (x) M - h A (x) J for the Newton iteration and gamma0/h M - J for the error estimate
+	system, errorMatrix     [][]float64
+	systemLU, errorLU       linalg.LU
+	factorizedStep          float64
+	factorized              bool
+	firstStep, lastRejected bool
+
+	// stage derivatives, stage values and their evaluations
+	ks, ys, fs [][]float64
+	// right hand side and update of the Newton iteration for all stages
+	residual                      []float64
+	f0, yNew, yError, work, fTemp []float64
+}
+
+// Integrate integrates yT' = Fcn(t, yT), the identity is used as mass matrix
+func (s *radau) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	if config == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	return s.IntegrateDAE(t, tEnd, yT, &DAEConfig{Config: *config})
+}
+
+// performs collocation at the Radau points, the stage equations are solved by a simplified
+// Newton iteration. The initial values are made consistent before the first step
+func (s *radau) IntegrateDAE(t, tEnd float64, yT []float64, config *DAEConfig) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective.Config); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+
+	evaluations, err := ConsistentInitialValues(t, yT, &effective)
+	in.StartupEvaluationCount = evaluations
+	in.EvaluationCount = evaluations
+	if err != nil {
+		return in.Statistics, err
+	}
+
+	in.EvaluateBlocked(t, yT, in.f0)
+	in.EvaluationCount++
+	in.EvaluateMass(t, yT, in.mass)
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, yT, in.f0, &in.Config, s.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		if in.jacobian == nil || !in.jacobianFresh && in.lastContraction > recomputeContraction {
+			s.computeJacobian(&in, t, yT)
+		}
+
+		// retry with a fresh Jacobian if the iteration fails with an old one
+		converged := s.stages(&in, t, stepNext, yT)
+		if !converged && !in.jacobianFresh {
+			s.computeJacobian(&in, t, yT)
+			converged = s.stages(&in, t, stepNext, yT)
+		}
+
+		errorEstimate := math.Inf(1)
+		if converged {
+			errorEstimate = s.estimateError(&in, t, stepNext, yT)
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.yNew)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			stepEstimate = stepNext * stepFactor(errorEstimate)
+		} else {
+			in.NewtonFailures++
+			stepEstimate = 0.5 * stepNext
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+			in.lastRejected = true
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.yNew)
+			in.EvaluateBlocked(t, yT, in.f0)
+			in.EvaluationCount++
+			if in.MassFunction != nil {
+				in.EvaluateMass(t, yT, in.mass)
+				in.factorized = false
+			}
+			in.jacobianFresh = false
+			in.firstStep, in.lastRejected = false, false
+
+			// keep the factorization for small changes of the step size
+			if ratio := stepEstimate / stepNext; ratio >= 1.0 && ratio <= 1.2 {
+				stepEstimate = stepNext
+			}
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *radau) setupIntegration(yT []float64, c *DAEConfig) (i integration) {
+	i.n = uint(len(yT))
+	i.DAEConfig = *c
+	i.firstStep = true
+
+	// allocate temp matrices
+	i.mass = util.MakeSquare(i.n)
+	i.stageMass = util.MakeSquare(i.n)
+	i.system = util.MakeSquare(s.Stages * i.n)
+	i.errorMatrix = util.MakeSquare(i.n)
+	i.ks = util.MakeRectangular(s.Stages, i.n)
+	i.ys = util.MakeRectangular(s.Stages, i.n)
+	i.fs = util.MakeRectangular(s.Stages, i.n)
+	i.residual = make([]float64, s.Stages*i.n)
+	i.f0 = make([]float64, i.n)
+	i.yNew = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+	i.work = make([]float64, i.n)
+	i.fTemp = make([]float64, i.n)
+
+	return
+}
+
+func (s *radau) computeJacobian(in *integration, t float64, yT []float64) {
+	if in.jacobian == nil {
+		in.jacobian = util.MakeSquare(in.n)
+	}
+	in.EvaluationCount += in.EvaluateJacobian(t, yT, in.f0, in.jacobian)
+	in.jacobianFresh = true
+	in.factorized = false
+}
+
+// factorizes the matrices of the Newton iteration and the error estimate for the step size
+func (s *radau) factorize(in *integration, step float64) error {
+	n := in.n
+	var i, j, row, col uint
+	for i = 0; i < s.Stages; i++ {
+		for j = 0; j < s.Stages; j++ {
+			factor := step * s.a[i][j]
+			for row = 0; row < n; row++ {
+				for col = 0; col < n; col++ {
+					value := -factor * in.jacobian[row][col]
+					if i == j {
+						value += in.mass[row][col]
+					}
+					in.system[i*n+row][j*n+col] = value
+				}
+			}
+		}
+	}
+	for row = 0; row < n; row++ {
+		for col = 0; col < n; col++ {
+			in.errorMatrix[row][col] = s.gamma0/step*in.mass[row][col] - in.jacobian[row][col]
+		}
+	}
+
+	if err := in.systemLU.Factorize(in.system); err != nil {
+		return err
+	}
+	if err := in.errorLU.Factorize(in.errorMatrix); err != nil {
+		return err
+	}
+	in.factorizedStep, in.factorized = step, true
+	return nil
+}
+
+// solves the collocation equations M(Y_i) K_i = f(t + c_i h, Y_i) with Y_i = yT + h sum_j a_ij K_j
+// and computes yNew, returns false if the Newton iteration did not converge
+func (s *radau) stages(in *integration, t, step float64, yT []float64) bool {
+	if !in.factorized || in.factorizedStep != step {
+		if s.factorize(in, step) != nil {
+			in.factorized = false
+			return false
+		}
+	}
+
+	n := in.n
+	var stg, j, id uint
+	for stg = 0; stg < s.Stages; stg++ {
+		for id = 0; id < n; id++ {
+			in.ks[stg][id] = 0.0
+		}
+	}
+
+	deltaNorm, converged := 0.0, false
+	for iteration := 0; iteration < maxNewtonIterations; iteration++ {
+		in.NewtonIterations++
+
+		// residual f(Y_i) - M(Y_i) K_i of all stages
+		for stg = 0; stg < s.Stages; stg++ {
+			for id = 0; id < n; id++ {
+				in.ys[stg][id] = yT[id]
+			}
+			for j = 0; j < s.Stages; j++ {
+				for id = 0; id < n; id++ {
+					in.ys[stg][id] += step * s.a[stg][j] * in.ks[j][id]
+				}
+			}
+			tStage := t + s.c[stg]*step
+			in.EvaluateBlocked(tStage, in.ys[stg], in.fs[stg])
+			in.EvaluationCount++
+
+			stageMass := in.mass
+			if in.MassFunction != nil {
+				in.EvaluateMass(tStage, in.ys[stg], in.stageMass)
+				stageMass = in.stageMass
+			}
+			linalg.MulVec(stageMass, in.ks[stg], in.work)
+			for id = 0; id < n; id++ {
+				in.residual[stg*n+id] = in.fs[stg][id] - in.work[id]
+			}
+		}
+		if util.FirstNonFinite(in.residual) >= 0 {
+			return false
+		}
+
+		in.systemLU.Solve(in.residual)
+
+		// the size of the update is measured on the stage values
+		lastDeltaNorm := deltaNorm
+		deltaNorm = 0.0
+		for stg = 0; stg < s.Stages; stg++ {
+			for id = 0; id < n; id++ {
+				in.ks[stg][id] += in.residual[stg*n+id]
+				in.work[id] = step * in.residual[stg*n+id]
+			}
+			deltaNorm = math.Max(deltaNorm, in.ErrorNorm(in.work, yT, in.ys[stg]))
+		}
+		if !util.IsFinite(deltaNorm) {
+			return false
+		}
+
+		if deltaNorm <= 0.01*newtonTolerance {
+			in.lastContraction, converged = 0.0, true
+			break
+		}
+		if iteration == 0 {
+			continue
+		}
+		contraction := deltaNorm / lastDeltaNorm
+		in.lastContraction = contraction
+		if contraction >= 1.0 {
+			return false
+		}
+		if contraction/(1.0-contraction)*deltaNorm <= newtonTolerance {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return false
+	}
+
+	// stiffly accurate, the new solution is the last stage value
+	last := s.Stages - 1
+	for id = 0; id < n; id++ {
+		in.yNew[id] = yT[id]
+	}
+	for j = 0; j < s.Stages; j++ {
+		for id = 0; id < n; id++ {
+			in.yNew[id] += step * s.a[last][j] * in.ks[j][id]
+		}
+	}
+	return true
+}
+
+// estimates the error by (gamma0/h M - J)^-1 (f0 + M sum_i e_i/h Z_i) with the stage
+// increments Z_i = Y_i - yT, the estimate is refined once after a rejection
+func (s *radau) estimateError(in *integration, t, step float64, yT []float64) float64 {
+	n := in.n
+	var stg, j, id uint
+
+	// sum_i e_i/h Z_i = sum_j (sum_i e_i a_ij) K_j
+	for id = 0; id < n; id++ {
+		in.work[id] = 0.0
+	}
+	for j = 0; j < s.Stages; j++ {
+		weight := 0.0
+		for stg = 0; stg < s.Stages; stg++ {
+			weight += s.e[stg] * s.a[stg][j]
+		}
+		for id = 0; id < n; id++ {
+			in.work[id] += weight * in.ks[j][id]
+		}
+	}
+	linalg.MulVec(in.mass, in.work, in.fTemp)
+	copy(in.work, in.fTemp)
+
+	for id = 0; id < n; id++ {
+		in.yError[id] = in.f0[id] + in.work[id]
+	}
+	in.errorLU.Solve(in.yError)
+	errorEstimate := in.ErrorNorm(in.yError, yT, in.yNew)
+
+	if errorEstimate >= 1.0 && (in.firstStep || in.lastRejected) {
+		for id = 0; id < n; id++ {
+			in.yError[id] += yT[id]
+		}
+		in.EvaluateBlocked(t, in.yError, in.fTemp)
+		in.EvaluationCount++
+		for id = 0; id < n; id++ {
+			in.yError[id] = in.fTemp[id] + in.work[id]
+		}
+		in.errorLU.Solve(in.yError)
+		errorEstimate = in.ErrorNorm(in.yError, yT, in.yNew)
+	}
+	return errorEstimate
+}
+
+// step size factor for the error estimate of third order
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -0.25)
+	return math.Max(0.2, math.Min(factor, 5.0)) // safety interval
+}
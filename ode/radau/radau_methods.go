@@ -0,0 +1,61 @@
+package radau
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+type RadauMethod uint
+
+const (
+	RadauIIA5            = RadauMethod(iota) // 3 stage Radau IIA, L-stable, stiffly accurate, order 5
+	NumberOfRadauMethods = uint(iota)
+)
+
+const (
+	// Newton iterations per step before the Jacobian is recomputed or the step rejected
+	maxNewtonIterations = 7
+	// the Newton iteration stops when the estimated remaining error is below
+	// newtonTolerance times the tolerance of the integration
+	newtonTolerance = 0.03
+	// the Jacobian is recomputed after a step whose Newton iteration contracted slower
+	recomputeContraction = 0.001
+	// Newton iterations for consistent initial values
+	maxConsistencyIterations = 10
+)
+
+// NewRadau creates a fully implicit Runge-Kutta integrator for differential-algebraic systems
+// of index 1. It uses dense linear algebra of size Stages * n and is meant for small
+// and medium sized systems. With the identity as mass matrix it is an ode.Integrator as well
+func NewRadau(m RadauMethod) (i ode.DAEIntegrator, err error) {
+	var s radau
+	s.method = m
+
+	switch m {
+	case RadauIIA5:
+		s.Name = "RadauIIA5"
+		s.Stages = 3
+		s.Order = 5
+		setCoeffsRadauIIA5(&s)
+	default:
+		err = &ode.ConfigError{Field: "RadauMethod", Reason: "unknown radau method"}
+	}
+
+	i = &s
+	return
+}
+
+// coefficients and error estimate of RADAU5 by Hairer and Wanner
+func setCoeffsRadauIIA5(s *radau) {
+	sq6 := math.Sqrt(6.0)
+
+	s.c = []float64{(4.0 - sq6) / 10.0, (4.0 + sq6) / 10.0, 1.0}
+	s.a = [][]float64{
+		{(88.0 - 7.0*sq6) / 360.0, (296.0 - 169.0*sq6) / 1800.0, (-2.0 + 3.0*sq6) / 225.0},
+		{(296.0 + 169.0*sq6) / 1800.0, (88.0 + 7.0*sq6) / 360.0, (-2.0 - 3.0*sq6) / 225.0},
+		{(16.0 - sq6) / 36.0, (16.0 + sq6) / 36.0, 1.0 / 9.0},
+	}
+	s.e = []float64{-(13.0 + 7.0*sq6) / 3.0, (-13.0 + 7.0*sq6) / 3.0, -1.0 / 3.0}
+	// real eigenvalue of the inverse coefficient matrix
+	s.gamma0 = 30.0 / (6.0 + math.Cbrt(81.0) - math.Cbrt(9.0))
+}
@@ -0,0 +1,111 @@
+package radau
+
+import (
+	"errors"
+	. "github.com/rollingthunder/differential/ode"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"math"
+	"testing"
+)
+
+// Robertson's chemical reaction with the conservation law as algebraic equation
+func robertsonDAE(t float64, y []float64, dy []float64) {
+	Robertson(t, y, dy)
+	dy[2] = y[0] + y[1] + y[2] - 1.0
+}
+
+var robertsonMass = [][]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 0}}
+
+func TestAllRadau(t *testing.T) {
+	integrators := make([]Integrator, NumberOfRadauMethods)
+	for j := 0; j < int(NumberOfRadauMethods); j++ {
+		s, err := NewRadau(RadauMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create Radau Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = s.(Integrator)
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestRadauRobertsonDAE(t *testing.T) {
+	for j := 0; j < int(NumberOfRadauMethods); j++ {
+		s, _ := NewRadau(RadauMethod(j))
+		// the algebraic variable is inconsistent and corrected before the first step
+		y := []float64{1, 0, 0.5}
+		config := DAEConfig{
+			Config: Config{Fcn: robertsonDAE, AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-6},
+			Mass:   robertsonMass,
+		}
+
+		stat, err := s.IntegrateDAE(0, 40, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+
+		CheckRelative(t, s.Info().Name, y, RobertsonReference, 1e-5)
+		if constraint := y[0] + y[1] + y[2] - 1.0; math.Abs(constraint) > 1e-12 {
+			t.Errorf("%s: algebraic equation violated by %g", s.Info().Name, constraint)
+		}
+		CheckStability(t, s.Info().Name, stat, 200)
+		if testing.Verbose() {
+			t.Logf("Robertson %s: %d steps, %d rejected, %d evaluations, %d newton iterations",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount, stat.NewtonIterations)
+		}
+	}
+}
+
+func TestRadauStateDependentMass(t *testing.T) {
+	// (1 + y^2) y' = (1 + y^2) cos(t), y = sin(t)
+	fcn := func(t float64, y []float64, dy []float64) {
+		dy[0] = (1.0 + y[0]*y[0]) * math.Cos(t)
+	}
+	mass := func(t float64, y []float64, m [][]float64) {
+		m[0][0] = 1.0 + y[0]*y[0]
+	}
+
+	for j := 0; j < int(NumberOfRadauMethods); j++ {
+		s, _ := NewRadau(RadauMethod(j))
+		y := []float64{0}
+		config := DAEConfig{Config: Config{Fcn: fcn, AbsoluteTolerance: 1e-8}, MassFunction: mass}
+
+		if _, err := s.IntegrateDAE(0, 10, y, &config); err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		if math.Abs(y[0]-math.Sin(10)) > 1e-6 {
+			t.Errorf("%s: result %g differs from solution %g", s.Info().Name, y[0], math.Sin(10))
+		}
+	}
+}
+
+func TestConsistentInitialValues(t *testing.T) {
+	y := []float64{0.5, 1e-5, 0}
+	config := DAEConfig{Config: Config{Fcn: robertsonDAE, AbsoluteTolerance: 1e-12}, Mass: robertsonMass}
+	if _, err := ConsistentInitialValues(0, y, &config); err != nil {
+		t.Fatalf("Initialization failed - %s", err.Error())
+	}
+	if y[0] != 0.5 || y[1] != 1e-5 {
+		t.Errorf("Differential variables changed to %g, %g", y[0], y[1])
+	}
+	if math.Abs(y[2]-(0.5-1e-5)) > 1e-12 {
+		t.Errorf("Algebraic variable %g, expected %g", y[2], 0.5-1e-5)
+	}
+
+	// a zero row without a zero column
+	config.Mass = [][]float64{{1, 0, 1}, {0, 1, 0}, {0, 0, 0}}
+	if _, err := ConsistentInitialValues(0, y, &config); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error, got %v", err)
+	}
+
+	// the algebraic equation has no solution
+	config.Mass = robertsonMass
+	config.Fcn = func(t float64, y []float64, dy []float64) {
+		robertsonDAE(t, y, dy)
+		dy[2] = y[2]*y[2] + 1.0
+	}
+	if _, err := ConsistentInitialValues(0, y, &config); !errors.Is(err, ErrInconsistent) {
+		t.Errorf("Expected inconsistency error, got %v", err)
+	}
+}
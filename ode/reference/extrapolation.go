@@ -0,0 +1,252 @@
+// Package reference computes reference solutions to many digits by extrapolation of the
+// modified midpoint rule at high order over precision.Big, or any other precision.Real
+package reference
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
+	"math"
+	"math/big"
+	"sync"
+)
+
+// bounds of the number of rows of the extrapolation table
+const (
+	minRows = 4
+	maxRows = 40
+)
+
+// Fcn is a right hand side with the time at the precision of T
+type Fcn[T any] func(t T, yT []T, dy_out []T)
+
+// Autonomous adapts fcn, which gets the time rounded to float64. That is exact only for
+// a right hand side independent of t, like those of problems.ReferenceFcn
+func Autonomous[T precision.Real[T]](fcn ad.Fcn[T]) Fcn[T] {
+	return func(t T, yT []T, dy_out []T) { fcn(t.Real(), yT, dy_out) }
+}
+
+// Extrapolation is the Gragg-Bulirsch-Stoer method with the harmonic sequence 2, 4, 6, ...
+// of midpoint steps over the floating point type T. Its number of rows and so its order is
+// fixed by the tolerance, only the step size is adaptive. The rows of the extrapolation table
+// are computed concurrently, so the right hand side has to be safe for concurrent use
+type Extrapolation[T precision.Real[T]] struct {
+	IntegratorInfo
+}
+
+type integration[T precision.Real[T]] struct {
+	Config
+	Statistics
+	fcn Fcn[T]
+	n   int
+
+	rows     int
+	sequence []int
+	// Aitken-Neville coefficients at the precision of the initial values
+	coeffs [][]T
+
+	f0 []T
+	// first columns of the extrapolation table, the extrapolated values are computed in place
+	table [][]T
+}
+
+func NewExtrapolation[T precision.Real[T]]() *Extrapolation[T] {
+	return &Extrapolation[T]{IntegratorInfo{Name: "Extrapolation", Stages: maxRows, Order: 2 * maxRows}}
+}
+
+// Integrate advances yT from t to tEnd with the right hand side fcn, Fcn, FcnBlocked and BlockSize
+// of config are ignored. The step size control and the error control are float64, the times passed
+// to fcn are at the precision of T. The precision of yT should exceed the digits of the tolerances
+func (e *Extrapolation[T]) Integrate(t, tEnd float64, yT []T, fcn Fcn[T], config *Config) (stat Statistics, err error) {
+	if fcn == nil {
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+	if config == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	var prototype T
+	if len(yT) > 0 {
+		prototype = yT[0]
+	}
+	adapted := *config
+	timed := func(t float64, yT []T, dy_out []T) { fcn(prototype.Const(t), yT, dy_out) }
+	adapted.Fcn, adapted.FcnBlocked, adapted.BlockSize = ad.Function(timed, prototype), nil, 0
+
+	effective, err := adapted.ValidateAndPrepare(uint(len(yT)), t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = e.Supports(&effective); err != nil {
+		return
+	}
+	in := setupIntegration(yT, fcn, &effective)
+	order := uint(2 * in.rows)
+
+	// the time and the step size at the precision of T
+	tT, tEndT := prototype.Const(t), prototype.Const(tEnd)
+	var step T
+
+	in.fcn(tT, yT, in.f0)
+	in.EvaluationCount = 1
+
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, precision.ToFloat64(yT), precision.ToFloat64(in.f0), &in.Config, order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext >= tEnd {
+			stepNext = tEnd - t
+			step = tEndT.Sub(tT)
+		} else {
+			step = prototype.Const(stepNext)
+		}
+		in.StepCount++
+
+		errorEstimate := in.extrapolate(tT, step, yT)
+		stepEstimate = stepNext * stepFactor(errorEstimate, in.rows)
+
+		if !(errorEstimate <= 1.0) {
+			// reject step
+			in.RejectedCount++
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			tT = tT.Add(step)
+			copy(yT, in.table[in.rows-1])
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			if in.OneStepOnly {
+				break
+			}
+			in.fcn(tT, yT, in.f0)
+			in.EvaluationCount++
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func setupIntegration[T precision.Real[T]](yT []T, fcn Fcn[T], c *Config) (in integration[T]) {
+	in.Config = *c
+	in.fcn = fcn
+	in.n = len(yT)
+
+	// rows from the digits of the tolerance, like GBS
+	digits := -math.Log10(math.Min(c.AbsoluteTolerance, c.RelativeTolerance))
+	in.rows = int(0.6*digits+0.5) + 1
+	in.rows = int(math.Max(minRows, math.Min(float64(in.rows), maxRows)))
+
+	in.sequence = make([]int, in.rows)
+	for j := range in.sequence {
+		in.sequence[j] = 2 * (j + 1)
+	}
+
+	// Aitken-Neville coefficients 1/((n_j/n_(j-l))^2 - 1) = n_(j-l)^2 / (n_j^2 - n_(j-l)^2)
+	in.coeffs = make([][]T, in.rows)
+	for j := range in.coeffs {
+		in.coeffs[j] = make([]T, j)
+		for l := 1; l <= j; l++ {
+			nj, nl := int64(in.sequence[j]), int64(in.sequence[j-l])
+			in.coeffs[j][l-1] = yT[0].Rat(big.NewRat(nl*nl, nj*nj-nl*nl))
+		}
+	}
+
+	in.f0 = make([]T, in.n)
+	in.table = make([][]T, in.rows)
+	for j := range in.table {
+		in.table[j] = make([]T, in.n)
+	}
+	return
+}
+
+// extrapolate computes the rows of the extrapolation table for a step from t and returns the error
+// estimate, the difference of the extrapolations of all rows and of all but the last row
+func (in *integration[T]) extrapolate(t T, step T, yT []T) float64 {
+	var wg sync.WaitGroup
+	for j := 0; j < in.rows; j++ {
+		wg.Add(1)
+		go func(j int) {
+			defer wg.Done()
+			in.midpoint(t, step, yT, j)
+		}(j)
+	}
+	wg.Wait()
+	for _, steps := range in.sequence {
+		in.EvaluationCount += uint(steps - 1)
+	}
+
+	// Aitken-Neville scheme column by column, afterwards table[j] holds the extrapolation of rows 0..j
+	for j := 1; j < in.rows; j++ {
+		for l := in.rows - 1; l >= j; l-- {
+			coeff := in.coeffs[l][j-1]
+			for id := range yT {
+				in.table[l][id] = in.table[l][id].Add(in.table[l][id].Sub(in.table[l-1][id]).Mul(coeff))
+			}
+		}
+	}
+
+	last, previous := in.table[in.rows-1], in.table[in.rows-2]
+	yError := make([]float64, in.n)
+	for id := range yError {
+		yError[id] = last[id].Sub(previous[id]).Real()
+	}
+	return in.ErrorNorm(yError, precision.ToFloat64(yT), precision.ToFloat64(last))
+}
+
+// midpoint computes the first column of row j, the modified midpoint rule with sequence[j] steps
+func (in *integration[T]) midpoint(t T, step T, yT []T, j int) {
+	steps := in.sequence[j]
+	h := step.Div(step.Const(float64(steps)))
+	h2 := h.Scale(2.0)
+
+	zPrevious, zCurrent, f := make([]T, in.n), in.table[j], make([]T, in.n)
+	for id := range yT {
+		zPrevious[id] = yT[id]
+		zCurrent[id] = yT[id].Add(h.Mul(in.f0[id]))
+	}
+	for m := 1; m < steps; m++ {
+		in.fcn(t.Add(h.Mul(h.Const(float64(m)))), zCurrent, f)
+		for id := range yT {
+			zNext := zPrevious[id].Add(h2.Mul(f[id]))
+			zPrevious[id] = zCurrent[id]
+			zCurrent[id] = zNext
+		}
+	}
+}
+
+// step size factor for the error estimate of the last row, the next to last
+// extrapolated value has order 2 (rows - 1)
+func stepFactor(errorEstimate float64, rows int) float64 {
+	if math.IsNaN(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.94 * math.Pow(0.65/errorEstimate, 1.0/float64(2*rows-1))
+	return math.Max(0.02, math.Min(factor, 4.0)) // safety interval
+}
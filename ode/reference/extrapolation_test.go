@@ -0,0 +1,109 @@
+package reference
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+// oscillator is the harmonic oscillator y' = z, z' = -y, the solution from (0, 1) is (sin t, cos t)
+func oscillator[T precision.Real[T]](t T, yT []T, dy_out []T) {
+	dy_out[0] = yT[1]
+	dy_out[1] = yT[0].Neg()
+}
+
+func TestExtrapolationBig(t *testing.T) {
+	const bits = 200
+	y := []precision.Big{precision.NewBig(0.0, bits), precision.NewBig(1.0, bits)}
+	config := &Config{AbsoluteTolerance: 1e-40, RelativeTolerance: 1e-40}
+
+	stat, err := NewExtrapolation[precision.Big]().Integrate(0.0, 1.0, y, oscillator[precision.Big], config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := precision.NewBig(1.0, bits)
+	expected := []precision.Big{one.Sin(), one.Cos()}
+	for i := range y {
+		if e := math.Abs(y[i].Sub(expected[i]).Real()); e > 1e-36 {
+			t.Errorf("Component %d: %s differs from %s by %g", i, y[i], expected[i], e)
+		}
+	}
+	if stat.CurrentTime != 1.0 {
+		t.Errorf("Integrated up to %g instead of 1", stat.CurrentTime)
+	}
+	t.Logf("Steps %d, rejected %d, evaluations %d", stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+}
+
+func TestExtrapolationNonAutonomous(t *testing.T) {
+	// y' = cos t, y(0) = 0 needs the time beyond float64 precision for y(1) = sin 1
+	const bits = 200
+	cosine := func(t precision.Big, yT []precision.Big, dy_out []precision.Big) { dy_out[0] = t.Cos() }
+	y := []precision.Big{precision.NewBig(0.0, bits)}
+	config := &Config{AbsoluteTolerance: 1e-40, RelativeTolerance: 1e-40}
+
+	if _, err := NewExtrapolation[precision.Big]().Integrate(0.0, 1.0, y, cosine, config); err != nil {
+		t.Fatal(err)
+	}
+	expected := precision.NewBig(1.0, bits).Sin()
+	if e := math.Abs(y[0].Sub(expected).Real()); e > 1e-36 {
+		t.Errorf("%s differs from %s by %g", y[0], expected, e)
+	}
+}
+
+func TestExtrapolationDoubleDouble(t *testing.T) {
+	y := []precision.DoubleDouble{precision.NewDoubleDouble(0.0), precision.NewDoubleDouble(1.0)}
+	config := &Config{AbsoluteTolerance: 1e-24, RelativeTolerance: 1e-24}
+
+	_, err := NewExtrapolation[precision.DoubleDouble]().Integrate(0.0, 10.0, y, oscillator[precision.DoubleDouble], config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ten := precision.NewDoubleDouble(10.0)
+	expected := []precision.DoubleDouble{ten.Sin(), ten.Cos()}
+	for i := range y {
+		if e := math.Abs(y[i].Sub(expected[i]).Real()); e > 1e-21 {
+			t.Errorf("Component %d: %s differs from %s by %g", i, y[i], expected[i], e)
+		}
+	}
+}
+
+func TestExtrapolationConfig(t *testing.T) {
+	e := NewExtrapolation[ad.Float]()
+	y := []ad.Float{0.0, 1.0}
+	if _, err := e.Integrate(0.0, 1.0, y, nil, &Config{}); err == nil {
+		t.Error("Expected an error without right hand side")
+	}
+	if _, err := e.Integrate(0.0, 1.0, y, oscillator[ad.Float], nil); err == nil {
+		t.Error("Expected an error without configuration")
+	}
+}
+
+// the embedded references are reproduced to 30 digits
+func TestExtrapolationReferences(t *testing.T) {
+	if testing.Short() {
+		t.Skipf("Skipping because we're running in short test mode.")
+	}
+	const bits = 150
+	for _, name := range problems.ReferenceNames() {
+		ref, err := problems.LoadReference(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, _ := problems.NewReferenceProblem(ref.Problem, ref.Size)
+		fcn, _ := problems.ReferenceFcn[precision.Big](ref.Problem, ref.Size)
+		y := precision.FromFloat64(p.Initialize(), precision.NewBig(0.0, bits))
+
+		config := &Config{AbsoluteTolerance: 1e-32, RelativeTolerance: 1e-32}
+		if _, err := NewExtrapolation[precision.Big]().Integrate(ref.T0, ref.T1, y, Autonomous(fcn), config); err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range ref.Floats(bits) {
+			if e := math.Abs(y[i].Sub(precision.BigFromFloat(expected)).Real()); e > 1e-30 {
+				t.Errorf("%s component %d: %s differs from %s by %g", name, i, y[i], ref.Values[i], e)
+			}
+		}
+	}
+}
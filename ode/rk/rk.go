@@ -1,123 +1,181 @@
 package rk
 
 import (
-	"errors"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/util"
 	"math"
 )
 
 type RKMethod int
 
-type rk struct {
+// GenericRK integrates with an explicit Runge-Kutta method over the floating point type T.
+// The coefficients are rounded from exact rationals to the precision of the initial values.
+// Time and the error control stay float64, the steps add up to tEnd - t at the precision of T,
+// but the right hand side is evaluated at float64 times
+type GenericRK[T precision.Real[T]] struct {
 	IntegratorInfo
-	method           RKMethod
-	firstStageAsLast bool
-	b, c, e          []float64
-	a                [][]float64
+	method  RKMethod
+	tableau *tableau
+	// coefficients at the precision of the zero value of T
+	coefficients coefficients[T]
+}
+
+// rk is the float64 integrator, it computes with ad.Float on the memory of the caller
+type rk struct {
+	generic *GenericRK[ad.Float]
+}
+
+// evaluation provides the right hand side to the steps, the stages may be evaluated in blocks
+type evaluation[T precision.Real[T]] struct {
+	fcn, stages ad.Fcn[T]
+}
+
+func (r *rk) Info() IntegratorInfo {
+	return r.generic.Info()
 }
 
 //-- performs Runge-Kutta integration
-func (r *rk) Integrate(t, tEnd float64, yT []float64, c *Config) (stat Statistics, err error) {
+func (r *rk) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	n := uint(len(yT))
+	return r.generic.integrate(t, tEnd, precision.Float64s(yT), config, func(c *Config) evaluation[ad.Float] {
+		fcn := func(t float64, yT, dy_out []ad.Float) {
+			y, dy := float64s(yT), float64s(dy_out)
+			c.Fcn(t, y, dy)
+		}
+		stages := func(t float64, yT, dy_out []ad.Float) {
+			y, dy := float64s(yT), float64s(dy_out)
+			for block := uint(0); block < n; block += c.BlockSize {
+				c.FcnBlocked(block, c.BlockSize, t, y, dy)
+			}
+		}
+		return evaluation[ad.Float]{fcn: fcn, stages: stages}
+	})
+}
+
+// Integrate advances yT from t to tEnd with the right hand side fcn,
+// Fcn, FcnBlocked and BlockSize of config are ignored
+func (r *GenericRK[T]) Integrate(t, tEnd float64, yT []T, fcn ad.Fcn[T], config *Config) (stat Statistics, err error) {
+	if fcn == nil {
+		err = &ConfigError{Field: "Fcn", Reason: "no evaluation function specified"}
+		return
+	}
+	if config == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+
+	// the float64 right hand side for the initial step size estimate
+	var prototype T
+	if len(yT) > 0 {
+		prototype = yT[0]
+	}
+	adapted := *config
+	adapted.Fcn, adapted.FcnBlocked, adapted.BlockSize = ad.Function(fcn, prototype), nil, 0
+	return r.integrate(t, tEnd, yT, &adapted, func(c *Config) evaluation[T] {
+		return evaluation[T]{fcn: fcn, stages: fcn}
+	})
+}
+
+func (r *GenericRK[T]) integrate(t, tEnd float64, yT []T, config *Config, evaluator func(c *Config) evaluation[T]) (stat Statistics, err error) {
 	var n uint = uint(len(yT))
 
-	err = c.ValidateAndPrepare(n)
+	stat.Effective, err = config.ValidateAndPrepare(n, t, tEnd)
 
 	if err != nil {
 		return
 	}
+	c := &stat.Effective
+	eval := evaluator(c)
 
-	// set default parameters if necessary
-	if c.MaxStepSize <= 0.0 {
-		c.MaxStepSize = tEnd - t
-	}
-	if c.MinStepSize <= 0.0 {
-		c.MinStepSize = 1e-10
-	}
-	if c.MaxStepCount == 0 {
-		c.MaxStepCount = 1000000
-	}
-	if c.AbsoluteTolerance <= 0.0 {
-		c.AbsoluteTolerance = 1e-4
+	if r.tableau == nil {
+		err = &ConfigError{Reason: "RK Method coefficients not initialized"}
+		return
 	}
-	if c.RelativeTolerance <= 0.0 {
-		c.RelativeTolerance = c.AbsoluteTolerance
+	// round the coefficients again if the initial values differ in precision from the zero value
+	co := &r.coefficients
+	if yT[0].Precision() != co.b[0].Precision() {
+		rounded := roundTableau(r.tableau, yT[0])
+		co = &rounded
 	}
 
-	if r.a == nil || r.b == nil || r.c == nil {
-		err = errors.New("RK Method coefficients not initialized")
-		return
+	// allocate temp matrices, the first stage is the derivative at the beginning of the step
+	yCurrent := make([]T, n)
+	yError := make([]T, n)
+	ks := make([][]T, r.Stages)
+	for stg := range ks {
+		ks[stg] = make([]T, n)
+	}
+	var yOld, fcnNew []T
+	if c.DenseOutput != nil {
+		yOld, fcnNew = make([]T, n), make([]T, n)
 	}
 
-	// allocate temp matrices
-	fcnValue := make([]float64, n)
-	yCurrent := make([]float64, n)
-	yError := make([]float64, n)
-	ks := util.MakeRectangular(r.Stages, uint(n))
-
-	c.Fcn(t, yT, fcnValue)
+	eval.fcn(t, yT, ks[0])
 	stat.EvaluationCount = 1
 
 	// compute initial step size if not set
 	stepEstimate := c.InitialStepSize
 	if stepEstimate <= 0.0 {
-		stepEstimate = EstimateStepSize(t, yT, fcnValue, c, r.Order)
+		stepEstimate = EstimateStepSize(t, precision.ToFloat64(yT), precision.ToFloat64(ks[0]), c, r.Order)
 	}
 	var stepNext float64
+	// the time and the step size at the precision of T
+	tT, tEndT := yT[0].Const(t), yT[0].Const(tEnd)
+	var step T
 	// repeat until tend
 	for t < tEnd && err == nil {
+		if c.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
 		// Set new step size
 		stepNext = stepEstimate
 
 		stat.StepCount++
-		if t+stepNext > tEnd {
+		if t+stepNext >= tEnd {
 			stepNext = tEnd - t
+			step = tEndT.Sub(tT)
+		} else {
+			step = yT[0].Const(stepNext)
 		}
 
 		// compute stages
-		var stg, id, ic uint
+		var stg uint
 		for stg = 1; stg < r.Stages; stg++ {
-			tCurrent := t + stepNext*r.c[stg]
-
-			for id = 0; id < n; id++ {
-				yCurrent[id] = yT[id] + stepNext*r.a[stg][0]*fcnValue[id]
-			}
-
-			for ic = 1; ic < stg; ic++ {
-				for id = 0; id < n; id++ {
-					yCurrent[id] = yCurrent[id] + stepNext*r.a[stg][ic]*ks[ic][id]
-				}
-			}
-
-			var block uint
-			for block = 0; block < n; block += c.BlockSize {
-				c.FcnBlocked(block, c.BlockSize, tCurrent, yCurrent, ks[stg])
-			}
+			combine(yCurrent, yT, step, co.a[stg][:stg], ks)
+			eval.stages(t+stepNext*co.c[stg], yCurrent, ks[stg])
 			stat.EvaluationCount++
 		}
 
 		// compute error estimate:
-		for id = 0; id < n; id++ {
-			yError[id] = stepNext * r.e[0] * fcnValue[id]
-		}
-
-		for stg = 1; stg < r.Stages; stg++ {
-			for id = 0; id < n; id++ {
-				yError[id] = yError[id] + stepNext*r.e[stg]*ks[stg][id]
-			}
-		}
+		combine(yError, nil, step, co.e, ks)
 
 		// compute error quotient
 		relativeError := 0.0
-		for id = 0; id < n; id++ {
-			currentTolerance := c.AbsoluteTolerance + c.RelativeTolerance*math.Abs(yT[id])
-			relativeError = relativeError + math.Pow(yError[id]/currentTolerance, 2.0)
+		errorCount := uint(c.ErrorCount(int(n)))
+		yErrorReal, yReal := precision.ToFloat64(yError), precision.ToFloat64(yT)
+		for id := uint(0); id < errorCount; id++ {
+			currentTolerance := c.AbsoluteTolerance + c.RelativeTolerance*math.Abs(yReal[id])
+			relativeError = relativeError + math.Pow(yErrorReal[id]/currentTolerance, 2.0)
 		}
-		relativeError = math.Sqrt(relativeError / float64(n))
+		relativeError = math.Sqrt(relativeError / float64(errorCount))
 
 		// new stepsize estimate
-		stepEstimate = 0.9 * math.Exp(-math.Log(1.0e-8+relativeError)/float64(r.Order))
-		stepEstimate = stepNext * math.Max(0.2, math.Min(stepEstimate, 2.0)) // safety interval
+		if util.IsFinite(relativeError) {
+			stepEstimate = 0.9 * math.Exp(-math.Log(1.0e-8+relativeError)/float64(r.Order))
+			stepEstimate = stepNext * math.Max(0.2, math.Min(stepEstimate, 2.0)) // safety interval
+		} else {
+			// NaN or Inf in the stages, shrink as much as possible
+			if c.NonFinitePolicy == AbortOnNonFinite {
+				err = &NonFiniteError{Time: t, StepSize: stepNext, Index: nonFiniteIndex(yError, yCurrent, yT, step, co.b, ks, c)}
+				break
+			}
+			relativeError = math.Inf(1)
+			stepEstimate = 0.2 * stepNext
+		}
 
 		// reject step
 		if relativeError > 1.0 {
@@ -125,18 +183,37 @@ func (r *rk) Integrate(t, tEnd float64, yT []float64, c *Config) (stat Statistic
 
 			// report failure, step size too small
 			if stepEstimate < c.MinStepSize {
-				err = errors.New("stepsize too small")
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
 				break
 			}
 		} else {
 			// accept step and compute new solution
-			t += stepNext
-			for id = 0; id < n; id++ {
-				yT[id] = yT[id] + stepNext*r.b[0]*fcnValue[id]
+			if c.DenseOutput != nil {
+				copy(yOld, yT)
 			}
-			for stg = 1; stg < r.Stages; stg++ {
-				for id = 0; id < n; id++ {
-					yT[id] = yT[id] + stepNext*r.b[stg]*ks[stg][id]
+			t += stepNext
+			tT = tT.Add(step)
+			combine(yT, yT, step, co.b, ks)
+
+			// the Hermite interpolant needs the derivative at the new point
+			newEvaluated := false
+			if c.DenseOutput != nil {
+				if r.method == DoPri5 {
+					stages := make([][]float64, r.Stages)
+					for stg := range stages {
+						stages[stg] = precision.ToFloat64(ks[stg])
+					}
+					c.DenseOutput(newDopriInterpolant(t-stepNext, stepNext, precision.ToFloat64(yOld), precision.ToFloat64(yT), stages[0], stages))
+				} else {
+					if r.tableau.firstStageAsLast {
+						copy(fcnNew, ks[r.Stages-1])
+					} else {
+						eval.fcn(t, yT, fcnNew)
+						stat.EvaluationCount++
+						newEvaluated = true
+					}
+					c.DenseOutput(NewHermiteInterpolant(t-stepNext, t, precision.ToFloat64(yOld), precision.ToFloat64(yT),
+						precision.ToFloat64(ks[0]), precision.ToFloat64(fcnNew)))
 				}
 			}
 
@@ -144,17 +221,19 @@ func (r *rk) Integrate(t, tEnd float64, yT []float64, c *Config) (stat Statistic
 			if c.OneStepOnly {
 				break
 			} else {
-				if r.firstStageAsLast {
-					copy(fcnValue, ks[r.Stages-1])
+				if r.tableau.firstStageAsLast {
+					copy(ks[0], ks[r.Stages-1])
+				} else if newEvaluated {
+					copy(ks[0], fcnNew)
 				} else {
-					c.Fcn(t, yT, fcnValue)
+					eval.fcn(t, yT, ks[0])
 					stat.EvaluationCount++
 				}
 			}
 		}
 		// failure, too many steps
 		if stat.StepCount > c.MaxStepCount {
-			err = errors.New("maximum step count exceeded")
+			err = &MaxStepsError{Time: t, StepCount: stat.StepCount}
 			break
 		}
 	}
@@ -166,3 +245,76 @@ func (r *rk) Integrate(t, tEnd float64, yT []float64, c *Config) (stat Statistic
 	return
 
 }
+
+// nonFiniteIndex returns the component responsible for a non-finite error quotient: the first
+// non-finite component of the error estimate, of the new solution, which is computed into yNew_out,
+// or of the current solution yT, else the component whose quotient overflowed
+func nonFiniteIndex[T precision.Real[T]](yError, yNew_out, yT []T, step T, b []T, ks [][]T, c *Config) int {
+	if index := util.FirstNonFinite(precision.ToFloat64(yError)); index >= 0 {
+		return index
+	}
+	combine(yNew_out, yT, step, b, ks)
+	if index := util.FirstNonFinite(precision.ToFloat64(yNew_out)); index >= 0 {
+		return index
+	}
+	yReal := precision.ToFloat64(yT)
+	if index := util.FirstNonFinite(yReal); index >= 0 {
+		return index
+	}
+	index, largest := 0, 0.0
+	for id := 0; id < c.ErrorCount(len(yError)); id++ {
+		quotient := math.Abs(yError[id].Real() / (c.AbsoluteTolerance + c.RelativeTolerance*math.Abs(yReal[id])))
+		if quotient > largest {
+			index, largest = id, quotient
+		}
+	}
+	return index
+}
+
+// combine computes y_out = y + step (w_0 k_0 + w_1 k_1 + ...) for the given weights,
+// starting from zero if y is nil. y_out may be y
+func combine[T precision.Real[T]](y_out, y []T, step T, w []T, ks [][]T) {
+	if out, ok := precision.AsFloat64(y_out); ok {
+		// ad.Float computes with float64 directly, as fast as non-generic code
+		h := step.Real()
+		yf, _ := precision.AsFloat64(y)
+		wf, _ := precision.AsFloat64(w)
+		k0, _ := precision.AsFloat64(ks[0])
+		if yf == nil {
+			for id := range out {
+				out[id] = h * wf[0] * k0[id]
+			}
+		} else {
+			for id := range out {
+				out[id] = yf[id] + h*wf[0]*k0[id]
+			}
+		}
+		for j := 1; j < len(wf); j++ {
+			kj, _ := precision.AsFloat64(ks[j])
+			for id := range out {
+				out[id] = out[id] + h*wf[j]*kj[id]
+			}
+		}
+		return
+	}
+
+	hw := w[0].Mul(step)
+	for id := range y_out {
+		if y == nil {
+			y_out[id] = hw.Mul(ks[0][id])
+		} else {
+			y_out[id] = y[id].Add(hw.Mul(ks[0][id]))
+		}
+	}
+	for j := 1; j < len(w); j++ {
+		hw = w[j].Mul(step)
+		for id := range y_out {
+			y_out[id] = y_out[id].Add(hw.Mul(ks[j][id]))
+		}
+	}
+}
+
+func float64s(x []ad.Float) []float64 {
+	f, _ := precision.AsFloat64(x)
+	return f
+}
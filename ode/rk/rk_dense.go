@@ -0,0 +1,55 @@
+package rk
+
+// continuous extension of DoPri5 of fourth order by Shampine, in the form used by
+// Hairer, Norsett and Wanner: y(t0 + theta h) = r1 + theta (r2 + (1 - theta) (r3 + theta (r4 + (1 - theta) r5)))
+type dopriInterpolant struct {
+	t0, t1 float64
+	r      [5][]float64
+}
+
+var dopriDense = [7]float64{
+	-12715105075.0 / 11282082432.0,
+	0.0,
+	87487479700.0 / 32700410799.0,
+	-10690763975.0 / 1880347072.0,
+	701980252875.0 / 199316789632.0,
+	-1453857185.0 / 822651844.0,
+	69997945.0 / 29380423.0,
+}
+
+// newDopriInterpolant needs the solution at both ends and the stages of the step,
+// the last stage is the evaluation at the end
+func newDopriInterpolant(t0, step float64, y0, y1, f0 []float64, ks [][]float64) *dopriInterpolant {
+	n := len(y0)
+	d := &dopriInterpolant{t0: t0, t1: t0 + step}
+	for j := range d.r {
+		d.r[j] = make([]float64, n)
+	}
+	for id := 0; id < n; id++ {
+		difference := y1[id] - y0[id]
+		spline := step*f0[id] - difference
+		d.r[0][id] = y0[id]
+		d.r[1][id] = difference
+		d.r[2][id] = spline
+		d.r[3][id] = difference - step*ks[6][id] - spline
+
+		sum := dopriDense[0] * f0[id]
+		for stg := 2; stg < 7; stg++ {
+			sum += dopriDense[stg] * ks[stg][id]
+		}
+		d.r[4][id] = step * sum
+	}
+	return d
+}
+
+func (d *dopriInterpolant) Interval() (t0, t1 float64) {
+	return d.t0, d.t1
+}
+
+func (d *dopriInterpolant) Evaluate(t float64, y_out []float64) {
+	theta := (t - d.t0) / (d.t1 - d.t0)
+	theta1 := 1.0 - theta
+	for id := range y_out {
+		y_out[id] = d.r[0][id] + theta*(d.r[1][id]+theta1*(d.r[2][id]+theta*(d.r[3][id]+theta1*d.r[4][id])))
+	}
+}
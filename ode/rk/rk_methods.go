@@ -1,9 +1,11 @@
 package rk
 
 import (
-	"errors"
+	"github.com/rollingthunder/differential/ad"
 	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/util"
+	"math/big"
 )
 
 const (
@@ -13,141 +15,139 @@ const (
 	NumberOfRKMethods = uint(iota)
 )
 
-func NewRK(m RKMethod) (i ode.Integrator, err error) {
-	var r rk
-	switch m {
-	case RK2:
-		r.Stages, r.Order = 3, 3
-		r.Name = "RK2"
-		makeCoeffs(&r)
-		setCoeffsRK2(&r)
-	case RKFB4:
-		r.Stages, r.Order = 6, 4
-		r.Name = "RKFB4"
-		makeCoeffs(&r)
-		setCoeffsRKFB4(&r)
-	case DoPri5:
-		r.Stages, r.Order = 7, 5
-		r.Name = "DoPri5"
-		r.firstStageAsLast = true
-		makeCoeffs(&r)
-		setCoeffsDoPri5(&r)
-
-	default:
-		err = errors.New("unknown rk method")
-	}
-
-	i = &r
-	return
+// tableau holds the coefficients of a method as exact rationals, so they are
+// available at any precision: the strictly lower triangular matrix a, weights b,
+// nodes c and the weights e of the difference to the embedded solution
+type tableau struct {
+	name             string
+	stages, order    uint
+	firstStageAsLast bool
+	a                [][]string
+	b, c, e          []string
 }
 
-func makeCoeffs(r *rk) {
-	r.b, r.c, r.e = make([]float64, r.Stages), make([]float64, r.Stages), make([]float64, r.Stages)
-	r.a = util.MakeSquare(r.Stages)
+var tableaus = [NumberOfRKMethods]tableau{
+	RK2: {
+		name: "RK2", stages: 3, order: 3,
+		a: [][]string{
+			{},
+			{"1"},
+			{"1/4", "1/4"},
+		},
+		b: []string{"1/6", "1/6", "2/3"},
+		c: []string{"0", "1", "1/2"},
+		e: []string{"-1/3", "-1/3", "2/3"},
+	},
+	RKFB4: {
+		name: "RKFB4", stages: 6, order: 4,
+		a: [][]string{
+			{},
+			{"1/4"},
+			{"3/32", "9/32"},
+			{"1932/2197", "-7200/2197", "7296/2197"},
+			{"439/216", "-8", "3680/513", "-845/4104"},
+			{"-8/27", "2", "-3544/2565", "1859/4104", "-11/40"},
+		},
+		b: []string{"25/216", "0", "1408/2565", "2197/4104", "-1/5", "0"},
+		c: []string{"0", "1/4", "3/8", "12/13", "1", "1/2"},
+		// b minus the weights 16/135, 0, 6656/12825, 28561/56430, -9/50, 2/55 of the fifth order solution
+		e: []string{"-1/360", "0", "128/4275", "2197/75240", "-1/50", "-2/55"},
+	},
+	DoPri5: {
+		name: "DoPri5", stages: 7, order: 5, firstStageAsLast: true,
+		a: [][]string{
+			{},
+			{"1/5"},
+			{"3/40", "9/40"},
+			{"44/45", "-56/15", "32/9"},
+			{"19372/6561", "-25360/2187", "64448/6561", "-212/729"},
+			{"9017/3168", "-355/33", "46732/5247", "49/176", "-5103/18656"},
+			{"35/384", "0", "500/1113", "125/192", "-2187/6784", "11/84"},
+		},
+		b: []string{"35/384", "0", "500/1113", "125/192", "-2187/6784", "11/84", "0"},
+		c: []string{"0", "1/5", "3/10", "4/5", "8/9", "1", "1"},
+		e: []string{"71/57600", "0", "-71/16695", "71/1920", "-17253/339200", "22/525", "-1/40"},
+	},
 }
-func setCoeffsRK2(r *rk) {
-	r.a[1][0] = 1.0
-	r.a[2][0] = 1.0 / 4.0
-	r.a[2][1] = 1.0 / 4.0
 
-	r.c[0] = 0.0
-	r.c[1] = 1.0
-	r.c[2] = 0.5
-	r.b[0] = 1.0 / 6.0
-	r.b[1] = 1.0 / 6.0
-	r.b[2] = 2.0 / 3.0
-	r.e[0] = -1.0 / 3.0
-	r.e[1] = -1.0 / 3.0
-	r.e[2] = 2.0 / 3.0
+// coefficients holds a tableau at the precision of T, the nodes are times and stay float64
+type coefficients[T any] struct {
+	a    [][]T
+	b, e []T
+	c    []float64
 }
-func setCoeffsRKFB4(r *rk) {
-	r.a[1][0] = 1.0 / 4
-	r.a[2][0] = 3.0 / 32.0
-	r.a[2][1] = 9.0 / 32.0
-	r.a[3][0] = 1932.0 / 2197.0
-	r.a[3][1] = -7200.0 / 2197.0
-	r.a[3][2] = 7296.0 / 2197.0
-	r.a[4][0] = 439.0 / 216.0
-	r.a[4][1] = -8.0
-	r.a[4][2] = 3680.0 / 513.0
-	r.a[4][3] = -845.0 / 4104.0
-	r.a[5][0] = -8.0 / 27.0
-	r.a[5][1] = 2.0
-	r.a[5][2] = -3544.0 / 2565.0
-	r.a[5][3] = 1859.0 / 4104.0
-	r.a[5][4] = -11.0 / 40.0
 
-	r.c[0] = 0.0
-	r.c[1] = 1.0 / 4.0
-	r.c[2] = 3.0 / 8
-	r.c[3] = 12.0 / 13.0
-	r.c[4] = 1.0
-	r.c[5] = 1.0 / 2.0
-
-	r.b[0] = 25.0 / 216.0
-	r.b[1] = 0.0
-	r.b[2] = 1408.0 / 2565.0
-	r.b[3] = 2197.0 / 4104.0
-	r.b[4] = -1.0 / 5.0
-	r.b[5] = 0.0
+func rational(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("rk: invalid coefficient " + s)
+	}
+	return r
+}
 
-	r.e[0] = 16.0 / 135.0
-	r.e[1] = 0.0
-	r.e[2] = 6656.0 / 12825.0
-	r.e[3] = 28561.0 / 56430.0
-	r.e[4] = -9.0 / 50.0
-	r.e[5] = 2.0 / 55.0
+// roundTableau converts tab to the precision of prototype,
+// the matrix a is square with zeros on and above the diagonal
+func roundTableau[T precision.Real[T]](tab *tableau, prototype T) (co coefficients[T]) {
+	co.a = make([][]T, tab.stages)
+	co.b, co.e, co.c = make([]T, tab.stages), make([]T, tab.stages), make([]float64, tab.stages)
+	for stg := range co.a {
+		co.a[stg] = make([]T, tab.stages)
+		for j := range co.a[stg] {
+			if j < len(tab.a[stg]) {
+				co.a[stg][j] = prototype.Rat(rational(tab.a[stg][j]))
+			} else {
+				co.a[stg][j] = prototype.Const(0.0)
+			}
+		}
+		co.b[stg] = prototype.Rat(rational(tab.b[stg]))
+		co.e[stg] = prototype.Rat(rational(tab.e[stg]))
+		co.c[stg], _ = rational(tab.c[stg]).Float64()
+	}
+	return
+}
 
-	// for difference of solutions
-	var i uint
-	for i = 0; i < r.Stages; i++ {
-		r.e[i] = r.b[i] - r.e[i]
+// NewGenericRK creates the integrator of method m over the floating point type T
+func NewGenericRK[T precision.Real[T]](m RKMethod) (r *GenericRK[T], err error) {
+	r = &GenericRK[T]{method: m}
+	if uint(m) >= NumberOfRKMethods {
+		err = &ode.ConfigError{Field: "RKMethod", Reason: "unknown rk method"}
+		return
 	}
+
+	r.tableau = &tableaus[m]
+	r.Name, r.Stages, r.Order = r.tableau.name, r.tableau.stages, r.tableau.order
+	r.DenseOutput = true
+	var zero T
+	r.coefficients = roundTableau(r.tableau, zero)
+	return
 }
-func setCoeffsDoPri5(r *rk) {
-	r.a[1][0] = 0.2
-	r.a[2][0] = 3.0 / 40.0
-	r.a[2][1] = 9.0 / 40.0
-	r.a[3][0] = 44.0 / 45.0
-	r.a[3][1] = -56.0 / 15.0
-	r.a[3][2] = 32.0 / 9.0
-	r.a[4][0] = 19372.0 / 6561.0
-	r.a[4][1] = -25360.0 / 2187.0
-	r.a[4][2] = 64448.0 / 6561.0
-	r.a[4][3] = -212.0 / 729.0
-	r.a[5][0] = 9017.0 / 3168.0
-	r.a[5][1] = -355.0 / 33.0
-	r.a[5][2] = 46732.0 / 5247.0
-	r.a[5][3] = 49.0 / 176.0
-	r.a[5][4] = -5103.0 / 18656.0
-	r.a[6][0] = 35.0 / 384.0
-	r.a[6][1] = 0.0
-	r.a[6][2] = 500.0 / 1113.0
-	r.a[6][3] = 125.0 / 192.0
-	r.a[6][4] = -2187.0 / 6784.0
-	r.a[6][5] = 11.0 / 84.0
 
-	r.b[0] = 35.0 / 384.0
-	r.b[1] = 0.0
-	r.b[2] = 500.0 / 1113.0
-	r.b[3] = 125.0 / 192.0
-	r.b[4] = -2187.0 / 6784.0
-	r.b[5] = 11.0 / 84.0
-	r.b[6] = 0.0
+// NewRK creates the integrator of method m for float64 values
+func NewRK(m RKMethod) (i ode.Integrator, err error) {
+	generic, err := NewGenericRK[ad.Float](m)
+	i = &rk{generic: generic}
+	return
+}
 
-	r.c[0] = 0.0
-	r.c[1] = 0.2
-	r.c[2] = 0.3
-	r.c[3] = 0.8
-	r.c[4] = 8.0 / 9.0
-	r.c[5] = 1.0
-	r.c[6] = 1.0
+// Coefficients returns the Butcher tableau of method m:
+// the strictly lower triangular matrix a, weights b, nodes c and error weights e
+// and whether the last stage is the first stage of the next step
+func Coefficients(m RKMethod) (a [][]float64, b, c, e []float64, firstStageAsLast bool, err error) {
+	if uint(m) >= NumberOfRKMethods {
+		err = &ode.ConfigError{Field: "RKMethod", Reason: "unknown rk method"}
+		return
+	}
+	tab := &tableaus[m]
+	co := roundTableau(tab, ad.Float(0))
 
-	r.e[0] = 71.0 / 57600.0
-	r.e[1] = 0.0
-	r.e[2] = -71.0 / 16695.0
-	r.e[3] = 71.0 / 1920.0
-	r.e[4] = -17253.0 / 339200.0
-	r.e[5] = 22.0 / 525.0
-	r.e[6] = -1.0 / 40.0
+	a = util.MakeSquare(tab.stages)
+	for stg := range a {
+		copy(a[stg], precision.ToFloat64(co.a[stg]))
+	}
+	b, c, e = make([]float64, tab.stages), make([]float64, tab.stages), make([]float64, tab.stages)
+	copy(b, precision.ToFloat64(co.b))
+	copy(c, co.c)
+	copy(e, precision.ToFloat64(co.e))
+	firstStageAsLast = tab.firstStageAsLast
+	return
 }
@@ -1,9 +1,14 @@
 package rk
 
 import (
+	"errors"
+	"github.com/rollingthunder/differential/ad"
 	. "github.com/rollingthunder/differential/ode"
 	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/precision"
 	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
 	"testing"
 )
 
@@ -25,6 +30,15 @@ func TestAllRK(t *testing.T) {
 	RunIntegratorTests(t, integrators, 1)
 }
 
+func TestRKAccuracy(t *testing.T) {
+	integrators := make([]Integrator, NumberOfRKMethods)
+	for j := range integrators {
+		integrators[j], _ = NewRK(RKMethod(j))
+	}
+
+	RunAccuracyTests(t, integrators, 1e-10, 1e-7)
+}
+
 func TestRKMBody4h(t *testing.T) {
 	peer, _ := NewRK(DoPri5)
 	mbody := problems.NewMBody(4)
@@ -48,3 +62,225 @@ func TestRKMBody4h(t *testing.T) {
 		t.Logf("MBody: result[0..10] = %f", instance[:10])
 	}
 }
+
+func TestRKErrors(t *testing.T) {
+	dopri, _ := NewRK(DoPri5)
+	mbody := problems.NewMBody(4)
+
+	_, err := dopri.Integrate(0, 1, mbody.Initialize(), &Config{})
+	var configErr *ConfigError
+	if !errors.Is(err, ErrInvalidConfig) || !errors.As(err, &configErr) || configErr.Field != "Fcn" {
+		t.Errorf("Missing function not reported, got %v", err)
+	}
+
+	_, err = dopri.Integrate(0, 1, mbody.Initialize(), &Config{Fcn: mbody.Fcn, MaxStepCount: 2})
+	var stepsErr *MaxStepsError
+	if !errors.Is(err, ErrMaxStepsExceeded) || !errors.As(err, &stepsErr) || stepsErr.StepCount <= 2 {
+		t.Errorf("Exceeded step count not reported, got %v", err)
+	}
+
+	_, err = dopri.Integrate(0, 1, mbody.Initialize(), &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-12, MinStepSize: 0.2, InitialStepSize: 0.5})
+	if !errors.Is(err, ErrStepSizeUnderflow) {
+		t.Errorf("Step size underflow not reported, got %v", err)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	_, err = dopri.Integrate(0, 1, mbody.Initialize(), &Config{Fcn: mbody.Fcn, Cancel: cancel})
+	var cancelErr *CancelledError
+	if !errors.Is(err, ErrCancelled) || !errors.As(err, &cancelErr) || cancelErr.Time != 0 {
+		t.Errorf("Cancellation not reported, got %v", err)
+	}
+
+	if _, err = NewRK(RKMethod(NumberOfRKMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown method not reported, got %v", err)
+	}
+}
+
+// y' = -sqrt(y), y(0) = 1 has the solution y(t) = (1 - t/2)^2,
+// steps that are too large make y negative and the derivative NaN
+func sqrtDecay(t float64, yT []float64, dy_out []float64) {
+	dy_out[0] = -math.Sqrt(yT[0])
+}
+
+func TestRKNonFinite(t *testing.T) {
+	dopri, _ := NewRK(DoPri5)
+
+	y := []float64{1.0}
+	_, err := dopri.Integrate(0, 1.5, y, &Config{Fcn: sqrtDecay, InitialStepSize: 1.5, AbsoluteTolerance: 1e-8})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	if !util.EpsEqual(y[0], 0.0625, 1e-6) {
+		t.Errorf("Expected %f but result was %f", 0.0625, y[0])
+	}
+
+	y = []float64{1.0}
+	_, err = dopri.Integrate(0, 1.5, y, &Config{Fcn: sqrtDecay, InitialStepSize: 1.5, NonFinitePolicy: AbortOnNonFinite})
+	var nonFiniteErr *NonFiniteError
+	if !errors.Is(err, ErrNonFinite) || !errors.As(err, &nonFiniteErr) || nonFiniteErr.Index != 0 || nonFiniteErr.StepSize != 1.5 {
+		t.Errorf("Non-finite stages not reported, got %v", err)
+	}
+}
+
+func TestRKNonFiniteIndex(t *testing.T) {
+	dopri, _ := NewRK(DoPri5)
+	constant := func(t float64, yT []float64, dy_out []float64) {
+		dy_out[0], dy_out[1] = 0.0, 0.0
+	}
+
+	// the error estimate is finite, the non-finite value is in the solution
+	_, err := dopri.Integrate(0, 1, []float64{1.0, math.NaN()}, &Config{Fcn: constant, InitialStepSize: 0.5, NonFinitePolicy: AbortOnNonFinite})
+	var nonFiniteErr *NonFiniteError
+	if !errors.As(err, &nonFiniteErr) || nonFiniteErr.Index != 1 {
+		t.Errorf("Non-finite solution component not reported, got %v", err)
+	}
+
+	// the error quotient overflows with finite values
+	growth := func(t float64, yT []float64, dy_out []float64) {
+		dy_out[0], dy_out[1] = 0.0, 1e300*(1.0+t)
+	}
+	_, err = dopri.Integrate(0, 1, []float64{1.0, 1.0}, &Config{Fcn: growth, InitialStepSize: 1.0, AbsoluteTolerance: 1e-300, RelativeTolerance: 1e-300, NonFinitePolicy: AbortOnNonFinite})
+	if !errors.As(err, &nonFiniteErr) || nonFiniteErr.Index != 1 {
+		t.Errorf("Overflowing error quotient not reported, got %v", err)
+	}
+}
+
+func TestRKConfigReuse(t *testing.T) {
+	dopri, _ := NewRK(DoPri5)
+	mbody := problems.NewMBody(4)
+
+	shared := Config{Fcn: mbody.Fcn}
+	intervals := [][2]float64{{0, 0.1}, {0, 1}}
+
+	for _, interval := range intervals {
+		reused, fresh := mbody.Initialize(), mbody.Initialize()
+
+		reusedStat, err := dopri.Integrate(interval[0], interval[1], reused, &shared)
+		if err != nil {
+			t.Fatalf("Integration failed - %s", err.Error())
+		}
+		freshStat, _ := dopri.Integrate(interval[0], interval[1], fresh, &Config{Fcn: mbody.Fcn})
+
+		if reusedStat.StepCount != freshStat.StepCount || reusedStat.Effective.MaxStepSize != interval[1]-interval[0] {
+			t.Errorf("Reused config behaves differently on [%v, %v]", interval[0], interval[1])
+		}
+		for i := range reused {
+			if reused[i] != fresh[i] {
+				t.Fatalf("Reused config gives different results on [%v, %v]", interval[0], interval[1])
+			}
+		}
+	}
+
+	if shared.MaxStepSize != 0 || shared.FcnBlocked != nil {
+		t.Errorf("Integrator modified the caller's configuration")
+	}
+}
+
+func TestRKDenseOutput(t *testing.T) {
+	// y = (sin t, cos t), the interpolants are checked halfway through each step
+	fcn := func(t float64, yT []float64, dy_out []float64) {
+		dy_out[0], dy_out[1] = yT[1], -yT[0]
+	}
+
+	for _, m := range []RKMethod{DoPri5, RKFB4} {
+		rk, _ := NewRK(m)
+
+		maxError, steps := 0.0, 0
+		config := Config{
+			Fcn:               fcn,
+			AbsoluteTolerance: 1e-8,
+			DenseOutput: func(step Interpolant) {
+				t0, t1 := step.Interval()
+				tMid := (t0 + t1) / 2
+				y := make([]float64, 2)
+				step.Evaluate(tMid, y)
+				maxError = math.Max(maxError, math.Max(math.Abs(y[0]-math.Sin(tMid)), math.Abs(y[1]-math.Cos(tMid))))
+				steps++
+			},
+		}
+
+		y := []float64{0, 1}
+		stat, err := rk.Integrate(0, 10, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", rk.Info().Name, err.Error())
+		}
+		if steps != int(stat.StepCount) {
+			t.Errorf("%s: %d interpolants for %d steps", rk.Info().Name, steps, stat.StepCount)
+		}
+		if maxError > 1e-5 {
+			t.Errorf("%s: interpolation error %g", rk.Info().Name, maxError)
+		}
+	}
+}
+
+// integrateDecay solves y' = -y, y(0) = 1 on [0, 1] with DoPri5 over T
+func integrateDecay[T precision.Real[T]](t *testing.T, one T, tolerance float64) T {
+	dopri, err := NewGenericRK[T](DoPri5)
+	if err != nil {
+		t.Fatalf("Couldn't create generic DoPri5 - %s", err.Error())
+	}
+	y := []T{one}
+	stat, err := dopri.Integrate(0, 1, y, func(t float64, yT []T, dy_out []T) { dy_out[0] = yT[0].Neg() }, &Config{AbsoluteTolerance: tolerance})
+	if err != nil {
+		t.Fatalf("Integration with precision %d failed - %s", one.Precision(), err.Error())
+	}
+	if testing.Verbose() {
+		t.Logf("Decay with precision %d: %d steps, %d rejected", one.Precision(), stat.StepCount, stat.RejectedCount)
+	}
+	return y[0]
+}
+
+func TestGenericRK(t *testing.T) {
+	exact := precision.NewBig(-1.0, 256).Exp()
+	errorOf := func(y precision.Big) float64 { return math.Abs(y.Sub(exact).Real()) }
+
+	if e := errorOf(precision.NewBig(float64(integrateDecay(t, precision.Float32(1.0), 1e-5)), 256)); e > 1e-4 {
+		t.Errorf("Float32: error %g", e)
+	}
+	if e := errorOf(precision.NewBig(float64(integrateDecay(t, ad.Float(1.0), 1e-12)), 256)); e > 1e-11 {
+		t.Errorf("Float: error %g", e)
+	}
+	if e := errorOf(precision.BigFromFloat(integrateDecay(t, precision.NewDoubleDouble(1.0), 1e-24).Big())); e > 1e-22 {
+		t.Errorf("DoubleDouble: error %g", e)
+	}
+	big := integrateDecay(t, precision.NewBig(1.0, 160), 1e-20)
+	if e := errorOf(big); e > 1e-18 || big.Precision() != 160 {
+		t.Errorf("Big: error %g with precision %d", e, big.Precision())
+	}
+}
+
+func TestGenericRKFloat64(t *testing.T) {
+	// the float64 API and the generic integrator over ad.Float with the generic right hand side agree
+	mbody := problems.NewMBody(4)
+	masses := mbody.(problems.ParametricProblem).Parameters()
+	dopri, _ := NewRK(DoPri5)
+	generic, _ := NewGenericRK[ad.Float](DoPri5)
+
+	y := mbody.Initialize()
+	stat, err := dopri.Integrate(0, 1, y, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-8})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	yGeneric := precision.Float64s(mbody.Initialize())
+	genericStat, err := generic.Integrate(0, 1, yGeneric, ad.Fix(problems.MBodyFcn[ad.Float](4), masses), &Config{AbsoluteTolerance: 1e-8})
+	if err != nil {
+		t.Fatalf("Generic integration failed - %s", err.Error())
+	}
+	if stat.StepCount != genericStat.StepCount || stat.EvaluationCount != genericStat.EvaluationCount {
+		t.Errorf("Generic integration took %d steps, %d evaluations instead of %d, %d",
+			genericStat.StepCount, genericStat.EvaluationCount, stat.StepCount, stat.EvaluationCount)
+	}
+	for i := range y {
+		if math.Abs(y[i]-float64(yGeneric[i])) > 1e-10 {
+			t.Fatalf("result[%d] = %g, generic %g", i, y[i], yGeneric[i])
+		}
+	}
+
+	if _, err := NewGenericRK[precision.Float32](RKMethod(NumberOfRKMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Unknown method not reported, got %v", err)
+	}
+	if _, err := generic.Integrate(0, 1, yGeneric, nil, &Config{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Missing function not reported, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package rkc
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type rkc struct {
+	IntegratorInfo
+	method RKCMethod
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	// coefficients for each stage count, computed when first needed
+	coeffs []*coefficients
+
+	// estimate of the spectral radius and the dominant eigenvector
+	spectralRadius float64
+	eigenvector    []float64
+	// accepted steps since the last estimate
+	stepsSinceEstimate int
+	radiusValid        bool
+
+	f0, fNew, yNew, yPrevious, yPrevious2, fStage, yError []float64
+}
+
+// accepted steps after which the spectral radius is estimated again
+const estimateInterval = 25
+
+// performs Runge-Kutta-Chebyshev integration with the number of stages chosen from
+// an estimate of the spectral radius of the Jacobian
+func (r *rkc) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = r.Supports(&effective); err != nil {
+		return
+	}
+
+	in := r.setupIntegration(yT, &effective)
+
+	in.EvaluateBlocked(t, yT, in.f0)
+	in.EvaluationCount = 1
+	r.estimateSpectralRadius(&in, t, yT)
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, yT, in.f0, &in.Config, r.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		if !in.radiusValid {
+			r.estimateSpectralRadius(&in, t, yT)
+		}
+
+		stepNext = math.Min(stepEstimate, maxStableStep(in.spectralRadius))
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		s := stageCount(stepNext, in.spectralRadius)
+		r.step(&in, s, t, stepNext, yT)
+
+		// error estimate 0.8 (y - yNew) + 0.4 h (f(y) + f(yNew))
+		in.EvaluateBlocked(t+stepNext, in.yNew, in.fNew)
+		in.EvaluationCount++
+		var id uint
+		for id = 0; id < in.n; id++ {
+			in.yError[id] = 0.8*(yT[id]-in.yNew[id]) + 0.4*stepNext*(in.f0[id]+in.fNew[id])
+		}
+		errorEstimate := in.ErrorNorm(in.yError, yT, in.yNew)
+
+		if !util.IsFinite(errorEstimate) {
+			if in.NonFinitePolicy == AbortOnNonFinite {
+				index := util.FirstNonFinite(in.yNew)
+				if index < 0 {
+					index = util.FirstNonFinite(in.fNew)
+				}
+				err = &NonFiniteError{Time: t, StepSize: stepNext, Index: index}
+				break
+			}
+			errorEstimate = math.Inf(1)
+		}
+		stepEstimate = stepNext * stepFactor(errorEstimate)
+
+		if errorEstimate > 1.0 {
+			// reject step, the spectral radius may have been underestimated
+			in.RejectedCount++
+			in.radiusValid = false
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.yNew)
+			copy(in.f0, in.fNew)
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			in.stepsSinceEstimate++
+			if in.stepsSinceEstimate >= estimateInterval {
+				in.radiusValid = false
+			}
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (r *rkc) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp matrices
+	i.coeffs = make([]*coefficients, maxStages+1)
+	i.eigenvector = make([]float64, i.n)
+	i.f0 = make([]float64, i.n)
+	i.fNew = make([]float64, i.n)
+	i.yNew = make([]float64, i.n)
+	i.yPrevious = make([]float64, i.n)
+	i.yPrevious2 = make([]float64, i.n)
+	i.fStage = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+func (r *rkc) estimateSpectralRadius(in *integration, t float64, yT []float64) {
+	radius, evaluations := in.SpectralRadius(t, yT, in.f0, in.eigenvector)
+	in.EvaluationCount += evaluations
+	in.spectralRadius = math.Max(radius, 1.0/in.MaxStepSize)
+	in.stepsSinceEstimate = 0
+	in.radiusValid = true
+}
+
+// computes a step of the s stage method into yNew, f0 holds the evaluation at (t, yT)
+func (r *rkc) step(in *integration, s int, t, step float64, yT []float64) {
+	co := in.coeffs[s]
+	if co == nil {
+		c := computeCoefficients(s)
+		co = &c
+		in.coeffs[s] = co
+	}
+
+	// the stages are only needed for the two following ones
+	yPrevious2, yPrevious, yCurrent := in.yPrevious2, in.yPrevious, in.yNew
+
+	var id uint
+	for id = 0; id < in.n; id++ {
+		yPrevious2[id] = yT[id]
+		yPrevious[id] = yT[id] + step*co.muTilde[1]*in.f0[id]
+	}
+
+	for j := 2; j <= s; j++ {
+		in.EvaluateBlocked(t+co.c[j-1]*step, yPrevious, in.fStage)
+		in.EvaluationCount++
+
+		mu, nu, muTilde, gammaTilde := co.mu[j], co.nu[j], co.muTilde[j], co.gammaTilde[j]
+		for id = 0; id < in.n; id++ {
+			yCurrent[id] = (1.0-mu-nu)*yT[id] + mu*yPrevious[id] + nu*yPrevious2[id] +
+				step*(muTilde*in.fStage[id]+gammaTilde*in.f0[id])
+		}
+		yPrevious2, yPrevious, yCurrent = yPrevious, yCurrent, yPrevious2
+	}
+
+	// after the rotation the last stage is yPrevious
+	in.yNew, in.yPrevious, in.yPrevious2 = yPrevious, yCurrent, yPrevious2
+}
+
+// step size factor for the error estimate of the second order method
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.1
+	}
+	factor := 0.8 * math.Pow(1.0e-10+errorEstimate, -1.0/3.0)
+	return math.Max(0.1, math.Min(factor, 10.0)) // safety interval
+}
@@ -0,0 +1,97 @@
+package rkc
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type RKCMethod uint
+
+const (
+	RKC2               = RKCMethod(iota) // second order Runge-Kutta-Chebyshev method of Sommeijer, Shampine and Verwer
+	NumberOfRKCMethods = uint(iota)
+)
+
+const (
+	// maximal number of stages, larger steps are limited by stability
+	maxStages = 250
+	// damping of the stability polynomial, w0 = 1 + damping / s^2
+	damping = 2.0 / 13.0
+)
+
+func NewRKC(m RKCMethod) (i ode.Integrator, err error) {
+	var r rkc
+	r.method = m
+	r.Order = 2
+	// the actual number of stages is chosen in each step
+	r.Stages = maxStages
+
+	switch m {
+	case RKC2:
+		r.Name = "RKC2"
+	default:
+		err = &ode.ConfigError{Field: "RKCMethod", Reason: "unknown rkc method"}
+	}
+
+	i = &r
+	return
+}
+
+// recurrence coefficients of the s stage method
+type coefficients struct {
+	// Y_j = (1 - mu_j - nu_j) y + mu_j Y_j-1 + nu_j Y_j-2 + muTilde_j h F(Y_j-1) + gammaTilde_j h F(y)
+	mu, nu, muTilde, gammaTilde []float64
+	// nodes of the internal stages
+	c []float64
+}
+
+// computes the coefficients of the s stage method from the shifted Chebyshev polynomials T_j(w0)
+func computeCoefficients(s int) (co coefficients) {
+	w0 := 1.0 + damping/float64(s*s)
+
+	// T_j, T_j' and T_j'' at w0
+	t, dt, ddt := make([]float64, s+1), make([]float64, s+1), make([]float64, s+1)
+	t[0], t[1] = 1.0, w0
+	dt[0], dt[1] = 0.0, 1.0
+	ddt[0], ddt[1] = 0.0, 0.0
+	for j := 2; j <= s; j++ {
+		t[j] = 2.0*w0*t[j-1] - t[j-2]
+		dt[j] = 2.0*t[j-1] + 2.0*w0*dt[j-1] - dt[j-2]
+		ddt[j] = 4.0*dt[j-1] + 2.0*w0*ddt[j-1] - ddt[j-2]
+	}
+	w1 := dt[s] / ddt[s]
+
+	b := make([]float64, s+1)
+	for j := 2; j <= s; j++ {
+		b[j] = ddt[j] / (dt[j] * dt[j])
+	}
+	b[0], b[1] = b[2], b[2]
+
+	co.mu, co.nu = make([]float64, s+1), make([]float64, s+1)
+	co.muTilde, co.gammaTilde = make([]float64, s+1), make([]float64, s+1)
+	co.c = make([]float64, s+1)
+
+	co.muTilde[1] = b[1] * w1
+	co.c[1] = co.muTilde[1]
+	for j := 2; j <= s; j++ {
+		co.mu[j] = 2.0 * b[j] * w0 / b[j-1]
+		co.nu[j] = -b[j] / b[j-2]
+		co.muTilde[j] = 2.0 * b[j] * w1 / b[j-1]
+		co.gammaTilde[j] = -(1.0 - b[j-1]*t[j-1]) * co.muTilde[j]
+		// the recurrence integrates y' = 1 exactly
+		co.c[j] = co.mu[j]*co.c[j-1] + co.nu[j]*co.c[j-2] + co.muTilde[j] + co.gammaTilde[j]
+	}
+	return
+}
+
+// number of stages such that h * spectralRadius lies in the stability region
+func stageCount(step, spectralRadius float64) int {
+	s := 1 + int(math.Sqrt(1.0+1.54*step*spectralRadius))
+	return util.Max(2, util.Min(s, maxStages))
+}
+
+// largest step size that is stable with maxStages stages
+func maxStableStep(spectralRadius float64) float64 {
+	return float64((maxStages-1)*(maxStages-1)-1) / (1.54 * spectralRadius)
+}
@@ -0,0 +1,104 @@
+package rkc
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+func TestAllRKC(t *testing.T) {
+	integrators := make([]Integrator, NumberOfRKCMethods)
+	for j := 0; j < int(NumberOfRKCMethods); j++ {
+		r, err := NewRKC(RKCMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create RKC Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = r
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestRKCOrder(t *testing.T) {
+	// y' = -y + sin(t) is solved by 0.5 (sin(t) - cos(t)) + 1.5 exp(-t)
+	fcn := func(t float64, y []float64, dy []float64) {
+		dy[0] = -y[0] + math.Sin(t)
+	}
+	exact := 0.5*(math.Sin(2)-math.Cos(2)) + 1.5*math.Exp(-2)
+
+	var errors [2]float64
+	for i, step := range []float64{0.02, 0.01} {
+		r, _ := NewRKC(RKC2)
+		y := []float64{1}
+		config := Config{
+			Fcn:               fcn,
+			InitialStepSize:   step,
+			MaxStepSize:       step,
+			AbsoluteTolerance: 1,
+		}
+		if _, err := r.Integrate(0, 2, y, &config); err != nil {
+			t.Fatalf("Integration failed - %s", err.Error())
+		}
+		errors[i] = math.Abs(y[0] - exact)
+	}
+
+	if order := math.Log2(errors[0] / errors[1]); order < 1.8 {
+		t.Errorf("Observed order %.2f, expected 2", order)
+	}
+}
+
+func TestSpectralRadius(t *testing.T) {
+	// linear system with eigenvalues -1, -10 and -1000
+	fcn := func(t float64, y []float64, dy []float64) {
+		dy[0] = -y[0]
+		dy[1] = -10 * y[1]
+		dy[2] = -1000 * y[2]
+	}
+	y := []float64{1, 1, 1}
+	f0 := make([]float64, 3)
+	fcn(0, y, f0)
+
+	config, _ := (&Config{Fcn: fcn}).ValidateAndPrepare(3, 0, 1)
+	radius, _ := config.SpectralRadius(0, y, f0, make([]float64, 3))
+	if radius < 1000 || radius > 1300 {
+		t.Errorf("Estimated spectral radius %g, expected a bound close to 1000", radius)
+	}
+}
+
+func TestRKCBrussBlocked(t *testing.T) {
+	// diffusion dominates for large N, the system gets mildly stiff
+	bruss := problems.NewBruss2D(128)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	r, _ := NewRKC(RKC2)
+
+	reference, _ := Reference(t, dopri, bruss, 1)
+
+	yDopri, yRKC := bruss.Initialize(), bruss.Initialize()
+	statDopri, err := dopri.Integrate(0, 1, yDopri, &Config{Fcn: bruss.Fcn, AbsoluteTolerance: 1e-4})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+	config := Config{
+		FcnBlocked:        bruss.FcnBlock,
+		BlockSize:         256,
+		AbsoluteTolerance: 1e-4,
+	}
+	statRKC, err := r.Integrate(0, 1, yRKC, &config)
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+
+	CheckReference(t, r.Info().Name, yRKC, reference, 1e-2)
+	if statRKC.EvaluationCount >= statDopri.EvaluationCount {
+		t.Errorf("RKC2 needed %d evaluations, DoPri5 only %d", statRKC.EvaluationCount, statDopri.EvaluationCount)
+	}
+
+	if testing.Verbose() {
+		t.Logf("Bruss2D RKC2: %d steps, %d rejected, %d evaluations", statRKC.StepCount, statRKC.RejectedCount, statRKC.EvaluationCount)
+		t.Logf("Bruss2D DoPri5: %d steps, %d rejected, %d evaluations", statDopri.StepCount, statDopri.RejectedCount, statDopri.EvaluationCount)
+	}
+}
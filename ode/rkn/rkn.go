@@ -0,0 +1,179 @@
+package rkn
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type rkn struct {
+	IntegratorInfo
+	firstStageAsLast bool
+	// order of the local error estimate for the step size control
+	errorOrder uint
+	// velocity coefficients, a is nil for methods for ddy = f(t, y)
+	b, c, e []float64
+	a       [][]float64
+	// position coefficients
+	bBar, eBar []float64
+	aBar       [][]float64
+}
+
+// performs Runge-Kutta-Nystrom integration of ddy = f(t, y, dy)
+func (r *rkn) IntegrateSecondOrder(t, tEnd float64, yT, dyT []float64, config *SecondOrderConfig) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	if r.aBar == nil {
+		err = &ConfigError{Reason: "RKN Method coefficients not initialized"}
+		return
+	}
+	if len(dyT) != len(yT) {
+		err = &ConfigError{Reason: "positions and velocities differ in size"}
+		return
+	}
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = r.Supports(&effective.Config); err != nil {
+		return
+	}
+	if r.a == nil && !effective.VelocityIndependent {
+		err = &ConfigError{Field: "VelocityIndependent", Reason: r.Name + " requires an acceleration independent of the velocity"}
+		return
+	}
+	stat.Effective = effective.Config
+	c := &effective
+
+	// allocate temp matrices, states are stacked (y, y')
+	state := make([]float64, 2*n)
+	copy(state[:n], yT)
+	copy(state[n:], dyT)
+	stateNew := make([]float64, 2*n)
+	stateError := make([]float64, 2*n)
+	yStage, dyStage := make([]float64, n), make([]float64, n)
+	ks := util.MakeRectangular(r.Stages, n)
+
+	c.Acceleration(t, yT, dyT, ks[0])
+	stat.EvaluationCount = 1
+
+	// compute initial step size if not set
+	stepEstimate := c.InitialStepSize
+	if stepEstimate <= 0.0 {
+		f0 := make([]float64, 2*n)
+		copy(f0[:n], dyT)
+		copy(f0[n:], ks[0])
+		stepEstimate = EstimateStepSize(t, state, f0, &c.Config, r.Order)
+	}
+	var stepNext float64
+	// repeat until tend
+	for t < tEnd {
+		if c.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		// Set new step size
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		stat.StepCount++
+		h, h2 := stepNext, stepNext*stepNext
+
+		// compute stages, methods for ddy = f(t, y) pass the velocity at the beginning of the step
+		var stg, ic, id uint
+		for stg = 1; stg < r.Stages; stg++ {
+			for id = 0; id < n; id++ {
+				yStage[id] = state[id] + r.c[stg]*h*state[n+id]
+				dyStage[id] = state[n+id]
+			}
+			for ic = 0; ic < stg; ic++ {
+				for id = 0; id < n; id++ {
+					yStage[id] += h2 * r.aBar[stg][ic] * ks[ic][id]
+				}
+				if r.a != nil {
+					for id = 0; id < n; id++ {
+						dyStage[id] += h * r.a[stg][ic] * ks[ic][id]
+					}
+				}
+			}
+			c.Acceleration(t+r.c[stg]*h, yStage, dyStage, ks[stg])
+			stat.EvaluationCount++
+		}
+
+		// compute new solution and error estimate
+		for id = 0; id < n; id++ {
+			stateNew[id] = state[id] + h*state[n+id]
+			stateNew[n+id] = state[n+id]
+			stateError[id], stateError[n+id] = 0.0, 0.0
+		}
+		for stg = 0; stg < r.Stages; stg++ {
+			for id = 0; id < n; id++ {
+				stateNew[id] += h2 * r.bBar[stg] * ks[stg][id]
+				stateNew[n+id] += h * r.b[stg] * ks[stg][id]
+				stateError[id] += h2 * r.eBar[stg] * ks[stg][id]
+				stateError[n+id] += h * r.e[stg] * ks[stg][id]
+			}
+		}
+
+		relativeError := c.ErrorNorm(stateError, state, stateNew)
+
+		// new stepsize estimate
+		if util.IsFinite(relativeError) {
+			stepEstimate = 0.9 * math.Exp(-math.Log(1.0e-8+relativeError)/float64(r.errorOrder))
+			stepEstimate = stepNext * math.Max(0.2, math.Min(stepEstimate, 2.0)) // safety interval
+		} else {
+			if c.NonFinitePolicy == AbortOnNonFinite {
+				index := util.FirstNonFinite(stateError)
+				err = &NonFiniteError{Time: t, StepSize: stepNext, Index: index % int(n)}
+				break
+			}
+			relativeError = math.Inf(1)
+			stepEstimate = 0.2 * stepNext
+		}
+		stepEstimate = math.Min(stepEstimate, c.MaxStepSize)
+
+		// reject step
+		if relativeError > 1.0 {
+			stat.RejectedCount++
+
+			// report failure, step size too small
+			if stepEstimate < c.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			state, stateNew = stateNew, state
+
+			// cancel after first step
+			if c.OneStepOnly {
+				break
+			}
+			if r.firstStageAsLast {
+				copy(ks[0], ks[r.Stages-1])
+			} else {
+				c.Acceleration(t, state[:n], state[n:], ks[0])
+				stat.EvaluationCount++
+			}
+		}
+
+		// failure, too many steps
+		if stat.StepCount > c.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: stat.StepCount}
+			break
+		}
+	}
+
+	copy(yT, state[:n])
+	copy(dyT, state[n:])
+
+	stat.CurrentTime = t
+	stat.LastStepSize = stepNext
+	stat.NextStepSize = stepEstimate
+
+	return
+}
@@ -0,0 +1,100 @@
+package rkn
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/util"
+)
+
+type RKNMethod int
+
+const (
+	RKN3               = RKNMethod(iota) // RKN3(2), induced by RK2
+	RKN4                                 // RKN4(5), induced by RKFB4
+	RKN5                                 // RKN5(4), induced by DoPri5
+	RKN6                                 // RKN6(4)6FD of Dormand, El-Mikkawy and Prince for ddy = f(t, y)
+	NumberOfRKNMethods = uint(iota)
+)
+
+// NewRKN creates a Runge-Kutta-Nystrom method. RKN3, RKN4 and RKN5 solve ddy = f(t, y, dy),
+// they are induced by the embedded Runge-Kutta pairs of package rk, i.e. the position
+// coefficients are aBar = a*a, bBar = b*a and eBar = e*a, so the results match those of the
+// rk method applied to the first order system. RKN6 is a genuine Nystrom pair for the special
+// form ddy = f(t, y), which needs fewer stages for its order
+func NewRKN(m RKNMethod) (i ode.SecondOrderIntegrator, err error) {
+	var r rkn
+	var source rk.RKMethod
+	switch m {
+	case RKN6:
+		r.Name, r.Order, r.Stages = "RKN6", 6, 6
+		r.setSpecialCoeffs()
+		i = &r
+		return
+	case RKN3:
+		r.Name, r.Order, source = "RKN3", 3, rk.RK2
+	case RKN4:
+		r.Name, r.Order, source = "RKN4", 4, rk.RKFB4
+	case RKN5:
+		r.Name, r.Order, source = "RKN5", 5, rk.DoPri5
+	default:
+		err = &ode.ConfigError{Field: "RKNMethod", Reason: "unknown rkn method"}
+		i = &r
+		return
+	}
+
+	r.a, r.b, r.c, r.e, r.firstStageAsLast, err = rk.Coefficients(source)
+	if err != nil {
+		i = &r
+		return
+	}
+	r.Stages = uint(len(r.b))
+	r.errorOrder = r.Order
+	r.induceCoeffs()
+
+	i = &r
+	return
+}
+
+func (r *rkn) induceCoeffs() {
+	r.aBar = util.MakeSquare(r.Stages)
+	r.bBar, r.eBar = make([]float64, r.Stages), make([]float64, r.Stages)
+
+	var i, j, k uint
+	for i = 0; i < r.Stages; i++ {
+		for j = 0; j < r.Stages; j++ {
+			for k = 0; k < r.Stages; k++ {
+				r.aBar[i][j] += r.a[i][k] * r.a[k][j]
+			}
+		}
+	}
+	for j = 0; j < r.Stages; j++ {
+		for k = 0; k < r.Stages; k++ {
+			r.bBar[j] += r.b[k] * r.a[k][j]
+			r.eBar[j] += r.e[k] * r.a[k][j]
+		}
+	}
+}
+
+// coefficients of RKN6(4)6FD, the last stage is evaluated at the new position
+func (r *rkn) setSpecialCoeffs() {
+	r.c = []float64{0.0, 1.0 / 10.0, 3.0 / 10.0, 7.0 / 10.0, 17.0 / 25.0, 1.0}
+	r.aBar = [][]float64{
+		{},
+		{1.0 / 200.0},
+		{-1.0 / 2200.0, 1.0 / 22.0},
+		{637.0 / 6600.0, -7.0 / 110.0, 7.0 / 33.0},
+		{225437.0 / 1968750.0, -30073.0 / 281250.0, 65569.0 / 281250.0, -9367.0 / 984375.0},
+		{151.0 / 2142.0, 5.0 / 116.0, 385.0 / 1368.0, 55.0 / 168.0, -6250.0 / 28101.0},
+	}
+	r.bBar = []float64{151.0 / 2142.0, 5.0 / 116.0, 385.0 / 1368.0, 55.0 / 168.0, -6250.0 / 28101.0, 0.0}
+	r.b = []float64{151.0 / 2142.0, 25.0 / 522.0, 275.0 / 684.0, 275.0 / 252.0, -78125.0 / 112404.0, 1.0 / 12.0}
+
+	// differences to the embedded weights of order 4
+	bBarHat := []float64{1349.0 / 157500.0, 7873.0 / 50000.0, 192199.0 / 900000.0, 521683.0 / 2100000.0, -16.0 / 125.0, 0.0}
+	bHat := []float64{1349.0 / 157500.0, 7873.0 / 45000.0, 27457.0 / 90000.0, 521683.0 / 630000.0, -2.0 / 5.0, 1.0 / 12.0}
+	r.eBar, r.e = make([]float64, r.Stages), make([]float64, r.Stages)
+	for j := range r.b {
+		r.eBar[j], r.e[j] = r.bBar[j]-bBarHat[j], r.b[j]-bHat[j]
+	}
+	r.firstStageAsLast, r.errorOrder = true, 5
+}
@@ -0,0 +1,165 @@
+package rkn
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+// ddy = -dy, y(0) = 0, dy(0) = 1 has the solution y(t) = 1 - exp(-t)
+func damped(t float64, yT, dyT []float64, ddy_out []float64) {
+	ddy_out[0] = -dyT[0]
+}
+
+// ddy = -y, y(0) = 0, dy(0) = 1 has the solution y(t) = sin(t)
+func oscillator(t float64, yT, dyT []float64, ddy_out []float64) {
+	ddy_out[0] = -yT[0]
+}
+
+func TestAllRKN(t *testing.T) {
+	for j := 0; j < int(NumberOfRKNMethods); j++ {
+		method, err := NewRKN(RKNMethod(j))
+		if err != nil {
+			t.Fatalf("Couldn't create RKN Method %d: %s", j, err.Error())
+		}
+
+		y, dy := []float64{0.0}, []float64{1.0}
+		config := SecondOrderConfig{
+			Config:       Config{AbsoluteTolerance: 1e-8},
+			Acceleration: damped,
+		}
+
+		stat, err := method.IntegrateSecondOrder(0, 3, y, dy, &config)
+
+		if RKNMethod(j) == RKN6 {
+			if _, ok := err.(*ConfigError); !ok {
+				t.Errorf("%s: Expected a ConfigError for an acceleration depending on the velocity, got %v", method.Info().Name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", method.Info().Name, err.Error())
+		}
+		if !util.EpsEqual(y[0], 1.0-math.Exp(-3), 1e-6) || !util.EpsEqual(dy[0], math.Exp(-3), 1e-6) {
+			t.Errorf("%s: Expected (%f, %f) but result was (%f, %f)", method.Info().Name, 1.0-math.Exp(-3), math.Exp(-3), y[0], dy[0])
+		}
+		if stat.CurrentTime != 3 {
+			t.Errorf("%s: Tried to integrate up to %f but only reached %f", method.Info().Name, 3.0, stat.CurrentTime)
+		}
+	}
+}
+
+func TestRKNOscillator(t *testing.T) {
+	for j := 0; j < int(NumberOfRKNMethods); j++ {
+		method, _ := NewRKN(RKNMethod(j))
+
+		y, dy := []float64{0.0}, []float64{1.0}
+		config := SecondOrderConfig{
+			Config:              Config{AbsoluteTolerance: 1e-10, RelativeTolerance: 1e-10},
+			Acceleration:        oscillator,
+			VelocityIndependent: true,
+		}
+
+		if _, err := method.IntegrateSecondOrder(0, 10, y, dy, &config); err != nil {
+			t.Fatalf("%s: Integration failed - %s", method.Info().Name, err.Error())
+		}
+		if !util.EpsEqual(y[0], math.Sin(10), 1e-7) || !util.EpsEqual(dy[0], math.Cos(10), 1e-7) {
+			t.Errorf("%s: Expected (%f, %f) but result was (%f, %f)", method.Info().Name, math.Sin(10), math.Cos(10), y[0], dy[0])
+		}
+	}
+}
+
+// the Nystrom pair needs fewer evaluations than DoPri5 for the same accuracy
+func TestRKN6MBody(t *testing.T) {
+	ref, err := problems.LoadReference("mbody4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ref.Float64()
+	mbody := problems.NewMBody(ref.Size).(problems.SecondOrderProblem)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	rkn, _ := NewRKN(RKN6)
+
+	maxError := func(y, dy []float64) (e float64) {
+		for i := 0; i < len(y)/3; i++ {
+			for k := 0; k < 3; k++ {
+				e = math.Max(e, math.Max(math.Abs(y[3*i+k]-expected[6*i+k]), math.Abs(dy[3*i+k]-expected[6*i+3+k])))
+			}
+		}
+		return
+	}
+
+	for _, tolerance := range []float64{1e-6, 1e-9, 1e-12} {
+		config := SecondOrderConfig{
+			Config:              Config{AbsoluteTolerance: tolerance, RelativeTolerance: tolerance},
+			Acceleration:        mbody.SecondOrderFcn,
+			VelocityIndependent: true,
+		}
+
+		y, dy := mbody.InitializeSplit()
+		rknStat, err := rkn.IntegrateSecondOrder(ref.T0, ref.T1, y, dy, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", rkn.Info().Name, err.Error())
+		}
+		rknError := maxError(y, dy)
+
+		y, dy = mbody.InitializeSplit()
+		dopriStat, err := SecondOrderAdapter{Integrator: dopri}.IntegrateSecondOrder(ref.T0, ref.T1, y, dy, &config)
+		if err != nil {
+			t.Fatalf("DoPri5: Integration failed - %s", err.Error())
+		}
+		dopriError := maxError(y, dy)
+
+		t.Logf("Tolerance %g: RKN6 error %g with %d evaluations, DoPri5 error %g with %d evaluations",
+			tolerance, rknError, rknStat.EvaluationCount, dopriError, dopriStat.EvaluationCount)
+		if rknError > 10.0*tolerance {
+			t.Errorf("Tolerance %g: RKN6 error %g", tolerance, rknError)
+		}
+		if rknStat.EvaluationCount >= dopriStat.EvaluationCount || rknError > dopriError {
+			t.Errorf("Tolerance %g: RKN6 is not more efficient than DoPri5", tolerance)
+		}
+	}
+}
+
+func TestRKNMBody4h(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	rkn, _ := NewRKN(RKN5)
+	mbody := problems.NewMBody(4).(problems.SecondOrderProblem)
+
+	// first order form
+	reference := mbody.Initialize()
+	referenceStat, err := dopri.Integrate(0, 1, reference, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-8})
+	if err != nil {
+		t.Fatalf("Reference integration failed - %s", err.Error())
+	}
+
+	config := SecondOrderConfig{
+		Config:       Config{AbsoluteTolerance: 1e-8},
+		Acceleration: mbody.SecondOrderFcn,
+	}
+
+	// RKN and the adapted first order method take the acceleration directly
+	variants := []SecondOrderIntegrator{rkn, SecondOrderAdapter{Integrator: dopri}}
+	for _, variant := range variants {
+		y, dy := mbody.InitializeSplit()
+
+		stat, err := variant.IntegrateSecondOrder(0, 1, y, dy, &config)
+
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", variant.Info().Name, err.Error())
+		}
+		if stat.StepCount != referenceStat.StepCount {
+			t.Errorf("%s: took %d steps, the first order form %d", variant.Info().Name, stat.StepCount, referenceStat.StepCount)
+		}
+		for i := 0; i < len(y)/3; i++ {
+			for k := 0; k < 3; k++ {
+				if !util.EpsEqual(y[3*i+k], reference[6*i+k], 1e-12) || !util.EpsEqual(dy[3*i+k], reference[6*i+3+k], 1e-12) {
+					t.Fatalf("%s: result differs from the first order form for body %d", variant.Info().Name, i)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package rosenbrock
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/internal/linear"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type rosenbrock struct {
+	IntegratorInfo
+	method RosenbrockMethod
+	gamma  float64
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	// Jacobian at the beginning of the step and LU decomposition of I - gamma h J
+	system *linear.System
+	// finite difference approximation of the time derivative of the right hand side
+	fTime []float64
+
+	f0, fStage, yStage, k1, k2, yError []float64
+}
+
+// performs linearly implicit Rosenbrock integration, suited for stiff problems
+func (r *rosenbrock) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = r.Supports(&effective); err != nil {
+		return
+	}
+
+	in := r.setupIntegration(yT, &effective)
+
+	in.EvaluateBlocked(t, yT, in.f0)
+	in.EvaluationCount = 1
+	jacobianValid := false
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, yT, in.f0, &in.Config, r.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		// the Jacobian is kept for retries of rejected steps
+		if !jacobianValid {
+			r.computeJacobian(&in, t, yT)
+			jacobianValid = true
+		}
+
+		errorEstimate := math.Inf(1)
+		if in.system.Factorize(r.gamma*stepNext) == nil {
+			r.step(&in, t, stepNext, yT)
+			errorEstimate = in.ErrorNorm(in.yError, yT, in.yStage)
+		}
+
+		if !util.IsFinite(errorEstimate) {
+			if in.NonFinitePolicy == AbortOnNonFinite {
+				err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.yStage)}
+				break
+			}
+			errorEstimate = math.Inf(1)
+		}
+		stepEstimate = stepNext * stepFactor(errorEstimate)
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.yStage)
+			in.EvaluateBlocked(t, yT, in.f0)
+			in.EvaluationCount++
+			jacobianValid = false
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (r *rosenbrock) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp matrices
+	i.system = linear.NewSystem(c, i.n)
+	i.fTime = make([]float64, i.n)
+	i.f0 = make([]float64, i.n)
+	i.fStage = make([]float64, i.n)
+	i.yStage = make([]float64, i.n)
+	i.k1 = make([]float64, i.n)
+	i.k2 = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+// computes the Jacobian (see Config.EvaluateJacobian) and approximates the time derivative
+// at (t, yT) with forward differences
+func (r *rosenbrock) computeJacobian(in *integration, t float64, yT []float64) {
+	var id uint
+	deltaTime := math.Sqrt(1e-16 * math.Max(1e-5, math.Abs(t)))
+	in.EvaluateBlocked(t+deltaTime, yT, in.fStage)
+	for id = 0; id < in.n; id++ {
+		in.fTime[id] = (in.fStage[id] - in.f0[id]) / deltaTime
+	}
+	in.EvaluationCount += 1 + in.system.ComputeJacobian(&in.Config, t, yT, in.f0)
+}
+
+// computes one step of ROS2 into yStage and its error estimate into yError
+//
+//	(I - gamma h J) k1 = f(t, y) + gamma h f_t
+//	(I - gamma h J) k2 = f(t + h, y + h k1) - 2 k1 - gamma h f_t
+//	y_new = y + 3/2 h k1 + 1/2 h k2
+//
+// the error is estimated against the first order solution y + h k1
+func (r *rosenbrock) step(in *integration, t, step float64, yT []float64) {
+	var id uint
+	for id = 0; id < in.n; id++ {
+		in.k1[id] = in.f0[id] + r.gamma*step*in.fTime[id]
+	}
+	in.system.Solve(in.k1)
+
+	for id = 0; id < in.n; id++ {
+		in.yStage[id] = yT[id] + step*in.k1[id]
+	}
+	in.EvaluateBlocked(t+step, in.yStage, in.fStage)
+	in.EvaluationCount++
+
+	for id = 0; id < in.n; id++ {
+		in.k2[id] = in.fStage[id] - 2.0*in.k1[id] - r.gamma*step*in.fTime[id]
+	}
+	in.system.Solve(in.k2)
+
+	for id = 0; id < in.n; id++ {
+		in.yStage[id] = yT[id] + step*(1.5*in.k1[id]+0.5*in.k2[id])
+		in.yError[id] = 0.5 * step * (in.k1[id] + in.k2[id])
+	}
+}
+
+// step size factor for the error estimate of the embedded first order solution
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -0.5)
+	return math.Max(0.2, math.Min(factor, 5.0)) // safety interval
+}
@@ -0,0 +1,31 @@
+package rosenbrock
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+type RosenbrockMethod uint
+
+const (
+	ROS2                      = RosenbrockMethod(iota) // L-stable two stage W-method of Verwer et al., order 2(1)
+	NumberOfRosenbrockMethods = uint(iota)
+)
+
+func NewRosenbrock(m RosenbrockMethod) (i ode.Integrator, err error) {
+	var r rosenbrock
+	r.method = m
+
+	switch m {
+	case ROS2:
+		r.Name = "ROS2"
+		r.Stages = 2
+		r.Order = 2
+		r.gamma = 1.0 + 1.0/math.Sqrt2
+	default:
+		err = &ode.ConfigError{Field: "RosenbrockMethod", Reason: "unknown rosenbrock method"}
+	}
+
+	i = &r
+	return
+}
@@ -0,0 +1,87 @@
+package rosenbrock
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"testing"
+)
+
+func TestAllRosenbrock(t *testing.T) {
+	integrators := make([]Integrator, NumberOfRosenbrockMethods)
+	for j := 0; j < int(NumberOfRosenbrockMethods); j++ {
+		r, err := NewRosenbrock(RosenbrockMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create Rosenbrock Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = r
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestRosenbrockStiff(t *testing.T) {
+	for j := 0; j < int(NumberOfRosenbrockMethods); j++ {
+		r, _ := NewRosenbrock(RosenbrockMethod(j))
+		y := []float64{1, 0, 0}
+		config := Config{
+			Fcn:               Robertson,
+			AbsoluteTolerance: 1e-8,
+			RelativeTolerance: 1e-4,
+		}
+
+		stat, err := r.Integrate(0, 40, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", r.Info().Name, err.Error())
+		}
+
+		CheckRelative(t, r.Info().Name, y, RobertsonReference, 1e-3)
+		CheckStability(t, r.Info().Name, stat, 1000)
+
+		if testing.Verbose() {
+			t.Logf("Robertson %s: %d steps, %d rejected, %d evaluations", r.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestRosenbrockBruss(t *testing.T) {
+	bruss := problems.NewBruss2D(4)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+
+	reference, _ := Reference(t, dopri, bruss, 1)
+
+	for j := 0; j < int(NumberOfRosenbrockMethods); j++ {
+		r, _ := NewRosenbrock(RosenbrockMethod(j))
+
+		// the dense analytic jacobian, used on the pattern when Sparsity is set
+		denseCalls := 0
+		dense := func(t float64, yT []float64, jac_out [][]float64) {
+			denseCalls++
+			sparse := NewSparseMatrix(bruss.Sparsity())
+			bruss.Jacobian(t, yT, sparse)
+			sparse.ToDense(jac_out)
+		}
+
+		// finite differences, colored finite differences and the analytic jacobians
+		configs := []Config{
+			{FcnBlocked: bruss.FcnBlock, BlockSize: 8},
+			{FcnBlocked: bruss.FcnBlock, BlockSize: 8, Sparsity: bruss.Sparsity()},
+			{FcnBlocked: bruss.FcnBlock, BlockSize: 8, Sparsity: bruss.Sparsity(), SparseJacobian: bruss.Jacobian},
+			{FcnBlocked: bruss.FcnBlock, BlockSize: 8, Sparsity: bruss.Sparsity(), Jacobian: dense},
+		}
+		for _, config := range configs {
+			y := bruss.Initialize()
+			config.AbsoluteTolerance = 1e-6
+			_, err := r.Integrate(0, 1, y, &config)
+			if err != nil {
+				t.Fatalf("%s: Integration failed - %s", r.Info().Name, err.Error())
+			}
+			CheckReference(t, r.Info().Name, y, reference, 1e-3)
+		}
+		if denseCalls == 0 {
+			t.Errorf("%s: the dense jacobian was ignored in favour of the sparsity pattern", r.Info().Name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ode
+
+// DiffusionFunction evaluates the noise coefficients g(t, yT) of the stochastic
+// differential equation dy = Fcn(t, y) dt + g(t, y) dW
+type DiffusionFunction func(t float64, yT []float64, g_out []float64)
+
+// NoiseType determines the layout of the diffusion coefficients
+type NoiseType int
+
+const (
+	// DiagonalNoise drives component i by the i-th of n Wiener processes, g has n entries.
+	// Component i of g may only depend on component i of y
+	DiagonalNoise NoiseType = iota
+	// GeneralNoise drives the system by NoiseDimension Wiener processes,
+	// g is the n x NoiseDimension matrix in row major order.
+	// Methods of strong order 1 assume commutative noise
+	GeneralNoise
+)
+
+// Calculus determines the interpretation of the stochastic integral
+type Calculus int
+
+const (
+	Ito Calculus = iota
+	Stratonovich
+)
+
+// SDEConfig configures the integration of a stochastic differential equation,
+// the Fcn of the embedded Config is the drift, its Jacobian settings are ignored
+type SDEConfig struct {
+	Config
+
+	Diffusion      DiffusionFunction
+	Noise          NoiseType
+	NoiseDimension uint
+	Calculus       Calculus
+
+	// Seed initializes the random number generator,
+	// equal seeds and configurations give equal paths
+	Seed int64
+
+	// Path, if set, is called with the increments dW of the Wiener processes
+	// over [t, t + h] of every accepted step
+	Path func(t, h float64, dW []float64)
+}
+
+// SDEIntegrator integrates stochastic differential equations along one sample path
+type SDEIntegrator interface {
+	Info() IntegratorInfo
+	IntegrateSDE(t, tEnd float64, yT []float64, config *SDEConfig) (stat Statistics, err error)
+}
+
+// WienerDimension returns the number of Wiener processes driving a system with n components
+func (c *SDEConfig) WienerDimension(n uint) uint {
+	if c.Noise == DiagonalNoise {
+		return n
+	}
+	return c.NoiseDimension
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components
+// and returns the effective configuration
+func (c *SDEConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e SDEConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Diffusion == nil {
+		err = &ConfigError{Field: "Diffusion", Reason: "no diffusion function specified"}
+		return
+	}
+	switch c.Noise {
+	case DiagonalNoise:
+	case GeneralNoise:
+		if c.NoiseDimension == 0 {
+			err = &ConfigError{Field: "NoiseDimension", Reason: "number of Wiener processes not specified"}
+			return
+		}
+	default:
+		err = &ConfigError{Field: "Noise", Reason: "unknown noise type"}
+		return
+	}
+	if c.Calculus != Ito && c.Calculus != Stratonovich {
+		err = &ConfigError{Field: "Calculus", Reason: "unknown calculus"}
+		return
+	}
+
+	e = *c
+	e.Config, err = c.Config.ValidateAndPrepare(n, t, tEnd)
+	return
+}
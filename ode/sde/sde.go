@@ -0,0 +1,267 @@
+package sde
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type sde struct {
+	IntegratorInfo
+	method   SDEMethod
+	adaptive bool
+}
+
+type integration struct {
+	SDEConfig
+	Statistics
+	// number of components and of Wiener processes
+	n, m  int
+	noise *wiener
+
+	dW []float64
+	// drift and diffusion at the beginning of the step and at the support values
+	f, fNew, g, gSupport  []float64
+	support, yNew, yError []float64
+	// Milstein correction, the difference to Euler-Maruyama
+	correction []float64
+}
+
+// integrates one sample path of the SDE, the fixed step methods cannot reject steps,
+// so they stop with a NonFiniteError regardless of the NonFinitePolicy
+func (s *sde) IntegrateSDE(t, tEnd float64, yT []float64, config *SDEConfig) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective.Config); err != nil {
+		return
+	}
+	if !s.adaptive && effective.InitialStepSize <= 0.0 {
+		err = &ConfigError{Field: "InitialStepSize", Reason: "fixed step methods need a step size"}
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		// estimate from the drift only, the step size control takes care of the noise
+		in.Fcn(t, yT, in.f)
+		stepEstimate = EstimateStepSize(t, yT, in.f, &in.Config, 1)
+		in.EvaluationCount++
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd || tEnd-(t+stepNext) < 1e-10*stepNext {
+			stepNext = tEnd - t
+		}
+		stepNext = in.noise.limit(stepNext)
+		in.StepCount++
+
+		in.noise.next(stepNext, in.dW)
+		s.step(&in, t, stepNext, yT)
+
+		errorEstimate := 0.0
+		if !s.adaptive {
+			if index := util.FirstNonFinite(in.yNew); index >= 0 {
+				err = &NonFiniteError{Time: t, StepSize: stepNext, Index: index}
+				break
+			}
+		} else {
+			errorEstimate = in.ErrorNorm(in.yError, yT, in.yNew)
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.yNew)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			stepEstimate = stepNext * stepFactor(errorEstimate)
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step, the increments are reused for the smaller steps
+			in.RejectedCount++
+			in.noise.reject(stepNext, in.dW)
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			if in.Path != nil {
+				in.Path(t, stepNext, in.dW)
+			}
+			t += stepNext
+			copy(yT, in.yNew)
+			if s.adaptive {
+				stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+			}
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *sde) setupIntegration(yT []float64, config *SDEConfig) (in integration) {
+	in.SDEConfig = *config
+	in.n, in.m = len(yT), int(config.WienerDimension(uint(len(yT))))
+	in.noise = newWiener(config.Seed)
+
+	gSize := in.n
+	if in.Noise == GeneralNoise {
+		gSize = in.n * in.m
+	}
+	in.dW = make([]float64, in.m)
+	in.f, in.fNew = make([]float64, in.n), make([]float64, in.n)
+	in.g, in.gSupport = make([]float64, gSize), make([]float64, gSize)
+	in.support, in.yNew, in.yError = make([]float64, in.n), make([]float64, in.n), make([]float64, in.n)
+	in.correction = make([]float64, in.n)
+	return
+}
+
+// step computes yNew over [t, t + h] with the increments dW,
+// and for the adaptive method the local error estimate yError
+func (s *sde) step(in *integration, t, h float64, yT []float64) {
+	in.Fcn(t, yT, in.f)
+	in.Diffusion(t, yT, in.g)
+	in.EvaluationCount += 2
+
+	// Euler-Maruyama step
+	for i := range yT {
+		in.yNew[i] = yT[i] + h*in.f[i]
+	}
+	in.addNoise(in.g, in.yNew)
+
+	switch {
+	case s.method == EulerMaruyama && in.Calculus == Stratonovich:
+		// Euler-Heun: average the diffusion over the predicted step
+		in.Diffusion(t+h, in.yNew, in.gSupport)
+		in.EvaluationCount++
+		for i := range in.g {
+			in.gSupport[i] = 0.5 * (in.gSupport[i] - in.g[i])
+		}
+		in.addNoise(in.gSupport, in.yNew)
+	case s.method == Milstein || s.method == MilsteinHeun:
+		in.milsteinCorrection(t, h, yT)
+		for i := range yT {
+			in.yNew[i] += in.correction[i]
+		}
+	}
+
+	if s.method == MilsteinHeun {
+		// replace the Euler drift by the trapezoidal rule. There is no embedded method,
+		// the error estimate is the heuristic difference to Euler-Maruyama: the drift
+		// correction plus the Milstein correction, whose size grows with the state
+		// dependence of the diffusion. It is no asymptotically correct estimate of the
+		// strong local error, it merely keeps the steps small where either term is large
+		in.Fcn(t+h, in.yNew, in.fNew)
+		in.EvaluationCount++
+		for i := range yT {
+			driftCorrection := 0.5 * h * (in.fNew[i] - in.f[i])
+			in.yNew[i] += driftCorrection
+			in.yError[i] = math.Abs(driftCorrection) + math.Abs(in.correction[i])
+		}
+	}
+}
+
+// addNoise adds g dW to y_out
+func (in *integration) addNoise(g, y_out []float64) {
+	if in.Noise == DiagonalNoise {
+		for i := range y_out {
+			y_out[i] += g[i] * in.dW[i]
+		}
+		return
+	}
+	for i := range y_out {
+		row := g[i*in.m : (i+1)*in.m]
+		for j, dWj := range in.dW {
+			y_out[i] += row[j] * dWj
+		}
+	}
+}
+
+// milsteinCorrection computes the double integral terms
+// 1/2 sum_j,k (L^j g_k) (dW_j dW_k - delta_jk h), without the h for Stratonovich SDEs.
+// The derivatives L^j g_k are approximated by differences of g at the support values
+// y + g_j sqrt(h), as in the derivative free schemes of Kloeden and Platen.
+// Without the drift in the support values the approximation error has zero mean
+// for Stratonovich SDEs, too
+func (in *integration) milsteinCorrection(t, h float64, yT []float64) {
+	sqrtH := math.Sqrt(h)
+	ito := 0.0
+	if in.Calculus == Ito {
+		ito = h
+	}
+
+	if in.Noise == DiagonalNoise {
+		// component i depends on the i-th process only, one support value suffices
+		for i := range yT {
+			in.support[i] = yT[i] + sqrtH*in.g[i]
+		}
+		in.Diffusion(t, in.support, in.gSupport)
+		in.EvaluationCount++
+		for i := range yT {
+			in.correction[i] = 0.5 * (in.gSupport[i] - in.g[i]) / sqrtH * (in.dW[i]*in.dW[i] - ito)
+		}
+		return
+	}
+
+	for i := range in.correction {
+		in.correction[i] = 0.0
+	}
+	for j := 0; j < in.m; j++ {
+		for i := range yT {
+			in.support[i] = yT[i] + sqrtH*in.g[i*in.m+j]
+		}
+		in.Diffusion(t, in.support, in.gSupport)
+		in.EvaluationCount++
+		for i := range yT {
+			for k := 0; k < in.m; k++ {
+				product := in.dW[j] * in.dW[k]
+				if j == k {
+					product -= ito
+				}
+				in.correction[i] += 0.5 * (in.gSupport[i*in.m+k] - in.g[i*in.m+k]) / sqrtH * product
+			}
+		}
+	}
+}
+
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -0.5)
+	return math.Max(0.2, math.Min(factor, 2.0)) // safety interval
+}
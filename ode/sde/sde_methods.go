@@ -0,0 +1,41 @@
+package sde
+
+import "github.com/rollingthunder/differential/ode"
+
+type SDEMethod uint
+
+const (
+	EulerMaruyama      = SDEMethod(iota) // strong order 1/2, Euler-Heun for Stratonovich SDEs, fixed steps
+	Milstein                             // derivative free Milstein scheme, strong order 1, fixed steps
+	MilsteinHeun                         // Milstein scheme with Heun drift, strong order 1, adaptive steps with a heuristic error estimate, see NewSDE
+	NumberOfSDEMethods = uint(iota)
+)
+
+// NewSDE creates an integrator for stochastic differential equations,
+// Order is the strong order rounded down.
+// The fixed step methods take steps of Config.InitialStepSize.
+// MilsteinHeun is no stochastic Runge-Kutta method: it has no embedded pair,
+// its step size control uses the size of the drift and Milstein corrections,
+// which is not an asymptotically correct estimate of the local error
+func NewSDE(m SDEMethod) (i ode.SDEIntegrator, err error) {
+	var s sde
+	s.method = m
+
+	switch m {
+	case EulerMaruyama:
+		s.Name = "EulerMaruyama"
+		s.Stages, s.Order = 1, 0
+	case Milstein:
+		s.Name = "Milstein"
+		s.Stages, s.Order = 2, 1
+	case MilsteinHeun:
+		s.Name = "MilsteinHeun"
+		s.Stages, s.Order = 3, 1
+		s.adaptive = true
+	default:
+		err = &ode.ConfigError{Field: "SDEMethod", Reason: "unknown sde method"}
+	}
+
+	i = &s
+	return
+}
@@ -0,0 +1,237 @@
+package sde
+
+import (
+	"errors"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+const (
+	mu, sigma = 0.5, 0.8
+	samples   = 64
+)
+
+// geometric Brownian motion dX = mu X dt + sigma X dW, X(0) = 1
+func gbmConfig(calculus Calculus, step float64, seed int64, w *float64) *SDEConfig {
+	return &SDEConfig{
+		Config: Config{
+			Fcn:               func(t float64, y []float64, dy []float64) { dy[0] = mu * y[0] },
+			InitialStepSize:   step,
+			AbsoluteTolerance: 1e-3,
+		},
+		Diffusion: func(t float64, y []float64, g []float64) { g[0] = sigma * y[0] },
+		Calculus:  calculus,
+		Seed:      seed,
+		Path:      func(t, h float64, dW []float64) { *w += dW[0] },
+	}
+}
+
+func gbmExact(calculus Calculus, t, w float64) float64 {
+	if calculus == Ito {
+		return math.Exp((mu-0.5*sigma*sigma)*t + sigma*w)
+	}
+	return math.Exp(mu*t + sigma*w)
+}
+
+// meanError returns the mean absolute error at t = 1 over the sample paths
+func meanError(t *testing.T, s SDEIntegrator, calculus Calculus, step float64) float64 {
+	sum := 0.0
+	for seed := int64(0); seed < samples; seed++ {
+		w := 0.0
+		y := []float64{1.0}
+		if _, err := s.IntegrateSDE(0, 1, y, gbmConfig(calculus, step, seed, &w)); err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		sum += math.Abs(y[0] - gbmExact(calculus, 1, w))
+	}
+	return sum / samples
+}
+
+func TestSDEConstructor(t *testing.T) {
+	for j := 0; j < int(NumberOfSDEMethods); j++ {
+		if _, err := NewSDE(SDEMethod(j)); err != nil {
+			t.Errorf("Couldn't create SDE method %d: %s", j, err.Error())
+		}
+	}
+	if _, err := NewSDE(SDEMethod(NumberOfSDEMethods)); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error for an unknown method, got %v", err)
+	}
+}
+
+func TestStrongOrder(t *testing.T) {
+	// the error decreases by 64^order when the step size is divided by 64
+	for _, test := range []struct {
+		method SDEMethod
+		ratio  float64
+	}{{EulerMaruyama, 4.0}, {Milstein, 30.0}} {
+		s, _ := NewSDE(test.method)
+		for _, calculus := range []Calculus{Ito, Stratonovich} {
+			coarse, fine := meanError(t, s, calculus, 1.0/16), meanError(t, s, calculus, 1.0/1024)
+			if testing.Verbose() {
+				t.Logf("%s (calculus %d): errors %g, %g", s.Info().Name, calculus, coarse, fine)
+			}
+			if coarse/fine < test.ratio {
+				t.Errorf("%s (calculus %d): error decreased from %g to %g only", s.Info().Name, calculus, coarse, fine)
+			}
+		}
+	}
+}
+
+func TestAdaptive(t *testing.T) {
+	s, _ := NewSDE(MilsteinHeun)
+	for _, calculus := range []Calculus{Ito, Stratonovich} {
+		errors := make([]float64, 2)
+		var rejected uint
+		for k, tolerance := range []float64{1e-2, 1e-4} {
+			for seed := int64(0); seed < samples; seed++ {
+				w := 0.0
+				y := []float64{1.0}
+				config := gbmConfig(calculus, 0, seed, &w)
+				config.AbsoluteTolerance = tolerance
+				stat, err := s.IntegrateSDE(0, 1, y, config)
+				if err != nil {
+					t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+				}
+				errors[k] += math.Abs(y[0]-gbmExact(calculus, 1, w)) / samples
+				rejected += stat.RejectedCount
+			}
+		}
+		if testing.Verbose() {
+			t.Logf("%s (calculus %d): errors %v, %d rejected", s.Info().Name, calculus, errors, rejected)
+		}
+		if errors[1] > 0.05*errors[0] {
+			t.Errorf("%s (calculus %d): errors %v do not follow the tolerance", s.Info().Name, calculus, errors)
+		}
+		if rejected == 0 {
+			t.Errorf("%s (calculus %d): no steps rejected", s.Info().Name, calculus)
+		}
+	}
+
+	// the Brownian bridges keep the distribution of W(1) intact despite the rejections
+	const paths = 1000
+	wSquared := 0.0
+	for seed := int64(0); seed < paths; seed++ {
+		w := 0.0
+		if _, err := s.IntegrateSDE(0, 1, []float64{1.0}, gbmConfig(Ito, 0, seed, &w)); err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		wSquared += w * w / paths
+	}
+	if math.Abs(wSquared-1.0) > 0.1 {
+		t.Errorf("%s: variance of W(1) is %g", s.Info().Name, wSquared)
+	}
+}
+
+func TestGeneralNoise(t *testing.T) {
+	// dX_i = a_i X_i dt + sum_j b_ij X_i dW_j, the noise is commutative
+	a := []float64{-1.0, 0.5}
+	b := [][]float64{{0.5, -0.3}, {0.2, 0.4}}
+
+	for _, m := range []SDEMethod{Milstein, MilsteinHeun} {
+		s, _ := NewSDE(m)
+		meanError := 0.0
+		for seed := int64(0); seed < samples; seed++ {
+			w := make([]float64, 2)
+			config := SDEConfig{
+				Config: Config{
+					Fcn: func(t float64, y []float64, dy []float64) {
+						dy[0], dy[1] = a[0]*y[0], a[1]*y[1]
+					},
+					InitialStepSize:   1.0 / 256,
+					AbsoluteTolerance: 1e-4,
+				},
+				Diffusion: func(t float64, y []float64, g []float64) {
+					for i := range y {
+						g[2*i], g[2*i+1] = b[i][0]*y[i], b[i][1]*y[i]
+					}
+				},
+				Noise:          GeneralNoise,
+				NoiseDimension: 2,
+				Seed:           seed,
+				Path: func(t, h float64, dW []float64) {
+					w[0] += dW[0]
+					w[1] += dW[1]
+				},
+			}
+			if m == MilsteinHeun {
+				config.InitialStepSize = 0
+			}
+
+			y := []float64{1.0, 1.0}
+			if _, err := s.IntegrateSDE(0, 1, y, &config); err != nil {
+				t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+			}
+			for i := range y {
+				exact := math.Exp(a[i] - 0.5*(b[i][0]*b[i][0]+b[i][1]*b[i][1]) + b[i][0]*w[0] + b[i][1]*w[1])
+				meanError += math.Abs(y[i]-exact) / (2 * samples)
+			}
+		}
+		if testing.Verbose() {
+			t.Logf("%s: mean error %g", s.Info().Name, meanError)
+		}
+		if meanError > 1e-2 {
+			t.Errorf("%s: mean error %g", s.Info().Name, meanError)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	for j := 0; j < int(NumberOfSDEMethods); j++ {
+		s, _ := NewSDE(SDEMethod(j))
+		results := make([]float64, 3)
+		for k, seed := range []int64{1, 1, 2} {
+			w := 0.0
+			y := []float64{1.0}
+			if _, err := s.IntegrateSDE(0, 1, y, gbmConfig(Ito, 0.01, seed, &w)); err != nil {
+				t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+			}
+			results[k] = y[0]
+		}
+		if results[0] != results[1] || results[0] == results[2] {
+			t.Errorf("%s: results %v for the seeds 1, 1, 2", s.Info().Name, results)
+		}
+	}
+}
+
+func TestSDEConfig(t *testing.T) {
+	s, _ := NewSDE(EulerMaruyama)
+	w := 0.0
+	invalid := []*SDEConfig{gbmConfig(Ito, 0, 0, &w), gbmConfig(Ito, 0.1, 0, &w), gbmConfig(Calculus(2), 0.1, 0, &w), gbmConfig(Ito, 0.1, 0, &w)}
+	invalid[1].Diffusion = nil
+	invalid[3].Noise = GeneralNoise
+	for k, config := range invalid {
+		if _, err := s.IntegrateSDE(0, 1, []float64{1}, config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Configuration %d: expected configuration error, got %v", k, err)
+		}
+	}
+}
+
+func TestNoisyBrusselator(t *testing.T) {
+	b := problems.NewNoisyBruss2D(8, 0.1)
+	s, _ := NewSDE(MilsteinHeun)
+
+	config := SDEConfig{
+		Config:    Config{Fcn: b.Fcn, AbsoluteTolerance: 1e-3},
+		Diffusion: b.Noise,
+	}
+	y := b.Initialize()
+	stat, err := s.IntegrateSDE(0, 1, y, &config)
+	if err != nil {
+		t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+	}
+	if util.FirstNonFinite(y) >= 0 {
+		t.Errorf("%s: non finite result", s.Info().Name)
+	}
+	for i, v := range y {
+		if v <= 0.0 {
+			t.Errorf("%s: concentration %d is %g", s.Info().Name, i, v)
+			break
+		}
+	}
+	if testing.Verbose() {
+		t.Logf("%s: %d steps, %d rejected, %d evaluations", s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount)
+	}
+}
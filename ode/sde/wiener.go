@@ -0,0 +1,62 @@
+package sde
+
+import (
+	"math"
+	"math/rand"
+)
+
+// wiener draws the increments of the Wiener processes. The increments of rejected
+// steps are kept and split by Brownian bridges, so rejections do not bias the path
+type wiener struct {
+	random *rand.Rand
+	// intervals of the path beyond the current time that are already fixed, the earliest last
+	pending []increment
+}
+
+type increment struct {
+	h  float64
+	dW []float64
+}
+
+func newWiener(seed int64) *wiener {
+	return &wiener{random: rand.New(rand.NewSource(seed))}
+}
+
+// limit cuts the step size h at the end of the next fixed interval
+func (w *wiener) limit(h float64) float64 {
+	if len(w.pending) > 0 {
+		return math.Min(h, w.pending[len(w.pending)-1].h)
+	}
+	return h
+}
+
+// next draws the increments over the next h, which may not exceed limit(h)
+func (w *wiener) next(h float64, dW_out []float64) {
+	if len(w.pending) == 0 {
+		scale := math.Sqrt(h)
+		for j := range dW_out {
+			dW_out[j] = scale * w.random.NormFloat64()
+		}
+		return
+	}
+
+	top := &w.pending[len(w.pending)-1]
+	if h >= top.h {
+		copy(dW_out, top.dW)
+		w.pending = w.pending[:len(w.pending)-1]
+		return
+	}
+
+	// Brownian bridge from the beginning to the end of the interval
+	ratio, scale := h/top.h, math.Sqrt(h*(top.h-h)/top.h)
+	for j := range dW_out {
+		dW_out[j] = ratio*top.dW[j] + scale*w.random.NormFloat64()
+		top.dW[j] -= dW_out[j]
+	}
+	top.h -= h
+}
+
+// reject returns the increments dW over the rejected step h
+func (w *wiener) reject(h float64, dW []float64) {
+	w.pending = append(w.pending, increment{h: h, dW: append([]float64(nil), dW...)})
+}
@@ -0,0 +1,337 @@
+package sdirk
+
+import (
+	"github.com/rollingthunder/differential/linalg"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/util"
+	"math"
+)
+
+type sdirk struct {
+	IntegratorInfo
+	method SDIRKMethod
+
+	// diagonal entry of the implicit stages
+	gamma float64
+	// strictly lower part of the coefficient matrix, a[i] are the coefficients of stage i
+	a       [][]float64
+	b, c, e []float64
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	// stage derivatives, ks[0] is the evaluation at the beginning of the step
+	ks [][]float64
+	// known part of the current stage, its solution and the Newton update
+	base, z, fz, delta, residual []float64
+	// perturbed point for the directional differences
+	zPerturbed, fPerturbed []float64
+	yError                 []float64
+	// vectors of GMRES, reused by all Newton iterations
+	krylov linalg.IterativeWorkspace
+}
+
+// performs ESDIRK integration, the stage equations are solved by an inexact Newton iteration
+// with GMRES, using directional differences of the right hand side instead of a Jacobian
+// and the Preconditioner of the Config if set
+func (s *sdirk) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+
+	in.EvaluateBlocked(t, yT, in.ks[0])
+	in.EvaluationCount = 1
+
+	// compute initial step size if not set
+	stepEstimate := in.InitialStepSize
+	if stepEstimate <= 0.0 {
+		stepEstimate = EstimateStepSize(t, yT, in.ks[0], &in.Config, s.Order)
+	}
+	var stepNext float64
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		stepNext = stepEstimate
+		if t+stepNext > tEnd {
+			stepNext = tEnd - t
+		}
+		in.StepCount++
+
+		errorEstimate := math.Inf(1)
+		if s.stages(&in, t, stepNext, yT) {
+			var stg, id uint
+			for id = 0; id < in.n; id++ {
+				in.yError[id] = 0.0
+			}
+			for stg = 0; stg < s.Stages; stg++ {
+				for id = 0; id < in.n; id++ {
+					in.yError[id] += stepNext * s.e[stg] * in.ks[stg][id]
+				}
+			}
+			s.filterError(&in, t+stepNext, stepNext*s.gamma)
+			// the method is stiffly accurate, the last stage is the new solution
+			errorEstimate = in.ErrorNorm(in.yError, yT, in.z)
+
+			if !util.IsFinite(errorEstimate) {
+				if in.NonFinitePolicy == AbortOnNonFinite {
+					err = &NonFiniteError{Time: t, StepSize: stepNext, Index: util.FirstNonFinite(in.z)}
+					break
+				}
+				errorEstimate = math.Inf(1)
+			}
+			stepEstimate = stepNext * stepFactor(errorEstimate)
+		} else {
+			// the Newton iteration failed
+			in.NewtonFailures++
+			stepEstimate = 0.25 * stepNext
+		}
+
+		if errorEstimate > 1.0 {
+			// reject step
+			in.RejectedCount++
+
+			// report failure, step size too small
+			if stepEstimate < in.MinStepSize {
+				err = &StepSizeError{Time: t, StepSize: stepEstimate}
+				break
+			}
+		} else {
+			// accept step
+			t += stepNext
+			copy(yT, in.z)
+			copy(in.ks[0], in.ks[s.Stages-1])
+			stepEstimate = math.Min(stepEstimate, in.MaxStepSize)
+
+			// cancel after first step
+			if in.OneStepOnly {
+				break
+			}
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *sdirk) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp matrices
+	i.ks = util.MakeRectangular(s.Stages, i.n)
+	i.base = make([]float64, i.n)
+	i.z = make([]float64, i.n)
+	i.fz = make([]float64, i.n)
+	i.delta = make([]float64, i.n)
+	i.residual = make([]float64, i.n)
+	i.zPerturbed = make([]float64, i.n)
+	i.fPerturbed = make([]float64, i.n)
+	i.yError = make([]float64, i.n)
+
+	return
+}
+
+// computes the implicit stages, the last one is left in z
+// returns false if a Newton iteration did not converge
+func (s *sdirk) stages(in *integration, t, step float64, yT []float64) bool {
+	var stg, j, id uint
+	for stg = 1; stg < s.Stages; stg++ {
+		for id = 0; id < in.n; id++ {
+			in.base[id] = yT[id]
+		}
+		for j = 0; j < stg; j++ {
+			for id = 0; id < in.n; id++ {
+				in.base[id] += step * s.a[stg][j] * in.ks[j][id]
+			}
+		}
+
+		// predict with the previous stage derivative
+		for id = 0; id < in.n; id++ {
+			in.z[id] = in.base[id] + step*s.gamma*in.ks[stg-1][id]
+		}
+
+		if !s.newton(in, t+s.c[stg]*step, step*s.gamma, yT) {
+			return false
+		}
+
+		// the stage derivative follows from the stage equation z = base + h gamma k
+		for id = 0; id < in.n; id++ {
+			in.ks[stg][id] = (in.z[id] - in.base[id]) / (step * s.gamma)
+		}
+	}
+	return true
+}
+
+// filters the error estimate with (I - shift J)^-1 as proposed by Hosea and Shampine,
+// so it stays bounded for the stiff components. J is taken at the new solution z
+func (s *sdirk) filterError(in *integration, t, shift float64) {
+	if in.JacobianVector == nil {
+		in.EvaluateBlocked(t, in.z, in.fz)
+		in.EvaluationCount++
+	}
+
+	// the unfiltered estimate is the initial guess, it is accurate for the non-stiff components
+	copy(in.residual, in.yError)
+	krylovConfig := linalg.IterativeConfig{
+		Tolerance:      filterTolerance,
+		MaxIterations:  maxKrylovIterations,
+		Restart:        krylovRestart,
+		Preconditioner: in.preconditioner(t, shift),
+		Workspace:      &in.krylov,
+	}
+	krylovIterations, krylovErr := linalg.GMRES(in.operator(t, shift), in.residual, in.yError, &krylovConfig)
+	in.KrylovIterations += uint(krylovIterations)
+	if krylovErr != nil {
+		in.KrylovFailures++
+	}
+}
+
+// operator computes (I - shift J) v, with J v approximated by a directional difference at z
+// unless given by JacobianVector. fz has to hold the evaluation at z
+func (in *integration) operator(t, shift float64) linalg.LinearOperator {
+	return func(v, y_out []float64) {
+		if in.JacobianVector != nil {
+			in.JacobianVector(t, in.z, v, in.fPerturbed)
+			for id := range v {
+				y_out[id] = v[id] - shift*in.fPerturbed[id]
+			}
+			return
+		}
+		vNorm := util.EuclideanNorm(v)
+		if vNorm == 0.0 {
+			copy(y_out, v)
+			return
+		}
+		epsilon := math.Sqrt(1.1e-16) * (1.0 + util.EuclideanNorm(in.z)) / vNorm
+		for id := range v {
+			in.zPerturbed[id] = in.z[id] + epsilon*v[id]
+		}
+		in.EvaluateBlocked(t, in.zPerturbed, in.fPerturbed)
+		in.EvaluationCount++
+		for id := range v {
+			y_out[id] = v[id] - shift*(in.fPerturbed[id]-in.fz[id])/epsilon
+		}
+	}
+}
+
+// preconditioner applies the Preconditioner of the Config at z, if set
+func (in *integration) preconditioner(t, shift float64) linalg.Preconditioner {
+	if in.Preconditioner == nil {
+		return nil
+	}
+	return func(r, z_out []float64) {
+		in.Preconditioner(t, in.z, shift, r, z_out)
+	}
+}
+
+// solves z = base + shift f(t, z) for z by an inexact Newton iteration starting at z
+func (s *sdirk) newton(in *integration, t, shift float64, yT []float64) bool {
+	var id uint
+	operator, preconditioner := in.operator(t, shift), in.preconditioner(t, shift)
+
+	forcing := maxForcing
+	residualNorm, deltaNorm := 0.0, 0.0
+	for iteration := 0; iteration < maxNewtonIterations; iteration++ {
+		in.NewtonIterations++
+
+		// residual of the stage equation
+		in.EvaluateBlocked(t, in.z, in.fz)
+		in.EvaluationCount++
+		for id = 0; id < in.n; id++ {
+			in.residual[id] = -(in.z[id] - in.base[id] - shift*in.fz[id])
+		}
+		if util.FirstNonFinite(in.residual) >= 0 {
+			return false
+		}
+
+		// Eisenstat-Walker forcing term, choice 2 with safeguard
+		lastResidualNorm := residualNorm
+		residualNorm = util.EuclideanNorm(in.residual)
+		if iteration > 0 && lastResidualNorm > 0.0 {
+			ratio := residualNorm / lastResidualNorm
+			next := 0.9 * ratio * ratio
+			if safeguard := 0.9 * forcing * forcing; safeguard > 0.1 {
+				next = math.Max(next, safeguard)
+			}
+			forcing = math.Min(next, maxForcing)
+		}
+
+		for id = 0; id < in.n; id++ {
+			in.delta[id] = 0.0
+		}
+		krylovConfig := linalg.IterativeConfig{
+			Tolerance:      math.Max(forcing, 1e-10),
+			MaxIterations:  maxKrylovIterations,
+			Restart:        krylovRestart,
+			Preconditioner: preconditioner,
+			Workspace:      &in.krylov,
+		}
+		krylovIterations, krylovErr := linalg.GMRES(operator, in.residual, in.delta, &krylovConfig)
+		in.KrylovIterations += uint(krylovIterations)
+		if krylovErr != nil {
+			// use the inexact update anyway, the Newton iteration notices if it is useless
+			in.KrylovFailures++
+		}
+
+		for id = 0; id < in.n; id++ {
+			in.z[id] += in.delta[id]
+		}
+
+		// convergence estimate from the contraction of the updates
+		lastDeltaNorm := deltaNorm
+		deltaNorm = in.ErrorNorm(in.delta, yT, in.z)
+		if !util.IsFinite(deltaNorm) {
+			return false
+		}
+		if deltaNorm <= 0.01*newtonTolerance {
+			return true
+		}
+		if iteration == 0 {
+			continue
+		}
+		contraction := deltaNorm / lastDeltaNorm
+		if contraction >= 1.0 {
+			return false
+		}
+		if contraction/(1.0-contraction)*deltaNorm <= newtonTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// step size factor for the error estimate of the second order method
+func stepFactor(errorEstimate float64) float64 {
+	if !util.IsFinite(errorEstimate) {
+		return 0.2
+	}
+	factor := 0.9 * math.Pow(1.0e-10+errorEstimate, -1.0/3.0)
+	return math.Max(0.2, math.Min(factor, 5.0)) // safety interval
+}
@@ -0,0 +1,41 @@
+package sdirk
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
+	"testing"
+)
+
+// 8 million unknowns, too many for a stored Jacobian
+const benchmarkGridSize = 2000
+
+func benchmarkBruss2D(b *testing.B, preconditioned bool) {
+	bruss := problems.NewBruss2D(benchmarkGridSize)
+	s, _ := NewSDIRK(TRBDF2)
+	config := Config{
+		Fcn:               bruss.Fcn,
+		AbsoluteTolerance: 1e-4,
+	}
+	if preconditioned {
+		config.Preconditioner = bruss.(problems.PreconditionedProblem).Preconditioner
+	}
+
+	var stat Statistics
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		y := bruss.Initialize()
+		b.StartTimer()
+
+		var err error
+		if stat, err = s.Integrate(0, 0.01, y, &config); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(stat.EvaluationCount), "evaluations/op")
+	b.ReportMetric(float64(stat.KrylovIterations), "krylov/op")
+}
+
+func BenchmarkBruss2D(b *testing.B) { benchmarkBruss2D(b, false) }
+
+func BenchmarkBruss2DPreconditioned(b *testing.B) { benchmarkBruss2D(b, true) }
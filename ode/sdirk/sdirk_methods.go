@@ -0,0 +1,65 @@
+package sdirk
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"math"
+)
+
+type SDIRKMethod uint
+
+const (
+	TRBDF2               = SDIRKMethod(iota) // trapezoidal rule followed by BDF2, L-stable, order 2(3)
+	NumberOfSDIRKMethods = uint(iota)
+)
+
+const (
+	// Newton iterations per stage before the step is rejected
+	maxNewtonIterations = 8
+	// the Newton iteration stops when the estimated remaining error is below
+	// newtonTolerance times the tolerance of the integration
+	newtonTolerance = 0.1
+	// upper bound for the forcing terms of the inexact Newton iteration
+	maxForcing = 0.1
+	// Krylov iterations per linear solve and dimension of the Krylov space before restarting
+	maxKrylovIterations = 60
+	krylovRestart       = 20
+	// relative residual of the linear solve filtering the error estimate
+	filterTolerance = 0.01
+)
+
+func NewSDIRK(m SDIRKMethod) (i ode.Integrator, err error) {
+	var s sdirk
+	s.method = m
+
+	switch m {
+	case TRBDF2:
+		s.Name = "TRBDF2"
+		s.Stages = 3
+		s.Order = 2
+		setCoeffsTRBDF2(&s)
+	default:
+		err = &ode.ConfigError{Field: "SDIRKMethod", Reason: "unknown sdirk method"}
+	}
+
+	i = &s
+	return
+}
+
+// TR-BDF2 of Bank et al. with the embedded third order method of Hosea and Shampine
+// written as an ESDIRK method with explicit first stage
+func setCoeffsTRBDF2(s *sdirk) {
+	gamma := 2.0 - math.Sqrt2
+	d := gamma / 2.0
+	w := math.Sqrt2 / 4.0
+
+	s.gamma = d
+	s.c = []float64{0.0, gamma, 1.0}
+	s.a = [][]float64{
+		{},
+		{d},
+		{w, w},
+	}
+	s.b = []float64{w, w, d}
+	// b - bHat, bHat = ((1 - w) / 3, (3w + 1) / 3, d / 3)
+	s.e = []float64{w - (1.0-w)/3.0, w - (3.0*w+1.0)/3.0, d - d/3.0}
+}
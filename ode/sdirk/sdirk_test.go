@@ -0,0 +1,133 @@
+package sdirk
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+func TestAllSDIRK(t *testing.T) {
+	integrators := make([]Integrator, NumberOfSDIRKMethods)
+	for j := 0; j < int(NumberOfSDIRKMethods); j++ {
+		s, err := NewSDIRK(SDIRKMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create SDIRK Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = s
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestSDIRKStiff(t *testing.T) {
+	for j := 0; j < int(NumberOfSDIRKMethods); j++ {
+		s, _ := NewSDIRK(SDIRKMethod(j))
+		stat := IntegrateStiffCosine(t, s, Config{AbsoluteTolerance: 1e-6})
+
+		// without the filter the error estimate of the stiff component rejects every other step
+		if stat.RejectedCount > 10 {
+			t.Errorf("%s: %d steps rejected, the error estimate is not filtered", s.Info().Name, stat.RejectedCount)
+		}
+	}
+}
+
+func TestSDIRKBrussMatrixFree(t *testing.T) {
+	// 2 * 64^2 unknowns, diffusion makes the system stiff
+	bruss := problems.NewBruss2D(64)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+
+	reference, _ := Reference(t, dopri, bruss, 1)
+
+	for j := 0; j < int(NumberOfSDIRKMethods); j++ {
+		s, _ := NewSDIRK(SDIRKMethod(j))
+		y := bruss.Initialize()
+		config := Config{
+			Fcn:               bruss.Fcn,
+			AbsoluteTolerance: 1e-5,
+		}
+
+		stat, err := s.Integrate(0, 1, y, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		CheckReference(t, s.Info().Name, y, reference, ReferenceTolerance)
+		if stat.NewtonIterations == 0 || stat.KrylovIterations == 0 {
+			t.Errorf("%s: Newton and Krylov iterations missing from the statistics", s.Info().Name)
+		}
+
+		if testing.Verbose() {
+			t.Logf("Bruss2D %s: %d steps, %d rejected, %d evaluations, %d Newton, %d Krylov iterations, %d Newton, %d Krylov failures",
+				s.Info().Name, stat.StepCount, stat.RejectedCount, stat.EvaluationCount,
+				stat.NewtonIterations, stat.KrylovIterations, stat.NewtonFailures, stat.KrylovFailures)
+		}
+	}
+}
+
+func TestSDIRKJacobianVector(t *testing.T) {
+	bruss := problems.NewBruss2D(16)
+	generic := bruss.(problems.GenericProblem)
+	s, _ := NewSDIRK(TRBDF2)
+
+	reference := bruss.Initialize()
+	referenceStat, err := s.Integrate(0, 1, reference, &Config{Fcn: bruss.Fcn, AbsoluteTolerance: 1e-6})
+	if err != nil {
+		t.Fatalf("Integration with directional differences failed - %s", err.Error())
+	}
+
+	// Jacobian-vector products by automatic differentiation
+	y := bruss.Initialize()
+	config := Config{AbsoluteTolerance: 1e-6}
+	generic.ParametricSystem().Fix(generic.Parameters()).Configure(&config)
+	stat, err := s.Integrate(0, 1, y, &config)
+	if err != nil {
+		t.Fatalf("Integration with Jacobian-vector products failed - %s", err.Error())
+	}
+	for i := range y {
+		if math.Abs(y[i]-reference[i]) > 1e-4 {
+			t.Fatalf("result[%d] = %g differs from %g with directional differences", i, y[i], reference[i])
+		}
+	}
+	// the products need no evaluations of Fcn
+	if stat.EvaluationCount >= referenceStat.EvaluationCount {
+		t.Errorf("%d evaluations with Jacobian-vector products, %d without", stat.EvaluationCount, referenceStat.EvaluationCount)
+	}
+}
+
+func TestSDIRKPreconditioner(t *testing.T) {
+	// the finer grid makes the diffusion stiff enough for the preconditioner to pay off
+	bruss := problems.NewBruss2D(128)
+	s, _ := NewSDIRK(TRBDF2)
+
+	reference := bruss.Initialize()
+	referenceStat, err := s.Integrate(0, 1, reference, &Config{Fcn: bruss.Fcn, AbsoluteTolerance: 1e-5})
+	if err != nil {
+		t.Fatalf("Integration without preconditioner failed - %s", err.Error())
+	}
+
+	y := bruss.Initialize()
+	config := Config{
+		Fcn:               bruss.Fcn,
+		AbsoluteTolerance: 1e-5,
+		Preconditioner:    bruss.(problems.PreconditionedProblem).Preconditioner,
+	}
+	stat, err := s.Integrate(0, 1, y, &config)
+	if err != nil {
+		t.Fatalf("Integration with preconditioner failed - %s", err.Error())
+	}
+	for i := range y {
+		if math.Abs(y[i]-reference[i]) > 1e-3 {
+			t.Fatalf("result[%d] = %g differs from %g without preconditioner", i, y[i], reference[i])
+		}
+	}
+	if stat.KrylovIterations >= referenceStat.KrylovIterations {
+		t.Errorf("%d Krylov iterations with preconditioner, %d without", stat.KrylovIterations, referenceStat.KrylovIterations)
+	}
+	if testing.Verbose() {
+		t.Logf("%d Krylov iterations and %d evaluations with preconditioner, %d and %d without",
+			stat.KrylovIterations, stat.EvaluationCount, referenceStat.KrylovIterations, referenceStat.EvaluationCount)
+	}
+}
@@ -0,0 +1,75 @@
+package ode
+
+// SecondOrderFunction evaluates the right hand side of the second order system
+// ddy(t) = f(t, y(t), dy(t)), where dy and ddy are the first and second derivative of y
+type SecondOrderFunction func(t float64, yT, dyT []float64, ddy_out []float64)
+
+// SecondOrderConfig configures the integration of a second order system,
+// Fcn and FcnBlocked of the embedded Config are ignored
+type SecondOrderConfig struct {
+	Config
+
+	Acceleration SecondOrderFunction
+
+	// VelocityIndependent states that Acceleration does not depend on dy, i.e. ddy = f(t, y).
+	// Nystrom methods for this special form require it
+	VelocityIndependent bool
+}
+
+// SecondOrderIntegrator integrates ddy = f(t, y, dy) given y and its derivative dy at t
+type SecondOrderIntegrator interface {
+	Info() IntegratorInfo
+	IntegrateSecondOrder(t, tEnd float64, yT, dyT []float64, config *SecondOrderConfig) (stat Statistics, err error)
+}
+
+// FirstOrder returns the equivalent first order system of size 2n
+// for the stacked state (y, y')
+func (f SecondOrderFunction) FirstOrder(n int) Function {
+	return func(t float64, yT []float64, dy_out []float64) {
+		copy(dy_out[:n], yT[n:])
+		f(t, yT[:n], yT[n:], dy_out[n:])
+	}
+}
+
+// ValidateAndPrepare checks the configuration for a second order system with n components
+// and returns the effective configuration, its Fcn is the equivalent first order system
+func (c *SecondOrderConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e SecondOrderConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Acceleration == nil {
+		err = &ConfigError{Field: "Acceleration", Reason: "no evaluation function specified"}
+		return
+	}
+
+	firstOrder := c.Config
+	firstOrder.Fcn, firstOrder.FcnBlocked = c.Acceleration.FirstOrder(int(n)), nil
+
+	e.Config, err = firstOrder.ValidateAndPrepare(2*n, t, tEnd)
+	e.Acceleration, e.VelocityIndependent = c.Acceleration, c.VelocityIndependent
+	return
+}
+
+// SecondOrderAdapter integrates second order systems with a first order Integrator
+type SecondOrderAdapter struct {
+	Integrator
+}
+
+func (a SecondOrderAdapter) IntegrateSecondOrder(t, tEnd float64, yT, dyT []float64, config *SecondOrderConfig) (stat Statistics, err error) {
+	n := len(yT)
+	effective, err := config.ValidateAndPrepare(uint(n), t, tEnd)
+	if err != nil {
+		return
+	}
+
+	state := make([]float64, 2*n)
+	copy(state[:n], yT)
+	copy(state[n:], dyT)
+
+	stat, err = a.Integrate(t, tEnd, state, &effective.Config)
+
+	copy(yT, state[:n])
+	copy(dyT, state[n:])
+	return
+}
@@ -0,0 +1,257 @@
+package ode
+
+import (
+	"math"
+	"sync"
+)
+
+// ParametricFunction evaluates the right hand side yT'(t) = f(t, yT, p) for the parameters p
+type ParametricFunction func(t float64, yT, p []float64, dy_out []float64)
+
+// SensitivityFunction evaluates the right hand sides of the sensitivity equations
+// s_j' = df/dy s_j + df/dp_j, where s[j] is the derivative of yT with respect to p_j
+type SensitivityFunction func(t float64, yT, p []float64, s [][]float64, ds_out [][]float64)
+
+// SensitivityConfig configures the integration of a parametric system together with the
+// derivatives of its solution with respect to the parameters. The embedded Config applies
+// to the augmented system of yT followed by the sensitivities of the parameters one after another,
+// its Fcn, FcnBlocked, BlockSize and Jacobian settings are ignored
+type SensitivityConfig struct {
+	Config
+
+	Parametric ParametricFunction
+	Parameters []float64
+
+	// Sensitivity, if set, evaluates the sensitivity equations.
+	// Else they are approximated by finite differences of Parametric
+	Sensitivity SensitivityFunction
+
+	// ErrorControl includes the sensitivities in the error estimate of the integrator,
+	// else it is restricted to the n components of the system unless Config.ErrorComponents is set
+	ErrorControl bool
+}
+
+// SensitivityIntegrator integrates a system together with its forward sensitivities
+type SensitivityIntegrator interface {
+	Info() IntegratorInfo
+	// IntegrateSensitivities advances yT and the n x len(Parameters) sensitivity matrix
+	// dyT/dp from t to tEnd. The initial sensitivities are zero, unless the initial values
+	// depend on the parameters
+	IntegrateSensitivities(t, tEnd float64, yT []float64, sensitivity [][]float64, config *SensitivityConfig) (stat Statistics, err error)
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components and returns
+// the effective configuration, its Fcn evaluates the augmented system
+func (c *SensitivityConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e SensitivityConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Parametric == nil {
+		err = &ConfigError{Field: "Parametric", Reason: "no evaluation function specified"}
+		return
+	}
+	if len(c.Parameters) == 0 {
+		err = &ConfigError{Field: "Parameters", Reason: "no parameters specified"}
+		return
+	}
+
+	e = *c
+	e.Parameters = append([]float64(nil), c.Parameters...)
+
+	augmented := c.Config
+	augmented.Fcn, augmented.FcnBlocked, augmented.BlockSize = e.augmented(int(n)), nil, 0
+	augmented.Jacobian, augmented.Sparsity, augmented.SparseJacobian, augmented.JacobianVector = nil, nil, nil, nil
+	augmented.Preconditioner = nil
+	if !c.ErrorControl && c.ErrorComponents == 0 {
+		augmented.ErrorComponents = n
+	}
+	e.Config, err = augmented.ValidateAndPrepare(n*uint(1+len(c.Parameters)), t, tEnd)
+	return
+}
+
+// the buffers of one evaluation of the augmented system
+type sensitivityState struct {
+	// headers of the sensitivities and their derivatives within the augmented vectors
+	s, ds [][]float64
+	// perturbed values of the finite differences
+	yPerturbed, pPerturbed []float64
+}
+
+// augmented returns the right hand side of the system of size n and its sensitivities,
+// it may be evaluated concurrently
+func (c *SensitivityConfig) augmented(n int) Function {
+	parametric, sensitivity, p := c.Parametric, c.Sensitivity, c.Parameters
+	states := sync.Pool{New: func() interface{} {
+		return &sensitivityState{
+			s: make([][]float64, len(p)), ds: make([][]float64, len(p)),
+			yPerturbed: make([]float64, n), pPerturbed: append([]float64(nil), p...),
+		}
+	}}
+	return func(t float64, yT []float64, dy_out []float64) {
+		state := states.Get().(*sensitivityState)
+		parametric(t, yT[:n], p, dy_out[:n])
+		for j := range p {
+			state.s[j], state.ds[j] = yT[(j+1)*n:(j+2)*n], dy_out[(j+1)*n:(j+2)*n]
+		}
+		if sensitivity != nil {
+			sensitivity(t, yT[:n], p, state.s, state.ds)
+		} else {
+			state.finiteDifferences(parametric, t, yT[:n], p, dy_out[:n])
+		}
+		states.Put(state)
+	}
+}
+
+// finiteDifferences approximates df/dy s_j + df/dp_j by a finite difference
+// in the direction (s_j, e_j) from f0 = f(t, yT, p), which costs one evaluation per parameter
+func (state *sensitivityState) finiteDifferences(parametric ParametricFunction, t float64, yT, p, f0 []float64) {
+	s, ds_out, yPerturbed, pPerturbed := state.s, state.ds, state.yPerturbed, state.pPerturbed
+
+	for j := range p {
+		delta := math.Sqrt(uround) * math.Max(1.0, math.Max(math.Abs(p[j]), maxNorm(yT))) / math.Max(1.0, maxNorm(s[j]))
+		for i := range yT {
+			yPerturbed[i] = yT[i] + delta*s[j][i]
+		}
+		pPerturbed[j] = p[j] + delta
+		parametric(t, yPerturbed, pPerturbed, ds_out[j])
+		pPerturbed[j] = p[j]
+		for i := range f0 {
+			ds_out[j][i] = (ds_out[j][i] - f0[i]) / delta
+		}
+	}
+}
+
+// ObjectiveFunction evaluates the derivatives dg/dy and dg/dp of a scalar objective g(yT, p)
+// of the solution at the end of the integration
+type ObjectiveFunction func(yT, p []float64, dgdy_out, dgdp_out []float64)
+
+// VectorJacobianFunction evaluates the products lambda^T df/dy and lambda^T df/dp
+type VectorJacobianFunction func(t float64, yT, p, lambda []float64, dfdy_out, dfdp_out []float64)
+
+// AdjointConfig configures the computation of the gradient of the objective g(y(tEnd), p)
+// with respect to the parameters by the adjoint method. The initial values may not depend on
+// the parameters. The embedded Config applies to the solution and to the adjoint system,
+// its Fcn, FcnBlocked and DenseOutput are ignored
+type AdjointConfig struct {
+	Config
+
+	Parametric ParametricFunction
+	Parameters []float64
+	Objective  ObjectiveFunction
+
+	// VectorJacobian, if set, evaluates the right hand side of the adjoint system.
+	// Else it is approximated by finite differences of Parametric, which costs
+	// one evaluation per component and parameter in every evaluation of the adjoint system
+	VectorJacobian VectorJacobianFunction
+
+	// CheckpointSteps is the number of steps between two checkpoints of the solution,
+	// the steps are recomputed and kept in memory one checkpoint interval at a time.
+	// Defaults to 100
+	CheckpointSteps uint
+}
+
+// AdjointIntegrator computes gradients of scalar objectives by the adjoint method
+type AdjointIntegrator interface {
+	Info() IntegratorInfo
+	// Gradient advances yT from t to tEnd and computes the gradient of the objective
+	Gradient(t, tEnd float64, yT, gradient_out []float64, config *AdjointConfig) (stat Statistics, err error)
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components and returns
+// the effective configuration, its Fcn evaluates Parametric for the Parameters
+// and its VectorJacobian is set
+func (c *AdjointConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e AdjointConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Parametric == nil {
+		err = &ConfigError{Field: "Parametric", Reason: "no evaluation function specified"}
+		return
+	}
+	if len(c.Parameters) == 0 {
+		err = &ConfigError{Field: "Parameters", Reason: "no parameters specified"}
+		return
+	}
+	if c.Objective == nil {
+		err = &ConfigError{Field: "Objective", Reason: "no objective specified"}
+		return
+	}
+
+	e = *c
+	e.Parameters = append([]float64(nil), c.Parameters...)
+	if e.CheckpointSteps == 0 {
+		e.CheckpointSteps = 100
+	}
+
+	parametric, p := c.Parametric, e.Parameters
+	solution := c.Config
+	solution.FcnBlocked, solution.DenseOutput = nil, nil
+	solution.Fcn = func(t float64, yT []float64, dy_out []float64) { parametric(t, yT, p, dy_out) }
+	e.Config, err = solution.ValidateAndPrepare(n, t, tEnd)
+	if err == nil && e.VectorJacobian == nil {
+		e.VectorJacobian = e.finiteDifferences(int(n))
+	}
+	return
+}
+
+// VectorJacobianProduct evaluates lambda^T df/dy and lambda^T df/dp with VectorJacobian,
+// the effective configuration approximates it by finite differences if unset
+func (c *AdjointConfig) VectorJacobianProduct(t float64, yT, lambda []float64, dfdy_out, dfdp_out []float64) {
+	vectorJacobian := c.VectorJacobian
+	if vectorJacobian == nil {
+		vectorJacobian = c.finiteDifferences(len(yT))
+	}
+	vectorJacobian(t, yT, c.Parameters, lambda, dfdy_out, dfdp_out)
+}
+
+// the buffers of one finite difference approximation of the vector-Jacobian products
+type productState struct {
+	f0, f, yPerturbed, pPerturbed []float64
+}
+
+// finiteDifferences approximates the vector-Jacobian products of a system with n components
+// by finite differences of Parametric, which costs one evaluation per component and parameter.
+// It may be evaluated concurrently
+func (c *AdjointConfig) finiteDifferences(n int) VectorJacobianFunction {
+	parametric, increments, parameters := c.Parametric, c.Config, len(c.Parameters)
+	states := sync.Pool{New: func() interface{} {
+		return &productState{
+			f0: make([]float64, n), f: make([]float64, n),
+			yPerturbed: make([]float64, n), pPerturbed: make([]float64, parameters),
+		}
+	}}
+	return func(t float64, yT, p, lambda []float64, dfdy_out, dfdp_out []float64) {
+		state := states.Get().(*productState)
+		f0, f, yPerturbed, pPerturbed := state.f0, state.f, state.yPerturbed, state.pPerturbed
+		parametric(t, yT, p, f0)
+
+		product := func(delta float64) float64 {
+			sum := 0.0
+			for i := range f {
+				sum += lambda[i] * (f[i] - f0[i])
+			}
+			return sum / delta
+		}
+
+		copy(yPerturbed, yT)
+		for k := range yT {
+			delta := increments.jacobianIncrement(yT[k])
+			yPerturbed[k] = yT[k] + delta
+			parametric(t, yPerturbed, p, f)
+			yPerturbed[k] = yT[k]
+			dfdy_out[k] = product(delta)
+		}
+
+		copy(pPerturbed, p)
+		for j := range p {
+			pPerturbed[j] = p[j] + math.Sqrt(uround)*math.Max(1.0, math.Abs(p[j]))
+			delta := pPerturbed[j] - p[j]
+			parametric(t, yT, pPerturbed, f)
+			pPerturbed[j] = p[j]
+			dfdp_out[j] = product(delta)
+		}
+		states.Put(state)
+	}
+}
@@ -0,0 +1,154 @@
+package sensitivity
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"sort"
+	"sync"
+)
+
+type adjoint struct {
+	IntegratorInfo
+	base Integrator
+}
+
+// a state of the forward solution from which the following steps are recomputed
+type checkpoint struct {
+	t, step float64
+	y       []float64
+}
+
+// the interpolants of the steps between two checkpoints
+type segment struct {
+	steps []Interpolant
+	ends  []float64
+}
+
+// NewAdjoint creates an integrator for gradients by the adjoint method,
+// the base integrator has to provide dense output
+func NewAdjoint(base Integrator) (i AdjointIntegrator, err error) {
+	var a adjoint
+	if base == nil {
+		err = &ConfigError{Field: "Integrator", Reason: "no integrator specified"}
+	} else if info := base.Info(); !info.DenseOutput {
+		err = &ConfigError{Field: "Integrator", Reason: "integrator " + info.Name + " provides no dense output"}
+	} else {
+		a.base = base
+		a.Name = "Adjoint-" + info.Name
+		a.Stages, a.Order = info.Stages, info.Order
+	}
+
+	i = &a
+	return
+}
+
+// integrates the solution forward and stores a checkpoint every CheckpointSteps steps.
+// The adjoint system lambda' = -(df/dy)^T lambda, lambda(tEnd) = dg/dy is integrated
+// backward in time, one checkpoint interval after another, together with the quadrature
+// mu' = -(df/dp)^T lambda of the gradient dg/dp + mu(t). The solution on the interval is
+// recomputed from the checkpoint and evaluated through the interpolants of its steps
+func (a *adjoint) Gradient(t, tEnd float64, yT, gradient_out []float64, config *AdjointConfig) (stat Statistics, err error) {
+	n := len(yT)
+
+	effective, err := config.ValidateAndPrepare(uint(n), t, tEnd)
+	if err != nil {
+		return
+	}
+	parameters := len(effective.Parameters)
+	if len(gradient_out) != parameters {
+		err = &ConfigError{Field: "gradient", Reason: "one entry per parameter needed"}
+		return
+	}
+
+	// forward integration
+	checkpoints := []checkpoint{{t: t, y: append([]float64(nil), yT...)}}
+	steps := uint(0)
+	forward := effective.Config
+	forward.DenseOutput = func(step Interpolant) {
+		t0, t1 := step.Interval()
+		if last := &checkpoints[len(checkpoints)-1]; last.step == 0.0 {
+			last.step = t1 - t0
+		}
+		if steps++; steps%effective.CheckpointSteps == 0 && t1 < tEnd {
+			y := make([]float64, n)
+			step.Evaluate(t1, y)
+			checkpoints = append(checkpoints, checkpoint{t: t1, y: y})
+		}
+	}
+	forwardStat, err := a.base.Integrate(t, tEnd, yT, &forward)
+	accumulate(&stat, &forwardStat)
+	if err != nil {
+		return
+	}
+
+	// terminal values of the adjoint system and the quadrature
+	adjointState := make([]float64, n+parameters)
+	dgdp := make([]float64, parameters)
+	effective.Objective(yT, effective.Parameters, adjointState[:n], dgdp)
+
+	// backward integration in the reversed time tau = -t
+	var current segment
+	// the settings specific to the system of the solution do not apply to the adjoint system
+	backward := effective.Config
+	backward.InitialStepSize, backward.DenseOutput = 0.0, nil
+	backward.FcnBlocked, backward.BlockSize, backward.ErrorComponents = nil, 0, 0
+	backward.Jacobian, backward.Sparsity, backward.SparseJacobian, backward.JacobianVector = nil, nil, nil, nil
+	backward.Preconditioner = nil
+	temps := sync.Pool{New: func() interface{} { return make([]float64, n) }}
+	backward.Fcn = func(tau float64, z []float64, dz_out []float64) {
+		y := temps.Get().([]float64)
+		current.evaluate(-tau, y)
+		effective.VectorJacobian(-tau, y, effective.Parameters, z[:n], dz_out[:n], dz_out[n:])
+		temps.Put(y)
+	}
+
+	end := tEnd
+	for k := len(checkpoints) - 1; k >= 0; k-- {
+		start := checkpoints[k]
+
+		// recompute the steps of the interval
+		current = segment{}
+		recompute := effective.Config
+		recompute.InitialStepSize = start.step
+		recompute.DenseOutput = func(step Interpolant) {
+			_, t1 := step.Interval()
+			current.steps = append(current.steps, step)
+			current.ends = append(current.ends, t1)
+		}
+		y := append([]float64(nil), start.y...)
+		recomputeStat, recomputeErr := a.base.Integrate(start.t, end, y, &recompute)
+		accumulate(&stat, &recomputeStat)
+		if err = recomputeErr; err != nil {
+			return
+		}
+
+		backwardStat, backwardErr := a.base.Integrate(-end, -start.t, adjointState, &backward)
+		accumulate(&stat, &backwardStat)
+		if err = backwardErr; err != nil {
+			return
+		}
+		backward.InitialStepSize = backwardStat.NextStepSize
+		end = start.t
+	}
+
+	for j := range gradient_out {
+		gradient_out[j] = dgdp[j] + adjointState[n+j]
+	}
+	stat.CurrentTime = tEnd
+	stat.Effective = effective.Config
+	return
+}
+
+// evaluate interpolates the solution at t from the step containing it
+func (s *segment) evaluate(t float64, y_out []float64) {
+	index := sort.SearchFloat64s(s.ends, t)
+	if index >= len(s.steps) {
+		index = len(s.steps) - 1
+	}
+	s.steps[index].Evaluate(t, y_out)
+}
+
+// accumulate adds the counts of one integration to the statistics of the whole computation
+func accumulate(stat, other *Statistics) {
+	stat.Add(*other)
+	stat.LastStepSize, stat.NextStepSize = other.LastStepSize, other.NextStepSize
+}
@@ -0,0 +1,81 @@
+package sensitivity
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"testing"
+)
+
+// the gradient of g = 1/2 |positions|^2 at tEnd with respect to the masses of many bodies
+const benchmarkBodies = 16
+
+func mbodyObjective(y, p []float64, dgdy, dgdp []float64) {
+	for i := range dgdy {
+		dgdy[i] = 0.0
+		if i%6 < 3 {
+			dgdy[i] = y[i]
+		}
+	}
+	for j := range dgdp {
+		dgdp[j] = 0.0
+	}
+}
+
+func benchmarkAdjoint(b *testing.B, vectorJacobian bool) {
+	problem := problems.NewMBody(benchmarkBodies).(problems.AdjointProblem)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	a, _ := NewAdjoint(dopri)
+	config := AdjointConfig{
+		Config:     Config{AbsoluteTolerance: 1e-8},
+		Parametric: problem.FcnParameters,
+		Parameters: problem.Parameters(),
+		Objective:  mbodyObjective,
+	}
+	if vectorJacobian {
+		config.VectorJacobian = problem.VectorJacobian
+	}
+	gradient := make([]float64, len(config.Parameters))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := a.Gradient(0, 1, problem.Initialize(), gradient, &config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGradientAdjoint(b *testing.B) { benchmarkAdjoint(b, true) }
+
+func BenchmarkGradientAdjointFiniteDifferences(b *testing.B) { benchmarkAdjoint(b, false) }
+
+// forward sensitivities by automatic differentiation, the gradient is dg/dy S
+func BenchmarkGradientForward(b *testing.B) {
+	problem := problems.NewMBody(benchmarkBodies).(problems.GenericProblem)
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	f, _ := NewForward(dopri)
+	config := SensitivityConfig{
+		Config:      Config{AbsoluteTolerance: 1e-8},
+		Parametric:  problem.FcnParameters,
+		Parameters:  problem.Parameters(),
+		Sensitivity: problem.ParametricSystem().Sensitivity(),
+	}
+	p := len(config.Parameters)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y := problem.Initialize()
+		s := util.MakeRectangular(uint(len(y)), uint(p))
+		if _, err := f.IntegrateSensitivities(0, 1, y, s, &config); err != nil {
+			b.Fatal(err)
+		}
+		dgdy, gradient := make([]float64, len(y)), make([]float64, p)
+		mbodyObjective(y, config.Parameters, dgdy, gradient)
+		for j := range gradient {
+			for k := range y {
+				gradient[j] += dgdy[k] * s[k][j]
+			}
+		}
+	}
+}
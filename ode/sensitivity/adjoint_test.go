@@ -0,0 +1,184 @@
+package sensitivity
+
+import (
+	"errors"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/adams"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+// finiteDifferenceGradient approximates the gradient of objective(y(tEnd))
+// by central differences of solutions for perturbed parameters
+func finiteDifferenceGradient(t *testing.T, problem problems.ParametricProblem, objective func(y []float64) float64, tEnd float64) []float64 {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	p := problem.Parameters()
+	gradient := make([]float64, len(p))
+	for j := range p {
+		delta := 1e-4 * math.Abs(p[j])
+		values := make([]float64, 2)
+		for k, sign := range []float64{1, -1} {
+			perturbed := append([]float64(nil), p...)
+			perturbed[j] += sign * delta
+			y := problem.Initialize()
+			fcn := func(t float64, y []float64, dy []float64) { problem.FcnParameters(t, y, perturbed, dy) }
+			if _, err := dopri.Integrate(0, tEnd, y, &Config{Fcn: fcn, AbsoluteTolerance: 1e-12}); err != nil {
+				t.Fatalf("%s: Integration failed - %s", dopri.Info().Name, err.Error())
+			}
+			values[k] = objective(y)
+		}
+		gradient[j] = (values[0] - values[1]) / (2 * delta)
+	}
+	return gradient
+}
+
+func compareGradients(t *testing.T, name string, gradient, expected []float64, tolerance float64) {
+	for j := range gradient {
+		if math.Abs(gradient[j]-expected[j]) > tolerance*(1.0+math.Abs(expected[j])) {
+			t.Errorf("%s: gradient %v differs from %v", name, gradient, expected)
+			return
+		}
+	}
+}
+
+func TestAdjointDecay(t *testing.T) {
+	// g = y(tEnd) for y' = -k y, dg/dk = -tEnd y(tEnd)
+	const k, tEnd = 2.0, 1.5
+	analytic := func(t float64, y, p, lambda []float64, dfdy, dfdp []float64) {
+		dfdy[0] = -p[0] * lambda[0]
+		dfdp[0] = -y[0] * lambda[0]
+	}
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	a, err := NewAdjoint(dopri)
+	if err != nil {
+		t.Fatalf("Couldn't create adjoint integrator - %s", err.Error())
+	}
+
+	for _, vectorJacobian := range []VectorJacobianFunction{analytic, nil} {
+		config := AdjointConfig{
+			Config:     Config{AbsoluteTolerance: 1e-10},
+			Parametric: decay,
+			Parameters: []float64{k},
+			Objective: func(y, p []float64, dgdy, dgdp []float64) {
+				dgdy[0], dgdp[0] = 1.0, 0.0
+			},
+			VectorJacobian:  vectorJacobian,
+			CheckpointSteps: 5,
+		}
+		y := []float64{3.0}
+		gradient := make([]float64, 1)
+		if _, err := a.Gradient(0, tEnd, y, gradient, &config); err != nil {
+			t.Fatalf("%s: Gradient failed - %s", a.Info().Name, err.Error())
+		}
+		exact := -tEnd * 3.0 * math.Exp(-k*tEnd)
+		if math.Abs(gradient[0]-exact) > 1e-7 {
+			t.Errorf("%s (analytic %t): gradient %g differs from %g", a.Info().Name, vectorJacobian != nil, gradient[0], exact)
+		}
+	}
+}
+
+func TestAdjointMBody(t *testing.T) {
+	// g = 1/2 |positions|^2 at tEnd with respect to the masses
+	const tEnd = 2.0
+	problem := problems.NewMBody(4).(problems.AdjointProblem)
+	objective := func(y []float64) (g float64) {
+		for i := 0; i < len(y); i += 6 {
+			g += 0.5 * (y[i]*y[i] + y[i+1]*y[i+1] + y[i+2]*y[i+2])
+		}
+		return
+	}
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	a, _ := NewAdjoint(dopri)
+	config := AdjointConfig{
+		Config:         Config{AbsoluteTolerance: 1e-10},
+		Parametric:     problem.FcnParameters,
+		Parameters:     problem.Parameters(),
+		Objective:      mbodyObjective,
+		VectorJacobian: problem.VectorJacobian,
+	}
+	y := problem.Initialize()
+	gradient := make([]float64, len(config.Parameters))
+	stat, err := a.Gradient(0, tEnd, y, gradient, &config)
+	if err != nil {
+		t.Fatalf("%s: Gradient failed - %s", a.Info().Name, err.Error())
+	}
+	compareGradients(t, a.Info().Name, gradient, finiteDifferenceGradient(t, problem, objective, tEnd), 1e-5)
+	if testing.Verbose() {
+		t.Logf("%s: gradient %v, %d steps, %d evaluations", a.Info().Name, gradient, stat.StepCount, stat.EvaluationCount)
+	}
+}
+
+func TestAdjointBrusselator(t *testing.T) {
+	// g = sum of u at tEnd with respect to A, B and alpha
+	const tEnd = 1.0
+	problem := problems.NewBruss2D(4).(problems.AdjointProblem)
+	objective := func(y []float64) (g float64) {
+		for i := 0; i < len(y); i += 2 {
+			g += y[i]
+		}
+		return
+	}
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	a, _ := NewAdjoint(dopri)
+	expected := finiteDifferenceGradient(t, problem, objective, tEnd)
+
+	// the gradient does not depend on the checkpoints, nor on the evaluation of the adjoint system
+	for k, checkpointSteps := range []uint{1, 7, 1000} {
+		config := AdjointConfig{
+			Config:     Config{AbsoluteTolerance: 1e-10},
+			Parametric: problem.FcnParameters,
+			Parameters: problem.Parameters(),
+			Objective: func(y, p []float64, dgdy, dgdp []float64) {
+				for i := range dgdy {
+					dgdy[i] = float64(1 - i%2)
+				}
+				for j := range dgdp {
+					dgdp[j] = 0.0
+				}
+			},
+			CheckpointSteps: checkpointSteps,
+		}
+		if k > 0 {
+			config.VectorJacobian = problem.VectorJacobian
+		}
+		y := problem.Initialize()
+		gradient := make([]float64, len(config.Parameters))
+		stat, err := a.Gradient(0, tEnd, y, gradient, &config)
+		if err != nil {
+			t.Fatalf("%s: Gradient failed - %s", a.Info().Name, err.Error())
+		}
+		compareGradients(t, a.Info().Name, gradient, expected, 1e-5)
+		if testing.Verbose() {
+			t.Logf("%s (%d steps per checkpoint): gradient %v, %d steps, %d evaluations", a.Info().Name, checkpointSteps, gradient, stat.StepCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestAdjointConfig(t *testing.T) {
+	objective := func(y, p []float64, dgdy, dgdp []float64) {}
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	a, _ := NewAdjoint(dopri)
+
+	invalid := []*AdjointConfig{
+		nil,
+		{Parameters: []float64{1}, Objective: objective},
+		{Parametric: decay, Objective: objective},
+		{Parametric: decay, Parameters: []float64{1}},
+	}
+	for k, config := range invalid {
+		if _, err := a.Gradient(0, 1, []float64{1}, make([]float64, 1), config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Setup %d: expected configuration error, got %v", k, err)
+		}
+	}
+
+	// the base integrator has to provide dense output
+	base, _ := adams.NewAdams(adams.PECE)
+	if _, err := NewAdjoint(base); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error without dense output, got %v", err)
+	}
+}
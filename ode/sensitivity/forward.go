@@ -0,0 +1,65 @@
+package sensitivity
+
+import . "github.com/rollingthunder/differential/ode"
+
+type forward struct {
+	IntegratorInfo
+	base Integrator
+}
+
+// NewForward creates an integrator for forward sensitivities, which solves the system and its
+// sensitivity equations as one augmented system with the base integrator
+func NewForward(base Integrator) (i SensitivityIntegrator, err error) {
+	var f forward
+	if base == nil {
+		err = &ConfigError{Field: "Integrator", Reason: "no integrator specified"}
+	} else {
+		info := base.Info()
+		f.base = base
+		f.Name = "Forward-" + info.Name
+		f.Stages, f.Order = info.Stages, info.Order
+	}
+
+	i = &f
+	return
+}
+
+// integrates the augmented system, the statistics refer to it as a whole
+func (f *forward) IntegrateSensitivities(t, tEnd float64, yT []float64, sensitivity [][]float64, config *SensitivityConfig) (stat Statistics, err error) {
+	n := len(yT)
+
+	effective, err := config.ValidateAndPrepare(uint(n), t, tEnd)
+	if err != nil {
+		return
+	}
+	parameters := len(effective.Parameters)
+	if len(sensitivity) != n {
+		err = &ConfigError{Field: "sensitivity", Reason: "one row per component needed"}
+		return
+	}
+	for _, row := range sensitivity {
+		if len(row) != parameters {
+			err = &ConfigError{Field: "sensitivity", Reason: "one column per parameter needed"}
+			return
+		}
+	}
+
+	// the columns of the sensitivity matrix follow the solution
+	augmented := make([]float64, n*(1+parameters))
+	copy(augmented, yT)
+	for i, row := range sensitivity {
+		for j, s := range row {
+			augmented[(j+1)*n+i] = s
+		}
+	}
+
+	stat, err = f.base.Integrate(t, tEnd, augmented, &effective.Config)
+
+	copy(yT, augmented[:n])
+	for i, row := range sensitivity {
+		for j := range row {
+			row[j] = augmented[(j+1)*n+i]
+		}
+	}
+	return
+}
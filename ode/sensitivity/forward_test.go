@@ -0,0 +1,191 @@
+package sensitivity
+
+import (
+	"errors"
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/ode/sdirk"
+	"github.com/rollingthunder/differential/problems"
+	"github.com/rollingthunder/differential/util"
+	"math"
+	"testing"
+)
+
+func decay(t float64, y, p []float64, dy []float64) {
+	dy[0] = -p[0] * y[0]
+}
+
+func TestForwardDecay(t *testing.T) {
+	// y' = -k y with y(0) = y0, the sensitivities are dy/dk = -t y and dy/dy0 = exp(-k t)
+	// for the initial value as a parameter p[1], with s(0) = (0, 1)
+	const k, tEnd = 2.0, 1.5
+	analytic := func(t float64, y, p []float64, s [][]float64, ds [][]float64) {
+		ds[0][0] = -p[0]*s[0][0] - y[0]
+		ds[1][0] = -p[0] * s[1][0]
+	}
+
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	f, err := NewForward(dopri)
+	if err != nil {
+		t.Fatalf("Couldn't create forward sensitivity integrator - %s", err.Error())
+	}
+
+	for _, sensitivity := range []SensitivityFunction{analytic, nil} {
+		for _, errorControl := range []bool{false, true} {
+			config := SensitivityConfig{
+				Config:       Config{AbsoluteTolerance: 1e-9},
+				Parametric:   decay,
+				Parameters:   []float64{k, 3.0},
+				Sensitivity:  sensitivity,
+				ErrorControl: errorControl,
+			}
+			y := []float64{3.0}
+			s := [][]float64{{0.0, 1.0}}
+			if _, err := f.IntegrateSensitivities(0, tEnd, y, s, &config); err != nil {
+				t.Fatalf("%s: Integration failed - %s", f.Info().Name, err.Error())
+			}
+
+			exact := 3.0 * math.Exp(-k*tEnd)
+			if math.Abs(y[0]-exact) > 1e-8 {
+				t.Errorf("%s: solution %g differs from %g", f.Info().Name, y[0], exact)
+			}
+			if math.Abs(s[0][0]+tEnd*exact) > 1e-6 || math.Abs(s[0][1]-math.Exp(-k*tEnd)) > 1e-6 {
+				t.Errorf("%s (analytic %t, error control %t): sensitivities %v differ from %g, %g",
+					f.Info().Name, sensitivity != nil, errorControl, s[0], -tEnd*exact, math.Exp(-k*tEnd))
+			}
+		}
+	}
+}
+
+func TestForwardBrusselator(t *testing.T) {
+	// compare with central differences of solutions for perturbed parameters
+	b := problems.NewBruss2D(4).(problems.ParametricProblem)
+	p := b.Parameters()
+	const tEnd = 1.0
+
+	integrators := make([]Integrator, 2)
+	integrators[0], _ = rk.NewRK(rk.DoPri5)
+	integrators[1], _ = sdirk.NewSDIRK(sdirk.TRBDF2)
+
+	for _, integrator := range integrators {
+		f, _ := NewForward(integrator)
+		config := SensitivityConfig{
+			Config:     Config{AbsoluteTolerance: 1e-8},
+			Parametric: b.FcnParameters,
+			Parameters: p,
+		}
+		y := b.Initialize()
+		s := util.MakeRectangular(uint(len(y)), uint(len(p)))
+		stat, err := f.IntegrateSensitivities(0, tEnd, y, s, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", f.Info().Name, err.Error())
+		}
+
+		for j := range p {
+			delta := 1e-4 * p[j]
+			solutions := make([][]float64, 2)
+			for k, sign := range []float64{1, -1} {
+				perturbed := append([]float64(nil), p...)
+				perturbed[j] += sign * delta
+				solutions[k] = b.Initialize()
+				fcn := func(t float64, y []float64, dy []float64) { b.FcnParameters(t, y, perturbed, dy) }
+				if _, err := integrator.Integrate(0, tEnd, solutions[k], &Config{Fcn: fcn, AbsoluteTolerance: 1e-11}); err != nil {
+					t.Fatalf("%s: Integration failed - %s", integrator.Info().Name, err.Error())
+				}
+			}
+			maxError := 0.0
+			for i := range y {
+				difference := (solutions[0][i] - solutions[1][i]) / (2 * delta)
+				maxError = math.Max(maxError, math.Abs(s[i][j]-difference)/(1.0+math.Abs(difference)))
+			}
+			if maxError > 1e-4 {
+				t.Errorf("%s: sensitivities for parameter %d differ by %g", f.Info().Name, j, maxError)
+			}
+		}
+		if testing.Verbose() {
+			t.Logf("%s: %d steps, %d evaluations", f.Info().Name, stat.StepCount, stat.EvaluationCount)
+		}
+	}
+}
+
+func TestForwardErrorControl(t *testing.T) {
+	// the sensitivity grows like t exp(-k t) and needs more steps than the solution for small k t
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	f, _ := NewForward(dopri)
+
+	steps := make([]uint, 2)
+	for k, errorControl := range []bool{false, true} {
+		config := SensitivityConfig{
+			Config:       Config{AbsoluteTolerance: 1e-6},
+			Parametric:   decay,
+			Parameters:   []float64{0.5},
+			ErrorControl: errorControl,
+		}
+		stat, err := f.IntegrateSensitivities(0, 10, []float64{1.0}, [][]float64{{0.0}}, &config)
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", f.Info().Name, err.Error())
+		}
+		steps[k] = stat.StepCount
+	}
+	if steps[1] <= steps[0] {
+		t.Errorf("%s: %d steps with error control of the sensitivities, %d without", f.Info().Name, steps[1], steps[0])
+	}
+}
+
+func TestForwardConfig(t *testing.T) {
+	dopri, _ := rk.NewRK(rk.DoPri5)
+	f, _ := NewForward(dopri)
+
+	invalid := []struct {
+		s      [][]float64
+		config *SensitivityConfig
+	}{
+		{[][]float64{{0}}, nil},
+		{[][]float64{{0}}, &SensitivityConfig{Parameters: []float64{1}}},
+		{[][]float64{{0}}, &SensitivityConfig{Parametric: decay}},
+		{[][]float64{{0, 0}}, &SensitivityConfig{Parametric: decay, Parameters: []float64{1}}},
+		{[][]float64{}, &SensitivityConfig{Parametric: decay, Parameters: []float64{1}}},
+	}
+	for k, test := range invalid {
+		if _, err := f.IntegrateSensitivities(0, 1, []float64{1}, test.s, test.config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Setup %d: expected configuration error, got %v", k, err)
+		}
+	}
+	if _, err := NewForward(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected configuration error without base integrator, got %v", err)
+	}
+}
+
+func TestForwardErrorComponents(t *testing.T) {
+	// without error control the error estimate is restricted to the system, unless set explicitly
+	for _, test := range []struct{ errorComponents, expected uint }{{0, 1}, {2, 2}} {
+		config := SensitivityConfig{Parametric: decay, Parameters: []float64{2.0, 3.0}}
+		config.ErrorComponents = test.errorComponents
+		effective, err := config.ValidateAndPrepare(1, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if effective.ErrorComponents != test.expected {
+			t.Errorf("ErrorComponents %d became %d instead of %d", test.errorComponents, effective.ErrorComponents, test.expected)
+		}
+	}
+}
+
+func TestForwardAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skipf("Skipping because the race detector drops pooled buffers.")
+	}
+	// the buffers of the augmented system are allocated once per integration
+	for _, sensitivity := range []SensitivityFunction{nil, func(t float64, y, p []float64, s [][]float64, ds [][]float64) {}} {
+		config := SensitivityConfig{Parametric: decay, Parameters: []float64{2.0, 3.0}, Sensitivity: sensitivity}
+		effective, err := config.ValidateAndPrepare(1, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, dy := []float64{1.0, 0.5, 1.0}, make([]float64, 3)
+		fcn := effective.Fcn
+		if allocs := testing.AllocsPerRun(100, func() { fcn(0, y, dy) }); allocs != 0 {
+			t.Errorf("%g allocations per evaluation", allocs)
+		}
+	}
+}
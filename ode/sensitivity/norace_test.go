@@ -0,0 +1,5 @@
+//go:build !race
+
+package sensitivity
+
+const raceEnabled = false
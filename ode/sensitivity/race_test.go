@@ -0,0 +1,6 @@
+//go:build race
+
+package sensitivity
+
+// the race detector drops items of a sync.Pool at random
+const raceEnabled = true
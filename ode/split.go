@@ -0,0 +1,96 @@
+package ode
+
+import "sync"
+
+// SplitConfig configures the integration of yT' = Explicit(t, yT) + Implicit(t, yT),
+// where Implicit holds the stiff part of the right hand side.
+// Fcn and FcnBlocked of the embedded Config are ignored, its Jacobian settings
+// refer to the whole right hand side
+type SplitConfig struct {
+	Config
+
+	Explicit, Implicit Function
+
+	// ImplicitJacobian, ImplicitSparsity and ImplicitSparseJacobian are the
+	// Jacobian settings for the Implicit part, see Config
+	ImplicitJacobian       JacobianFunction
+	ImplicitSparsity       *Sparsity
+	ImplicitSparseJacobian SparseJacobianFunction
+
+	// prepared configuration of the Implicit part
+	implicit Config
+}
+
+// SplitIntegrator integrates a right hand side split into a non-stiff and a stiff part
+type SplitIntegrator interface {
+	Info() IntegratorInfo
+	IntegrateSplit(t, tEnd float64, yT []float64, config *SplitConfig) (stat Statistics, err error)
+}
+
+// Sum returns the whole right hand side Explicit + Implicit, it may be evaluated concurrently
+func (c *SplitConfig) Sum(n int) Function {
+	explicit, implicit := c.Explicit, c.Implicit
+	temps := sync.Pool{New: func() interface{} { return make([]float64, n) }}
+	return func(t float64, yT []float64, dy_out []float64) {
+		temp := temps.Get().([]float64)
+		explicit(t, yT, dy_out)
+		implicit(t, yT, temp)
+		for id := range dy_out {
+			dy_out[id] += temp[id]
+		}
+		temps.Put(temp)
+	}
+}
+
+// ValidateAndPrepare checks the configuration for a system with n components and returns
+// the effective configuration, its Fcn is the whole right hand side
+func (c *SplitConfig) ValidateAndPrepare(n uint, t, tEnd float64) (e SplitConfig, err error) {
+	if c == nil {
+		err = &ConfigError{Reason: "nil configuration"}
+		return
+	}
+	if c.Explicit == nil || c.Implicit == nil {
+		err = &ConfigError{Field: "Explicit", Reason: "explicit and implicit part have to be specified"}
+		return
+	}
+
+	whole := c.Config
+	whole.Fcn, whole.FcnBlocked = c.Sum(int(n)), nil
+	if e.Config, err = whole.ValidateAndPrepare(n, t, tEnd); err != nil {
+		return
+	}
+
+	implicit := e.Config
+	implicit.Fcn, implicit.FcnBlocked = c.Implicit, nil
+	implicit.Jacobian, implicit.Sparsity, implicit.SparseJacobian = c.ImplicitJacobian, c.ImplicitSparsity, c.ImplicitSparseJacobian
+	implicit.JacobianVector, implicit.Preconditioner = nil, nil
+	if e.implicit, err = implicit.ValidateAndPrepare(n, t, tEnd); err != nil {
+		if configErr, ok := err.(*ConfigError); ok && configErr.Field != "" {
+			configErr.Field = "Implicit" + configErr.Field
+		}
+		return
+	}
+
+	e.Explicit, e.Implicit = c.Explicit, c.Implicit
+	e.ImplicitJacobian, e.ImplicitSparsity, e.ImplicitSparseJacobian = c.ImplicitJacobian, c.ImplicitSparsity, c.ImplicitSparseJacobian
+	return
+}
+
+// ImplicitConfig returns the configuration of the Implicit part, with the tolerances of the
+// whole system. It is only valid for configurations returned by ValidateAndPrepare
+func (c *SplitConfig) ImplicitConfig() *Config {
+	return &c.implicit
+}
+
+// SplitAdapter integrates split systems as a whole with an Integrator
+type SplitAdapter struct {
+	Integrator
+}
+
+func (a SplitAdapter) IntegrateSplit(t, tEnd float64, yT []float64, config *SplitConfig) (stat Statistics, err error) {
+	effective, err := config.ValidateAndPrepare(uint(len(yT)), t, tEnd)
+	if err != nil {
+		return
+	}
+	return a.Integrate(t, tEnd, yT, &effective.Config)
+}
@@ -0,0 +1,76 @@
+package ode
+
+import "math"
+
+// Starter computes the starting values that multistep and peer methods
+// need before they can perform their first regular integration step
+type Starter interface {
+	// Start computes the solution at each of the given times (all >= t)
+	// from the initial value yT at t and stores it in the corresponding row of values
+	Start(t float64, yT []float64, times []float64, values [][]float64, c *Config) (stat Statistics, err error)
+}
+
+// IntegratorStarter computes starting values using an arbitrary Integrator,
+// each value is obtained by integrating from the initial value to the requested time
+type IntegratorStarter struct {
+	Integrator Integrator
+
+	// ToleranceFactor scales the tolerances of the calling integration
+	// to get a higher accuracy for the starting values
+	// If <= 0.0, 0.1 is used
+	ToleranceFactor float64
+}
+
+func (s *IntegratorStarter) Start(t float64, yT []float64, times []float64, values [][]float64, c *Config) (stat Statistics, err error) {
+	factor := s.ToleranceFactor
+	if factor <= 0.0 {
+		factor = 1e-1
+	}
+
+	for i, tValue := range times {
+		copy(values[i], yT)
+		if tValue == t {
+			continue
+		}
+
+		startConfig := Config{
+			InitialStepSize:   tValue - t,
+			MinStepSize:       c.MinStepSize,
+			MaxStepSize:       c.MaxStepSize,
+			RelativeTolerance: math.Max(factor*c.RelativeTolerance, 1e-14),
+			AbsoluteTolerance: math.Max(factor*c.AbsoluteTolerance, 1e-14),
+			MaxStepCount:      c.MaxStepCount,
+			BlockSize:         c.BlockSize,
+			NonFinitePolicy:   c.NonFinitePolicy,
+			Cancel:            c.Cancel,
+			Fcn:               c.Fcn,
+			FcnBlocked:        c.FcnBlocked,
+		}
+		if c.MaxStepSize > 0.0 {
+			startConfig.InitialStepSize = math.Min(startConfig.InitialStepSize, c.MaxStepSize)
+		}
+
+		var startStat Statistics
+		startStat, err = s.Integrator.Integrate(t, tValue, values[i], &startConfig)
+		stat.StepCount += startStat.StepCount
+		stat.RejectedCount += startStat.RejectedCount
+		stat.EvaluationCount += startStat.EvaluationCount
+		if err != nil {
+			return
+		}
+	}
+	stat.CurrentTime = t
+	return
+}
+
+// HistoryStarter supplies known solution values as starting values,
+// e.g. an analytical solution or the dense output of a previous integration
+type HistoryStarter func(t float64, y_out []float64)
+
+func (h HistoryStarter) Start(t float64, yT []float64, times []float64, values [][]float64, c *Config) (stat Statistics, err error) {
+	for i, tValue := range times {
+		h(tValue, values[i])
+	}
+	stat.CurrentTime = t
+	return
+}
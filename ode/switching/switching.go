@@ -0,0 +1,142 @@
+package switching
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+)
+
+type switching struct {
+	IntegratorInfo
+	method SwitchingMethod
+
+	explicit, implicit Integrator
+	// h * spectral radius the explicit method can handle
+	stabilityBoundary float64
+}
+
+type integration struct {
+	Config
+	Statistics
+	n uint
+
+	stiff bool
+	// consecutive checks suggesting to switch
+	switchVotes int
+
+	f0, eigenvector []float64
+}
+
+// integrates with an explicit method as long as the problem is not stiff and switches
+// to an implicit method when the step size of the explicit one is limited by stability
+func (s *switching) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	var n uint = uint(len(yT))
+
+	effective, err := config.ValidateAndPrepare(n, t, tEnd)
+	if err != nil {
+		return
+	}
+	if err = s.Supports(&effective); err != nil {
+		return
+	}
+
+	in := s.setupIntegration(yT, &effective)
+
+	// the methods integrate segments of checkInterval steps with the effective configuration,
+	// so they keep their state, e.g. the derivative and the Jacobian, within a segment
+	inner := in.Config
+	inner.Starter = nil
+
+	var stepNext, stepEstimate float64 = 0.0, in.InitialStepSize
+
+	// repeat until tend
+	for t < tEnd {
+		if in.Cancelled() {
+			err = &CancelledError{Time: t}
+			break
+		}
+
+		method := s.explicit
+		if in.stiff {
+			method = s.implicit
+		}
+
+		// the segment ends when the method exceeds its step count
+		inner.InitialStepSize = stepEstimate
+		inner.MaxStepCount = checkInterval
+		if remaining := in.MaxStepCount - in.StepCount; remaining < checkInterval {
+			inner.MaxStepCount = remaining
+		}
+		if inner.MaxStepCount == 0 {
+			inner.MaxStepCount = 1
+		}
+		var segmentStat Statistics
+		segmentStat, err = method.Integrate(t, tEnd, yT, &inner)
+		in.Statistics.Add(segmentStat)
+		if in.stiff {
+			in.StiffStepCount += segmentStat.StepCount
+		}
+		if _, segmentEnd := err.(*MaxStepsError); segmentEnd {
+			err = nil
+		}
+		if err != nil {
+			break
+		}
+
+		t = segmentStat.CurrentTime
+		stepNext, stepEstimate = segmentStat.LastStepSize, segmentStat.NextStepSize
+
+		// cancel after first step
+		if in.OneStepOnly {
+			break
+		}
+
+		// failure, too many steps
+		if in.StepCount > in.MaxStepCount {
+			err = &MaxStepsError{Time: t, StepCount: in.StepCount}
+			break
+		}
+
+		if t < tEnd {
+			s.checkStiffness(&in, t, stepEstimate, yT)
+		}
+	}
+
+	in.CurrentTime = t
+	in.LastStepSize = stepNext
+	in.NextStepSize = stepEstimate
+	in.Effective = in.Config
+
+	stat = in.Statistics
+	return
+}
+
+func (s *switching) setupIntegration(yT []float64, c *Config) (i integration) {
+	i.n = uint(len(yT))
+	i.Config = *c
+
+	// allocate temp matrices
+	i.f0 = make([]float64, i.n)
+	i.eigenvector = make([]float64, i.n)
+
+	return
+}
+
+// compares the step size to the stability boundary of the explicit method and
+// switches methods if the outcome was the same for several checks in a row
+func (s *switching) checkStiffness(in *integration, t, step float64, yT []float64) {
+	in.EvaluateBlocked(t, yT, in.f0)
+	radius, evaluations := in.SpectralRadius(t, yT, in.f0, in.eigenvector)
+	in.EvaluationCount += evaluations + 1
+
+	ratio := step * radius / s.stabilityBoundary
+	if (!in.stiff && ratio > stiffRatio) || (in.stiff && ratio < nonStiffRatio) {
+		in.switchVotes++
+	} else {
+		in.switchVotes = 0
+	}
+
+	if in.switchVotes >= switchThreshold {
+		in.stiff = !in.stiff
+		in.switchVotes = 0
+		in.SwitchCount++
+	}
+}
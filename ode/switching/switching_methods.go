@@ -0,0 +1,53 @@
+package switching
+
+import (
+	"github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	"github.com/rollingthunder/differential/ode/rosenbrock"
+)
+
+type SwitchingMethod uint
+
+const (
+	DoPri5ROS2               = SwitchingMethod(iota) // DoPri5 for non-stiff parts, ROS2 for stiff parts
+	NumberOfSwitchingMethods = uint(iota)
+)
+
+const (
+	// steps of the method between two stiffness checks
+	checkInterval = 5
+	// consecutive checks with the same outcome needed to switch
+	switchThreshold = 3
+	// h * spectral radius relative to the stability boundary above which
+	// the step size of the explicit method is considered limited by stability
+	stiffRatio = 0.8
+	// ... and below which the explicit method could take the step of the implicit one
+	nonStiffRatio = 0.25
+)
+
+func NewSwitching(m SwitchingMethod) (i ode.Integrator, err error) {
+	var s switching
+	s.method = m
+
+	switch m {
+	case DoPri5ROS2:
+		s.Name = "DoPri5ROS2"
+		if s.explicit, err = rk.NewRK(rk.DoPri5); err != nil {
+			break
+		}
+		if s.implicit, err = rosenbrock.NewRosenbrock(rosenbrock.ROS2); err != nil {
+			break
+		}
+		// intersection of the stability region of DoPri5 with the negative real axis
+		s.stabilityBoundary = 3.3
+	default:
+		err = &ode.ConfigError{Field: "SwitchingMethod", Reason: "unknown switching method"}
+	}
+	if err == nil {
+		s.Stages = s.explicit.Info().Stages
+		s.Order = s.implicit.Info().Order
+	}
+
+	i = &s
+	return
+}
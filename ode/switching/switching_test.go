@@ -0,0 +1,106 @@
+package switching
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/ode/rk"
+	. "github.com/rollingthunder/differential/ode/testing"
+	"github.com/rollingthunder/differential/problems"
+	"reflect"
+	"testing"
+)
+
+func TestAllSwitching(t *testing.T) {
+	integrators := make([]Integrator, NumberOfSwitchingMethods)
+	for j := 0; j < int(NumberOfSwitchingMethods); j++ {
+		s, err := NewSwitching(SwitchingMethod(j))
+		if err != nil {
+			t.Errorf("Couldn't create Switching Method %d: %s", j, err.Error())
+		} else {
+			integrators[j] = s
+		}
+	}
+
+	RunIntegratorTests(t, integrators, 1)
+}
+
+func TestSwitchToImplicit(t *testing.T) {
+	for j := 0; j < int(NumberOfSwitchingMethods); j++ {
+		s, _ := NewSwitching(SwitchingMethod(j))
+		dopri, _ := rk.NewRK(rk.DoPri5)
+
+		stat := IntegrateStiffCosine(t, s, Config{AbsoluteTolerance: 1e-5})
+		statDopri, err := dopri.Integrate(0, 10, []float64{0}, &Config{Fcn: StiffCosine, AbsoluteTolerance: 1e-5})
+		if err != nil {
+			t.Fatalf("DoPri5: Integration failed - %s", err.Error())
+		}
+
+		if stat.SwitchCount == 0 || stat.StiffStepCount == 0 {
+			t.Errorf("%s: no switch to the implicit method", s.Info().Name)
+		}
+		if stat.EvaluationCount >= statDopri.EvaluationCount {
+			t.Errorf("%s: %d evaluations, DoPri5 alone needed %d", s.Info().Name, stat.EvaluationCount, statDopri.EvaluationCount)
+		}
+
+		if testing.Verbose() {
+			t.Logf("%s: %d steps (%d stiff), %d switches, %d evaluations, DoPri5: %d steps, %d evaluations",
+				s.Info().Name, stat.StepCount, stat.StiffStepCount, stat.SwitchCount, stat.EvaluationCount,
+				statDopri.StepCount, statDopri.EvaluationCount)
+		}
+	}
+}
+
+func TestNoSwitchNonStiff(t *testing.T) {
+	mbody := problems.NewMBody(4)
+
+	for j := 0; j < int(NumberOfSwitchingMethods); j++ {
+		s, _ := NewSwitching(SwitchingMethod(j))
+		y := mbody.Initialize()
+
+		stat, err := s.Integrate(0, 5, y, &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-8})
+		if err != nil {
+			t.Fatalf("%s: Integration failed - %s", s.Info().Name, err.Error())
+		}
+		if stat.SwitchCount != 0 || stat.StiffStepCount != 0 {
+			t.Errorf("%s: %d switches, %d stiff steps on a non-stiff problem", s.Info().Name, stat.SwitchCount, stat.StiffStepCount)
+		}
+
+		// the explicit method keeps its state across the steps of a segment and takes the steps of DoPri5 alone
+		dopri, _ := rk.NewRK(rk.DoPri5)
+		statDopri, _ := dopri.Integrate(0, 5, mbody.Initialize(), &Config{Fcn: mbody.Fcn, AbsoluteTolerance: 1e-8})
+		if stat.StepCount != statDopri.StepCount {
+			t.Errorf("%s: %d steps, DoPri5 alone took %d", s.Info().Name, stat.StepCount, statDopri.StepCount)
+		}
+	}
+}
+
+// counting reaches tEnd in one step and reports one of every count
+type counting struct{}
+
+func (counting) Info() IntegratorInfo { return IntegratorInfo{Name: "Counting"} }
+
+func (counting) Integrate(t, tEnd float64, yT []float64, config *Config) (stat Statistics, err error) {
+	counts := reflect.ValueOf(&stat).Elem()
+	for i := 0; i < counts.NumField(); i++ {
+		if counts.Field(i).Kind() == reflect.Uint {
+			counts.Field(i).SetUint(1)
+		}
+	}
+	stat.CurrentTime = tEnd
+	return
+}
+
+func TestSwitchingStatistics(t *testing.T) {
+	s := &switching{IntegratorInfo: IntegratorInfo{Name: "Counting"}, explicit: counting{}, implicit: counting{}, stabilityBoundary: 1.0}
+	stat, err := s.Integrate(0, 1, []float64{0}, &Config{Fcn: StiffCosine})
+	if err != nil {
+		t.Fatalf("Integration failed - %s", err.Error())
+	}
+
+	// every count of the inner integration is passed on
+	counts := reflect.ValueOf(stat)
+	for i := 0; i < counts.NumField(); i++ {
+		if counts.Field(i).Kind() == reflect.Uint && counts.Field(i).Uint() == 0 {
+			t.Errorf("%s of the inner integration was dropped", counts.Type().Field(i).Name)
+		}
+	}
+}
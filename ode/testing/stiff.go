@@ -0,0 +1,97 @@
+package testing
+
+import (
+	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
+	"math"
+	"testing"
+)
+
+// ReferenceTolerance is the deviation from the reference solution of the Brusselator
+// expected of the stiff integrators at tolerances around 1e-5
+const ReferenceTolerance = 2e-3
+
+// Reference integrates problem over [0, tEnd] with tolerance 1e-8 for a reference solution.
+// The integrator is passed in, usually DoPri5, as package rk imports this package
+func Reference(t *testing.T, explicit Integrator, problem problems.Problem, tEnd float64) ([]float64, Statistics) {
+	t.Helper()
+	reference := problem.Initialize()
+	stat, err := explicit.Integrate(0, tEnd, reference, &Config{Fcn: problem.Fcn, AbsoluteTolerance: 1e-8})
+	if err != nil {
+		t.Fatalf("Reference integration failed - %s", err.Error())
+	}
+	return reference, stat
+}
+
+// CheckReference stops the test if a component of the result y of the method name
+// differs from the reference by more than tolerance
+func CheckReference(t *testing.T, name string, y, reference []float64, tolerance float64) {
+	t.Helper()
+	for i := range y {
+		if math.Abs(y[i]-reference[i]) > tolerance {
+			t.Fatalf("%s: result[%d] = %g differs from reference %g", name, i, y[i], reference[i])
+		}
+	}
+}
+
+// Robertson is the stiff chemical reaction of Robertson, starting at y = (1, 0, 0)
+func Robertson(t float64, y []float64, dy_out []float64) {
+	dy_out[0] = -0.04*y[0] + 1.0e4*y[1]*y[2]
+	dy_out[1] = 0.04*y[0] - 1.0e4*y[1]*y[2] - 3.0e7*y[1]*y[1]
+	dy_out[2] = 3.0e7 * y[1] * y[1]
+}
+
+// RobertsonReference is the solution of Robertson at t = 40
+var RobertsonReference = []float64{0.7158271, 9.185535e-6, 0.2841637}
+
+// CheckRelative fails the test if a component of the result y of the method name
+// differs from the reference by more than tolerance relative to the reference
+func CheckRelative(t *testing.T, name string, y, reference []float64, tolerance float64) {
+	t.Helper()
+	for i := range y {
+		if math.Abs(y[i]-reference[i]) > tolerance*math.Abs(reference[i]) {
+			t.Errorf("%s: y[%d] = %g, expected %g", name, i, y[i], reference[i])
+		}
+	}
+}
+
+// CheckStability fails the test if the method name needed more than maxSteps steps
+// for a stiff problem, i.e. if its step sizes were limited by stability instead of accuracy
+func CheckStability(t *testing.T, name string, stat Statistics, maxSteps uint) {
+	t.Helper()
+	if stat.StepCount > maxSteps {
+		t.Errorf("%s: %d steps, the problem should not be limited by stability", name, stat.StepCount)
+	}
+}
+
+// StiffCosine is y' = -1e4 (y - cos(t)), which follows cos(t) closely after a short transient.
+// Explicit methods are limited by stability from there on and need more than 1e4 steps on [0, 10]
+func StiffCosine(t float64, y []float64, dy_out []float64) {
+	dy_out[0] = -StiffCosineRate * (y[0] - math.Cos(t))
+}
+
+// StiffCosineRate is the negative eigenvalue of the Jacobian of StiffCosine
+const StiffCosineRate = 1.0e4
+
+// StiffCosineSolution is the solution of StiffCosine with y(0) = 0
+func StiffCosineSolution(t float64) float64 {
+	a := StiffCosineRate / (StiffCosineRate*StiffCosineRate + 1.0)
+	return a*(StiffCosineRate*math.Cos(t)+math.Sin(t)) - a*StiffCosineRate*math.Exp(-StiffCosineRate*t)
+}
+
+// IntegrateStiffCosine integrates StiffCosine from y(0) = 0 to t = 10 with the settings of config,
+// whose Fcn is replaced, and checks the result to 1e-4 and that at most 2000 steps were needed
+func IntegrateStiffCosine(t *testing.T, m Integrator, config Config) Statistics {
+	t.Helper()
+	y := []float64{0}
+	config.Fcn, config.FcnBlocked = StiffCosine, nil
+	stat, err := m.Integrate(0, 10, y, &config)
+	if err != nil {
+		t.Fatalf("%s: Integration failed - %s", m.Info().Name, err.Error())
+	}
+	if exact := StiffCosineSolution(10); math.Abs(y[0]-exact) > 1e-4 {
+		t.Errorf("%s: result %g differs from solution %g", m.Info().Name, y[0], exact)
+	}
+	CheckStability(t, m.Info().Name, stat, 2000)
+	return stat
+}
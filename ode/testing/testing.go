@@ -2,6 +2,7 @@ package testing
 
 import (
 	. "github.com/rollingthunder/differential/ode"
+	"github.com/rollingthunder/differential/problems"
 	"github.com/rollingthunder/differential/util"
 	"math"
 	"testing"
@@ -93,3 +94,42 @@ func RunIntegratorTests(t *testing.T, methods []Integrator, iterations int) {
 		}
 	}
 }
+
+// RunAccuracyTests integrates the problems of the embedded reference solutions with the given
+// tolerance and checks that the error relative to max(1, |reference|) is at most maxError
+func RunAccuracyTests(t *testing.T, methods []Integrator, tolerance, maxError float64) {
+	for _, name := range problems.ReferenceNames() {
+		ref, err := problems.LoadReference(name)
+		if err != nil {
+			t.Fatalf("Couldn't load reference %s: %s", name, err.Error())
+		}
+		p, err := problems.NewReferenceProblem(ref.Problem, ref.Size)
+		if err != nil {
+			t.Fatalf("Couldn't create problem of reference %s: %s", name, err.Error())
+		}
+		expected := ref.Float64()
+
+		for _, m := range methods {
+			if m == nil {
+				continue
+			}
+			y := p.Initialize()
+			stat, err := m.Integrate(ref.T0, ref.T1, y, &Config{Fcn: p.Fcn, AbsoluteTolerance: tolerance, RelativeTolerance: tolerance})
+			if err != nil {
+				t.Errorf("%s on %s: %s", m.Info().Name, name, err.Error())
+				continue
+			}
+
+			maxErr := 0.0
+			for i := range y {
+				maxErr = math.Max(maxErr, math.Abs(y[i]-expected[i])/math.Max(1.0, math.Abs(expected[i])))
+			}
+			if !(maxErr <= maxError) {
+				t.Errorf("%s on %s: error %g exceeds %g", m.Info().Name, name, maxErr, maxError)
+			}
+			if testing.Verbose() {
+				t.Logf("%s\t%s\terror %g\tsteps %d\tevaluations %d", m.Info().Name, name, maxErr, stat.StepCount, stat.EvaluationCount)
+			}
+		}
+	}
+}
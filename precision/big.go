@@ -0,0 +1,297 @@
+package precision
+
+import (
+	"math"
+	"math/big"
+	"sync"
+)
+
+// DefaultBigPrecision is the precision in bits of Big values without precision, e.g. the zero value
+const DefaultBigPrecision = 256
+
+// guard bits of the series of the elementary functions of Big
+const bigGuardBits = 64
+
+// Big is an arbitrary precision number based on big.Float. Values are immutable,
+// operations allocate their result with the larger precision of the operands,
+// the zero value takes the precision of the other operand.
+// As in math/big, operations whose result would be NaN panic with big.ErrNaN
+type Big struct {
+	f *big.Float
+}
+
+var bigZero = new(big.Float)
+
+// NewBig converts x exactly to a number with the given precision in bits
+func NewBig(x float64, precision uint) Big {
+	return Big{new(big.Float).SetPrec(precision).SetFloat64(x)}
+}
+
+// BigFromFloat copies f, the number has the precision of f
+func BigFromFloat(f *big.Float) Big {
+	return Big{new(big.Float).Copy(f)}
+}
+
+// Float returns a copy of x as big.Float
+func (x Big) Float() *big.Float {
+	return new(big.Float).SetPrec(x.Precision()).Set(x.value())
+}
+
+func (x Big) value() *big.Float {
+	if x.f == nil {
+		return bigZero
+	}
+	return x.f
+}
+
+func (x Big) Precision() uint {
+	if x.f == nil || x.f.Prec() == 0 {
+		return DefaultBigPrecision
+	}
+	return x.f.Prec()
+}
+
+// precision of the result of an operation on x and y
+func (x Big) precision(y Big) uint {
+	if x.f == nil {
+		return y.Precision()
+	} else if y.f == nil {
+		return x.Precision()
+	}
+	if px, py := x.Precision(), y.Precision(); px < py {
+		return py
+	}
+	return x.Precision()
+}
+
+func (x Big) result() *big.Float {
+	return new(big.Float).SetPrec(x.Precision())
+}
+
+func (x Big) Add(y Big) Big {
+	return Big{new(big.Float).SetPrec(x.precision(y)).Add(x.value(), y.value())}
+}
+
+func (x Big) Sub(y Big) Big {
+	return Big{new(big.Float).SetPrec(x.precision(y)).Sub(x.value(), y.value())}
+}
+
+func (x Big) Mul(y Big) Big {
+	return Big{new(big.Float).SetPrec(x.precision(y)).Mul(x.value(), y.value())}
+}
+
+func (x Big) Div(y Big) Big {
+	return Big{new(big.Float).SetPrec(x.precision(y)).Quo(x.value(), y.value())}
+}
+
+func (x Big) Neg() Big { return Big{x.result().Neg(x.value())} }
+
+func (x Big) Scale(c float64) Big {
+	return Big{x.result().Mul(x.value(), new(big.Float).SetFloat64(c))}
+}
+
+func (x Big) Sqrt() Big { return Big{x.result().Sqrt(x.value())} }
+
+// halvings of the reduced argument of Exp
+const bigHalvings = 10
+
+// ln 2 at the highest precision computed so far
+var bigLn2Cache struct {
+	sync.Mutex
+	f *big.Float
+}
+
+// bigLn2 returns ln 2 = 2 atanh(1/3) = 2 sum 1 / ((2j + 1) 3^(2j + 1)) with the given precision
+func bigLn2(precision uint) *big.Float {
+	bigLn2Cache.Lock()
+	defer bigLn2Cache.Unlock()
+	if f := bigLn2Cache.f; f != nil && f.Prec() >= precision {
+		return new(big.Float).SetPrec(precision).Set(f)
+	}
+
+	w := precision + bigGuardBits
+	power := new(big.Float).SetPrec(w).SetInt64(1)
+	power.Quo(power, new(big.Float).SetPrec(w).SetInt64(3))
+	ninth := new(big.Float).SetPrec(w).SetInt64(9)
+	sum, term := new(big.Float).SetPrec(w), new(big.Float).SetPrec(w)
+	divisor := new(big.Float).SetPrec(w)
+	for j := int64(0); j == 0 || !negligible(term, sum, w); j++ {
+		term.Quo(power, divisor.SetInt64(2*j+1))
+		sum.Add(sum, term)
+		power.Quo(power, ninth)
+	}
+	bigLn2Cache.f = sum.SetMantExp(sum, 1)
+	return new(big.Float).SetPrec(precision).Set(sum)
+}
+
+// Exp reduces the argument to |r| <= ln(2) / 2 by x = k ln(2) + r and further to
+// r 2^-10, sums the Taylor series of exp(r 2^-10) - 1, squares ten times and scales by 2^k
+func (x Big) Exp() Big {
+	v := x.value()
+	switch {
+	case v.IsInf() && v.Signbit():
+		return Big{x.result()}
+	case v.IsInf():
+		return Big{x.result().SetInf(false)}
+	case v.Sign() == 0:
+		return Big{x.result().SetInt64(1)}
+	}
+	xf, _ := v.Float64()
+	k := math.Round(xf / math.Ln2)
+	if math.Abs(k) > math.MaxInt32/2 {
+		// beyond the exponent range of big.Float
+		if k < 0 {
+			return Big{x.result()}
+		}
+		return Big{x.result().SetInf(false)}
+	}
+
+	// x - k ln(2) cancels the leading bits of x
+	w := x.Precision() + bigGuardBits
+	if exponent := v.MantExp(nil); exponent > 0 {
+		w += uint(exponent)
+	}
+	r := bigLn2(w)
+	r.Mul(r, new(big.Float).SetFloat64(k))
+	r.Sub(new(big.Float).SetPrec(w).Set(v), r)
+	r.SetMantExp(r, -bigHalvings)
+
+	sum, term := new(big.Float).SetPrec(w).Set(r), new(big.Float).SetPrec(w).Set(r)
+	divisor := new(big.Float).SetPrec(w)
+	for j := int64(2); !negligible(term, sum, w); j++ {
+		term.Mul(term, r)
+		term.Quo(term, divisor.SetInt64(j))
+		sum.Add(sum, term)
+	}
+	// (1 + s)^2 - 1 = s (2 + s)
+	two := new(big.Float).SetPrec(w)
+	for j := 0; j < bigHalvings; j++ {
+		two.SetInt64(2)
+		sum.Mul(sum, two.Add(two, sum))
+	}
+	sum.Add(sum, big.NewFloat(1.0))
+	return Big{x.result().Set(sum.SetMantExp(sum, int(k)))}
+}
+
+// Log refines the float64 logarithm y by the iteration y + 2 (x - exp(y)) / (x + exp(y)),
+// which triples the number of correct digits
+func (x Big) Log() Big {
+	v := x.value()
+	switch {
+	case v.Sign() < 0:
+		panic(big.ErrNaN{})
+	case v.Sign() == 0:
+		return Big{x.result().SetInf(true)}
+	case v.IsInf():
+		return x
+	}
+	p := x.Precision()
+	w := p + bigGuardBits
+
+	mantissa := new(big.Float)
+	exponent := v.MantExp(mantissa)
+	m, _ := mantissa.Float64()
+	y := Big{new(big.Float).SetPrec(w).SetFloat64(math.Log(m) + float64(exponent)*math.Ln2)}
+	xw := Big{new(big.Float).SetPrec(w).Set(v)}
+	for iteration := 0; iteration < 64; iteration++ {
+		e := y.Exp()
+		correction := xw.Sub(e).Div(xw.Add(e)).Scale(2.0)
+		y = y.Add(correction)
+		if negligible(correction.f, y.f, w-bigGuardBits/2) {
+			break
+		}
+	}
+	return Big{x.result().Set(y.f)}
+}
+
+// sinCos reduces the argument to |r| < 2^-8 by x = 2^k r, sums the Taylor series
+// and doubles the angle k times
+func (x Big) sinCos() (sin, cos Big) {
+	v := x.value()
+	if v.IsInf() {
+		panic(big.ErrNaN{})
+	}
+	k := v.MantExp(nil) + 8
+	if k < 0 || v.Sign() == 0 {
+		k = 0
+	}
+	w := x.Precision() + bigGuardBits + uint(k)
+
+	r := new(big.Float).SetPrec(w).SetMantExp(v, -k)
+	r2 := new(big.Float).SetPrec(w).Mul(r, r)
+	s, c := new(big.Float).SetPrec(w).Set(r), new(big.Float).SetPrec(w).SetInt64(1)
+	sTerm, cTerm := new(big.Float).SetPrec(w).Set(r), new(big.Float).SetPrec(w).SetInt64(1)
+	divisor := new(big.Float).SetPrec(w)
+	for j := int64(2); !negligible(sTerm, s, w) || !negligible(cTerm, c, w); j += 2 {
+		cTerm.Mul(cTerm, r2)
+		cTerm.Quo(cTerm, divisor.SetInt64(-j*(j-1)))
+		sTerm.Mul(sTerm, r2)
+		sTerm.Quo(sTerm, divisor.SetInt64(-j*(j+1)))
+		c.Add(c, cTerm)
+		s.Add(s, sTerm)
+	}
+	// sin(2a) = 2 sin(a) cos(a), cos(2a) = 1 - 2 sin(a)^2
+	square := new(big.Float).SetPrec(w)
+	for j := 0; j < k; j++ {
+		square.Mul(s, s)
+		s.Mul(s, c)
+		s.SetMantExp(s, 1)
+		c.SetInt64(1)
+		c.Sub(c, square.SetMantExp(square, 1))
+	}
+	return Big{x.result().Set(s)}, Big{x.result().Set(c)}
+}
+
+func (x Big) Sin() Big {
+	sin, _ := x.sinCos()
+	return sin
+}
+
+func (x Big) Cos() Big {
+	_, cos := x.sinCos()
+	return cos
+}
+
+// Pow multiplies for small integer powers and computes exp(p log(x)) else
+func (x Big) Pow(p float64) Big {
+	switch {
+	case p == 0.5:
+		return x.Sqrt()
+	case p == math.Trunc(p) && math.Abs(p) <= 64:
+		result, power := x.Const(1.0), x
+		for k := int(math.Abs(p)); k > 0; k >>= 1 {
+			if k&1 == 1 {
+				result = result.Mul(power)
+			}
+			power = power.Mul(power)
+		}
+		if p < 0 {
+			result = x.Const(1.0).Div(result)
+		}
+		return result
+	}
+	return x.Log().Scale(p).Exp()
+}
+
+func (x Big) Const(c float64) Big { return Big{x.result().SetFloat64(c)} }
+func (x Big) Rat(r *big.Rat) Big  { return Big{x.result().SetRat(r)} }
+
+func (x Big) Real() float64 {
+	f, _ := x.value().Float64()
+	return f
+}
+
+func (x Big) String() string {
+	return x.value().Text('g', int(float64(x.Precision())*math.Log10(2.0)))
+}
+
+// negligible reports whether |term| < 2^-bits |sum|
+func negligible(term, sum *big.Float, bits uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return false
+	}
+	return term.MantExp(nil) < sum.MantExp(nil)-int(bits)
+}
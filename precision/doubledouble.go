@@ -0,0 +1,216 @@
+package precision
+
+import (
+	"math"
+	"math/big"
+)
+
+// DoubleDouble is the unevaluated sum Hi + Lo of two float64 with |Lo| <= ulp(Hi) / 2,
+// which carries about 32 decimal digits. The algorithms follow Dekker and Knuth,
+// as in the QD library of Hida, Li and Bailey
+type DoubleDouble struct {
+	Hi, Lo float64
+}
+
+var (
+	ddLn2    = DoubleDouble{6.931471805599452862e-01, 2.319046813846299558e-17}
+	ddPiHalf = DoubleDouble{1.570796326794896558e+00, 6.123233995736766036e-17}
+)
+
+const (
+	ddEpsilon = 4.93038065763132e-32 // 2^-104
+	// halvings of the argument of Exp
+	ddHalvings = 10
+)
+
+// NewDoubleDouble converts x exactly
+func NewDoubleDouble(x float64) DoubleDouble {
+	return DoubleDouble{Hi: x}
+}
+
+// twoSum returns s = fl(a + b) and the rounding error e, a + b = s + e
+func twoSum(a, b float64) (s, e float64) {
+	s = a + b
+	bb := s - a
+	e = (a - (s - bb)) + (b - bb)
+	return
+}
+
+// quickTwoSum is twoSum for |a| >= |b|
+func quickTwoSum(a, b float64) (s, e float64) {
+	s = a + b
+	e = b - (s - a)
+	return
+}
+
+// twoProd returns p = fl(a b) and the rounding error e, a b = p + e
+func twoProd(a, b float64) (p, e float64) {
+	p = a * b
+	e = math.FMA(a, b, -p)
+	return
+}
+
+func (x DoubleDouble) Add(y DoubleDouble) DoubleDouble {
+	s, e := twoSum(x.Hi, y.Hi)
+	t, f := twoSum(x.Lo, y.Lo)
+	e += t
+	s, e = quickTwoSum(s, e)
+	e += f
+	s, e = quickTwoSum(s, e)
+	return DoubleDouble{s, e}
+}
+
+func (x DoubleDouble) Sub(y DoubleDouble) DoubleDouble { return x.Add(y.Neg()) }
+func (x DoubleDouble) Neg() DoubleDouble               { return DoubleDouble{-x.Hi, -x.Lo} }
+
+func (x DoubleDouble) Mul(y DoubleDouble) DoubleDouble {
+	p, e := twoProd(x.Hi, y.Hi)
+	e += x.Hi*y.Lo + x.Lo*y.Hi
+	p, e = quickTwoSum(p, e)
+	return DoubleDouble{p, e}
+}
+
+func (x DoubleDouble) Scale(c float64) DoubleDouble {
+	p, e := twoProd(x.Hi, c)
+	e += x.Lo * c
+	p, e = quickTwoSum(p, e)
+	return DoubleDouble{p, e}
+}
+
+// Div corrects the quotient of the leading parts twice
+func (x DoubleDouble) Div(y DoubleDouble) DoubleDouble {
+	q1 := x.Hi / y.Hi
+	r := x.Sub(y.Scale(q1))
+	q2 := r.Hi / y.Hi
+	r = r.Sub(y.Scale(q2))
+	q3 := r.Hi / y.Hi
+	s, e := quickTwoSum(q1, q2)
+	return DoubleDouble{s, e}.Add(DoubleDouble{Hi: q3})
+}
+
+// Sqrt corrects the float64 root by one Newton step
+func (x DoubleDouble) Sqrt() DoubleDouble {
+	if x.Hi <= 0.0 {
+		return DoubleDouble{Hi: math.Sqrt(x.Hi)}
+	}
+	a := math.Sqrt(x.Hi)
+	p, e := twoProd(a, a)
+	r := x.Sub(DoubleDouble{p, e})
+	return DoubleDouble{Hi: a}.Add(DoubleDouble{Hi: 0.5 * r.Hi / a})
+}
+
+// Exp reduces the argument to |r| <= ln(2) / 2^11 by x = k ln(2) + 2^10 r,
+// sums the Taylor series of exp(r) and squares the result 10 times
+func (x DoubleDouble) Exp() DoubleDouble {
+	switch {
+	case x.Hi > 709.0:
+		return DoubleDouble{Hi: math.Inf(1)}
+	case x.Hi < -745.0:
+		return DoubleDouble{}
+	case math.IsNaN(x.Hi):
+		return x
+	}
+	k := math.Round(x.Hi / ddLn2.Hi)
+	r := x.Sub(ddLn2.Scale(k)).Scale(math.Ldexp(1.0, -ddHalvings))
+
+	// exp(r) - 1, which keeps the precision of the small terms
+	sum, term := r, r
+	for j := 2.0; math.Abs(term.Hi) > ddEpsilon*math.Abs(sum.Hi); j++ {
+		term = term.Mul(r).Div(DoubleDouble{Hi: j})
+		sum = sum.Add(term)
+	}
+	// (1 + s)^2 - 1 = s (2 + s)
+	for j := 0; j < ddHalvings; j++ {
+		sum = sum.Mul(sum.Add(DoubleDouble{Hi: 2.0}))
+	}
+	result := sum.Add(DoubleDouble{Hi: 1.0})
+	return DoubleDouble{math.Ldexp(result.Hi, int(k)), math.Ldexp(result.Lo, int(k))}
+}
+
+// Log corrects the float64 logarithm y by the Newton step y + x exp(-y) - 1
+func (x DoubleDouble) Log() DoubleDouble {
+	if x.Hi <= 0.0 || math.IsInf(x.Hi, 1) || math.IsNaN(x.Hi) {
+		return DoubleDouble{Hi: math.Log(x.Hi)}
+	}
+	y := DoubleDouble{Hi: math.Log(x.Hi)}
+	return y.Add(x.Mul(y.Neg().Exp())).Sub(DoubleDouble{Hi: 1.0})
+}
+
+// sinCos reduces the argument by multiples of pi / 2 and sums the Taylor series
+func (x DoubleDouble) sinCos() (sin, cos DoubleDouble) {
+	k := math.Round(x.Hi / ddPiHalf.Hi)
+	r := x.Sub(ddPiHalf.Scale(k))
+	r2 := r.Mul(r)
+
+	sin, cos = r, DoubleDouble{Hi: 1.0}
+	sinTerm, cosTerm := r, DoubleDouble{Hi: 1.0}
+	for j := 2.0; math.Abs(sinTerm.Hi) > ddEpsilon || math.Abs(cosTerm.Hi) > ddEpsilon; j += 2 {
+		cosTerm = cosTerm.Mul(r2).Div(DoubleDouble{Hi: -j * (j - 1.0)})
+		sinTerm = sinTerm.Mul(r2).Div(DoubleDouble{Hi: -j * (j + 1.0)})
+		cos, sin = cos.Add(cosTerm), sin.Add(sinTerm)
+	}
+
+	switch quadrant := int64(k) & 3; quadrant {
+	case 1:
+		sin, cos = cos, sin.Neg()
+	case 2:
+		sin, cos = sin.Neg(), cos.Neg()
+	case 3:
+		sin, cos = cos.Neg(), sin
+	}
+	return
+}
+
+func (x DoubleDouble) Sin() DoubleDouble {
+	sin, _ := x.sinCos()
+	return sin
+}
+
+func (x DoubleDouble) Cos() DoubleDouble {
+	_, cos := x.sinCos()
+	return cos
+}
+
+// Pow multiplies for small integer powers and computes exp(p log(x)) else
+func (x DoubleDouble) Pow(p float64) DoubleDouble {
+	if p == math.Trunc(p) && math.Abs(p) <= 64 {
+		result, power := DoubleDouble{Hi: 1.0}, x
+		for k := int(math.Abs(p)); k > 0; k >>= 1 {
+			if k&1 == 1 {
+				result = result.Mul(power)
+			}
+			power = power.Mul(power)
+		}
+		if p < 0 {
+			result = DoubleDouble{Hi: 1.0}.Div(result)
+		}
+		return result
+	}
+	return x.Log().Scale(p).Exp()
+}
+
+func (x DoubleDouble) Const(c float64) DoubleDouble { return DoubleDouble{Hi: c} }
+func (x DoubleDouble) Real() float64                { return x.Hi + x.Lo }
+func (x DoubleDouble) Precision() uint              { return 106 }
+
+// Rat rounds r to the leading part and the rest of r to the trailing part
+func (x DoubleDouble) Rat(r *big.Rat) DoubleDouble {
+	hi, _ := r.Float64()
+	rest := new(big.Rat).Sub(r, new(big.Rat).SetFloat64(hi))
+	lo, _ := rest.Float64()
+	hi, lo = quickTwoSum(hi, lo)
+	return DoubleDouble{hi, lo}
+}
+
+// Big returns x exactly as big.Float
+func (x DoubleDouble) Big() *big.Float {
+	hi := new(big.Float).SetPrec(2048).SetFloat64(x.Hi)
+	return hi.Add(hi, new(big.Float).SetFloat64(x.Lo))
+}
+
+func (x DoubleDouble) String() string {
+	if math.IsNaN(x.Hi) {
+		return "NaN"
+	}
+	return x.Big().Text('g', 32)
+}
@@ -0,0 +1,156 @@
+package precision
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+// 50 digits of the constants
+const (
+	e     = "2.71828182845904523536028747135266249775724709369996"
+	ln2   = "0.69314718055994530941723212145817656807550013436026"
+	sqrt2 = "1.41421356237309504880168872420969807856967187537695"
+	sin1  = "0.84147098480789650665250232163029899962256306079837"
+	cos1  = "0.54030230586813971740093660744297660373231042061792"
+)
+
+// elementary evaluates the functions whose results are the constants above
+func elementary[T Real[T]](one T) []T {
+	return []T{one.Exp(), one.Scale(2.0).Log(), one.Scale(2.0).Sqrt(), one.Sin(), one.Cos(), one.Scale(2.0).Pow(0.5)}
+}
+
+func constants() []*big.Float {
+	values := []string{e, ln2, sqrt2, sin1, cos1, sqrt2}
+	f := make([]*big.Float, len(values))
+	for i, v := range values {
+		f[i], _ = new(big.Float).SetPrec(512).SetString(v)
+	}
+	return f
+}
+
+func relativeError(x, expected *big.Float) float64 {
+	difference := new(big.Float).SetPrec(512).Sub(x, expected)
+	relative, _ := difference.Quo(difference, expected).Float64()
+	return math.Abs(relative)
+}
+
+func TestDoubleDouble(t *testing.T) {
+	expected := constants()
+	for i, x := range elementary(NewDoubleDouble(1.0)) {
+		if err := relativeError(x.Big(), expected[i]); err > 1e-30 {
+			t.Errorf("Function %d: %s has relative error %g", i, x, err)
+		}
+	}
+
+	third := NewDoubleDouble(1.0).Div(NewDoubleDouble(3.0))
+	tenth := third.Rat(big.NewRat(1, 10))
+	if err := relativeError(third.Scale(3.0).Big(), big.NewFloat(1.0)); err > 1e-31 {
+		t.Errorf("3 (1 / 3) = %s", third.Scale(3.0))
+	}
+	if err := relativeError(tenth.Big(), new(big.Float).SetPrec(512).SetRat(big.NewRat(1, 10))); err > 1e-32 {
+		t.Errorf("1 / 10 = %s", tenth)
+	}
+
+	for _, x := range []float64{-30.0, -1.5, 0.1, 7.0, 100.0} {
+		dd := NewDoubleDouble(x)
+		sin, cos := dd.Sin(), dd.Cos()
+		if one := sin.Mul(sin).Add(cos.Mul(cos)); math.Abs(one.Sub(dd.Const(1.0)).Real()) > 1e-30 {
+			t.Errorf("sin(%g)^2 + cos(%g)^2 - 1 = %g", x, x, one.Sub(dd.Const(1.0)).Real())
+		}
+		if math.Abs(sin.Real()-math.Sin(x)) > 1e-15 || math.Abs(cos.Real()-math.Cos(x)) > 1e-15 {
+			t.Errorf("sin(%g) = %g, cos(%g) = %g differ from float64", x, sin.Real(), x, cos.Real())
+		}
+		if roundTrip := dd.Exp().Log().Sub(dd); math.Abs(roundTrip.Real()) > 1e-30*math.Max(1.0, math.Abs(x)) {
+			t.Errorf("log(exp(%g)) - %g = %g", x, x, roundTrip.Real())
+		}
+		if power := dd.Mul(dd).Mul(dd).Sub(dd.Pow(3.0)); math.Abs(power.Real()) > 1e-30*math.Abs(x*x*x) {
+			t.Errorf("%g^3 differs by %g", x, power.Real())
+		}
+	}
+}
+
+func TestBig(t *testing.T) {
+	expected := constants()
+	for i, x := range elementary(NewBig(1.0, 200)) {
+		if x.Precision() != 200 {
+			t.Errorf("Function %d: precision %d, expected 200", i, x.Precision())
+		}
+		if err := relativeError(x.Float(), expected[i]); err > 1e-49 {
+			t.Errorf("Function %d: %s has relative error %g", i, x, err)
+		}
+	}
+
+	// identities at higher precision than the constants
+	for _, x := range []float64{-30.0, -1.5, 0.1, 7.0, 100.0} {
+		b := NewBig(x, 400)
+		sin, cos := b.Sin(), b.Cos()
+		if err := relativeError(sin.Mul(sin).Add(cos.Mul(cos)).Float(), big.NewFloat(1.0)); err > 1e-118 {
+			t.Errorf("sin(%g)^2 + cos(%g)^2 - 1 = %g", x, x, err)
+		}
+		if math.Abs(sin.Real()-math.Sin(x)) > 1e-15 || math.Abs(cos.Real()-math.Cos(x)) > 1e-15 {
+			t.Errorf("sin(%g) = %g, cos(%g) = %g differ from float64", x, sin.Real(), x, cos.Real())
+		}
+		if err := relativeError(b.Exp().Log().Float(), b.Float()); err > 1e-117 {
+			t.Errorf("log(exp(%g)) has relative error %g", x, err)
+		}
+	}
+
+	// the zero value takes the precision of the other operand
+	var zero Big
+	if sum := zero.Add(NewBig(1.0, 64)); sum.Precision() != 64 || sum.Real() != 1.0 {
+		t.Errorf("0 + 1 = %s with precision %d", sum, sum.Precision())
+	}
+	if tenth := NewBig(0, 64).Rat(big.NewRat(1, 10)); tenth.Precision() != 64 {
+		t.Errorf("Rational with precision %d, expected 64", tenth.Precision())
+	}
+	if product := NewBig(3.0, 64).Mul(NewBig(0.5, 300)); product.Precision() != 300 {
+		t.Errorf("Product with precision %d, expected 300", product.Precision())
+	}
+}
+
+func TestBigExp(t *testing.T) {
+	// large arguments of both signs keep the full relative precision
+	one := big.NewFloat(1.0)
+	for _, x := range []float64{-10000.0, -745.0, -300.0, -100.0, -0.5, 1e-30, 2.0, 100.0, 300.0} {
+		b := NewBig(x, 400)
+		exp := b.Exp()
+		if err := relativeError(exp.Mul(b.Neg().Exp()).Float(), one); err > 1e-117 {
+			t.Errorf("exp(%g) exp(%g) - 1 = %g", x, -x, err)
+		}
+		if expected := math.Exp(x); math.Abs(x) < 700 && math.Abs(exp.Real()-expected) > 1e-15*expected {
+			t.Errorf("exp(%g) = %g differs from float64 %g", x, exp.Real(), expected)
+		}
+		half := b.Scale(0.5).Exp()
+		if err := relativeError(half.Mul(half).Float(), exp.Float()); err > 1e-117 {
+			t.Errorf("exp(%g / 2)^2 has relative error %g", x, err)
+		}
+	}
+
+	// 2^-1000.5 by exp(-1000.5 log(2))
+	power := NewBig(2.0, 400).Pow(-1000.5)
+	expected := new(big.Float).SetPrec(400).Sqrt(big.NewFloat(2.0))
+	expected.Quo(new(big.Float).SetMantExp(big.NewFloat(1.0), -1000), expected)
+	if err := relativeError(power.Float(), expected); err > 1e-115 {
+		t.Errorf("2^-1000.5 has relative error %g", err)
+	}
+}
+
+func TestViews(t *testing.T) {
+	x := []float64{1.0, 2.0, 3.0}
+	view := Float64s(x)
+	view[1] = 5.0
+	if x[1] != 5.0 {
+		t.Errorf("Float64s copies its argument")
+	}
+	if back, ok := AsFloat64(view); !ok || &back[0] != &x[0] {
+		t.Errorf("AsFloat64 copies ad.Float values")
+	}
+	if _, ok := AsFloat64([]Float32{1.0}); ok {
+		t.Errorf("AsFloat64 accepts Float32 values")
+	}
+	converted := FromFloat64(x, NewBig(0, 100))
+	if back := ToFloat64(converted); back[1] != 5.0 || converted[2].Precision() != 100 {
+		t.Errorf("Conversion to Big and back gives %v", back)
+	}
+}
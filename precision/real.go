@@ -0,0 +1,78 @@
+// Package precision provides floating point types of different precision for the
+// generic integrators: Float32, the double-double DoubleDouble and the arbitrary
+// precision Big. All of them are scalars for generic right hand sides, and so is
+// ad.Float, the float64 type of the generic integrators
+package precision
+
+import (
+	"github.com/rollingthunder/differential/ad"
+	"math"
+	"math/big"
+	"unsafe"
+)
+
+// Real is a floating point type the generic integrators compute with
+type Real[T any] interface {
+	ad.Scalar[T]
+	// Rat returns r rounded to the precision of the receiver
+	Rat(r *big.Rat) T
+	// Precision returns the number of mantissa bits
+	Precision() uint
+}
+
+// Float32 is a float32 as Real, for memory bound computations
+type Float32 float32
+
+func (x Float32) Add(y Float32) Float32   { return x + y }
+func (x Float32) Sub(y Float32) Float32   { return x - y }
+func (x Float32) Mul(y Float32) Float32   { return x * y }
+func (x Float32) Div(y Float32) Float32   { return x / y }
+func (x Float32) Neg() Float32            { return -x }
+func (x Float32) Scale(c float64) Float32 { return Float32(c) * x }
+func (x Float32) Sqrt() Float32           { return Float32(math.Sqrt(float64(x))) }
+func (x Float32) Exp() Float32            { return Float32(math.Exp(float64(x))) }
+func (x Float32) Log() Float32            { return Float32(math.Log(float64(x))) }
+func (x Float32) Sin() Float32            { return Float32(math.Sin(float64(x))) }
+func (x Float32) Cos() Float32            { return Float32(math.Cos(float64(x))) }
+func (x Float32) Pow(p float64) Float32   { return Float32(math.Pow(float64(x), p)) }
+func (x Float32) Const(c float64) Float32 { return Float32(c) }
+func (x Float32) Real() float64           { return float64(x) }
+func (x Float32) Precision() uint         { return 24 }
+func (x Float32) Rat(r *big.Rat) Float32 {
+	f, _ := r.Float32()
+	return Float32(f)
+}
+
+// Float64s returns x as []ad.Float, sharing its memory
+func Float64s(x []float64) []ad.Float {
+	return unsafe.Slice((*ad.Float)(unsafe.SliceData(x)), len(x))
+}
+
+// AsFloat64 returns x as []float64 sharing its memory, if T is ad.Float
+func AsFloat64[T any](x []T) ([]float64, bool) {
+	if f, ok := any(x).([]ad.Float); ok {
+		return unsafe.Slice((*float64)(unsafe.SliceData(f)), len(f)), true
+	}
+	return nil, false
+}
+
+// ToFloat64 rounds x to float64 values, for ad.Float it returns x itself without copying
+func ToFloat64[T Real[T]](x []T) []float64 {
+	if f, ok := AsFloat64(x); ok {
+		return f
+	}
+	f := make([]float64, len(x))
+	for i := range x {
+		f[i] = x[i].Real()
+	}
+	return f
+}
+
+// FromFloat64 converts x to values of the precision of prototype
+func FromFloat64[T Real[T]](x []float64, prototype T) []T {
+	y := make([]T, len(x))
+	for i := range x {
+		y[i] = prototype.Const(x[i])
+	}
+	return y
+}
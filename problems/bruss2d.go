@@ -1,5 +1,7 @@
 package problems
 
+import "github.com/rollingthunder/differential/ad"
+import "github.com/rollingthunder/differential/ode"
 import "github.com/rollingthunder/differential/util"
 import "fmt"
 
@@ -89,7 +91,7 @@ func (b *brusselator) FcnCorrect(t float64, yT []float64, dy_out []float64) {
 // alpha = 0.002
 // u[0, x, y] = 2 + 0.25y
 // v(0, x, y) = 1 + 0.8x
-func NewBruss2D(N uint) TiledProblem {
+func NewBruss2D(N uint) ReactionDiffusionProblem {
 	if N <= 0 {
 		return nil
 	}
@@ -222,3 +224,258 @@ func (b *brusselator) FcnBlock(startIdx, blockSize uint, t float64, yT []float64
 		dy_out[i+1] = b.alphaN1Squared*dy_out[i+1] + b.a*yT[i] - yT[i]*yT[i]*yT[i+1]
 	}
 }
+
+// neighbours of a cell in the order top, right, bottom, left
+// cells outside the grid are mirrored at the boundary
+func (b *brusselator) neighbours(index int) [4]int {
+	top, right, bottom, left := index-b.n, index+1, index+b.n, index-1
+	if top < 0 {
+		top = bottom
+	} else if bottom >= b.cellcount {
+		bottom = top
+	}
+
+	if idxModN := index % b.n; idxModN == 0 {
+		left = right
+	} else if b.n-idxModN == 1 {
+		right = left
+	}
+	return [4]int{top, right, bottom, left}
+}
+
+// Sparsity returns the pattern of the Jacobian: each component depends on
+// both components of its cell and on the same component of the neighbouring cells
+func (b *brusselator) Sparsity() *ode.Sparsity {
+	rows := make([][]int, 2*b.cellcount)
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		u, v := []int{here, here + 1}, []int{here, here + 1}
+		for _, neighbour := range b.neighbours(index) {
+			u = append(u, neighbour<<1)
+			v = append(v, neighbour<<1+1)
+		}
+		rows[here], rows[here+1] = u, v
+	}
+	return ode.NewSparsity(rows)
+}
+
+// Jacobian computes the Jacobian at (t, yT) on the pattern returned by Sparsity
+func (b *brusselator) Jacobian(t float64, yT []float64, jac_out *ode.SparseMatrix) {
+	for k := range jac_out.Values {
+		jac_out.Values[k] = 0.0
+	}
+
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		u, v := yT[here], yT[here+1]
+
+		jac_out.Values[jac_out.Index(here, here)] = 2.0*u*v - b.a1 - 4.0*b.alphaN1Squared
+		jac_out.Values[jac_out.Index(here, here+1)] = u * u
+		jac_out.Values[jac_out.Index(here+1, here)] = b.a - 2.0*u*v
+		jac_out.Values[jac_out.Index(here+1, here+1)] = -u*u - 4.0*b.alphaN1Squared
+
+		// mirrored neighbours count twice
+		for _, neighbour := range b.neighbours(index) {
+			jac_out.Values[jac_out.Index(here, neighbour<<1)] += b.alphaN1Squared
+			jac_out.Values[jac_out.Index(here+1, neighbour<<1+1)] += b.alphaN1Squared
+		}
+	}
+}
+
+// Preconditioner approximates the solution of (I - shift J) z = r by one symmetric
+// Gauss-Seidel sweep over the cells, solving for both components of a cell at once
+func (b *brusselator) Preconditioner(t float64, yT []float64, shift float64, r, z_out []float64) {
+	coupling := shift * b.alphaN1Squared
+
+	// solves the diagonal block of a cell, the local Jacobian including the diffusion
+	solve := func(here int, ru, rv float64) (float64, float64) {
+		u, v := yT[here], yT[here+1]
+		d11 := 1.0 - shift*(2.0*u*v-b.a1-4.0*b.alphaN1Squared)
+		d12 := -shift * u * u
+		d21 := -shift * (b.a - 2.0*u*v)
+		d22 := 1.0 + shift*(u*u+4.0*b.alphaN1Squared)
+		det := d11*d22 - d12*d21
+		return (d22*ru - d12*rv) / det, (d11*rv - d21*ru) / det
+	}
+
+	// forward sweep with the lower neighbours, mirrored neighbours count twice
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		ru, rv := r[here], r[here+1]
+		for _, neighbour := range b.neighbours(index) {
+			if neighbour < index {
+				ru += coupling * z_out[neighbour<<1]
+				rv += coupling * z_out[neighbour<<1+1]
+			}
+		}
+		z_out[here], z_out[here+1] = solve(here, ru, rv)
+	}
+
+	// backward sweep with the upper neighbours
+	for index := b.cellcount - 1; index >= 0; index-- {
+		here := index << 1
+		ru, rv := 0.0, 0.0
+		for _, neighbour := range b.neighbours(index) {
+			if neighbour > index {
+				ru += coupling * z_out[neighbour<<1]
+				rv += coupling * z_out[neighbour<<1+1]
+			}
+		}
+		du, dv := solve(here, ru, rv)
+		z_out[here] += du
+		z_out[here+1] += dv
+	}
+}
+
+// Reaction computes the reaction part of the right hand side
+// du = B + u^2*v - (A + 1)*u, dv = A * u - u^2*v
+func (b *brusselator) Reaction(t float64, yT []float64, dy_out []float64) {
+	for here := 0; here < 2*b.cellcount; here += 2 {
+		u, v := yT[here], yT[here+1]
+		dy_out[here] = b.b + u*u*v - b.a1*u
+		dy_out[here+1] = b.a*u - u*u*v
+	}
+}
+
+// Diffusion computes the diffusion part of the right hand side
+// (alpha * (n-1)^2) * (top + right + bottom + left - 4 * here) for both components
+func (b *brusselator) Diffusion(t float64, yT []float64, dy_out []float64) {
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		du, dv := -4.0*yT[here], -4.0*yT[here+1]
+		for _, neighbour := range b.neighbours(index) {
+			du += yT[neighbour<<1]
+			dv += yT[neighbour<<1+1]
+		}
+		dy_out[here], dy_out[here+1] = b.alphaN1Squared*du, b.alphaN1Squared*dv
+	}
+}
+
+// DiffusionSparsity returns the pattern of the Jacobian of the diffusion part:
+// each component depends on the same component of its own and the neighbouring cells
+func (b *brusselator) DiffusionSparsity() *ode.Sparsity {
+	rows := make([][]int, 2*b.cellcount)
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		u, v := []int{here}, []int{here + 1}
+		for _, neighbour := range b.neighbours(index) {
+			u = append(u, neighbour<<1)
+			v = append(v, neighbour<<1+1)
+		}
+		rows[here], rows[here+1] = u, v
+	}
+	return ode.NewSparsity(rows)
+}
+
+// DiffusionJacobian computes the Jacobian of the diffusion part on the pattern returned by DiffusionSparsity
+func (b *brusselator) DiffusionJacobian(t float64, yT []float64, jac_out *ode.SparseMatrix) {
+	for k := range jac_out.Values {
+		jac_out.Values[k] = 0.0
+	}
+
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		jac_out.Values[jac_out.Index(here, here)] = -4.0 * b.alphaN1Squared
+		jac_out.Values[jac_out.Index(here+1, here+1)] = -4.0 * b.alphaN1Squared
+
+		// mirrored neighbours count twice
+		for _, neighbour := range b.neighbours(index) {
+			jac_out.Values[jac_out.Index(here, neighbour<<1)] += b.alphaN1Squared
+			jac_out.Values[jac_out.Index(here+1, neighbour<<1+1)] += b.alphaN1Squared
+		}
+	}
+}
+
+// Parameters returns the reaction constants A, B and the diffusion constant alpha
+func (b *brusselator) Parameters() []float64 {
+	return []float64{b.a, b.b, b.alpha}
+}
+
+// FcnParameters evaluates the right hand side for the parameters p = (A, B, alpha)
+func (b *brusselator) FcnParameters(t float64, yT, p []float64, dy_out []float64) {
+	a, bb := p[0], p[1]
+	n1 := float64(b.n) - 1.0
+	alphaN1Squared := p[2] * n1 * n1
+
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		u, v := yT[here], yT[here+1]
+		laplaceU, laplaceV := -4.0*u, -4.0*v
+		for _, neighbour := range b.neighbours(index) {
+			laplaceU += yT[neighbour<<1]
+			laplaceV += yT[neighbour<<1+1]
+		}
+		dy_out[here] = bb + u*u*v - (a+1.0)*u + alphaN1Squared*laplaceU
+		dy_out[here+1] = a*u - u*u*v + alphaN1Squared*laplaceV
+	}
+}
+
+// VectorJacobian computes lambda^T df/dy and lambda^T df/dp for the parameters p = (A, B, alpha),
+// the transposed Laplacian scatters each cell to its neighbours
+func (b *brusselator) VectorJacobian(t float64, yT, p, lambda []float64, dfdy_out, dfdp_out []float64) {
+	a := p[0]
+	n1 := float64(b.n) - 1.0
+	alphaN1Squared := p[2] * n1 * n1
+
+	for here := 0; here < 2*b.cellcount; here += 2 {
+		u, v := yT[here], yT[here+1]
+		lu, lv := lambda[here], lambda[here+1]
+		dfdy_out[here] = lu*(2.0*u*v-(a+1.0)) + lv*(a-2.0*u*v) - 4.0*alphaN1Squared*lu
+		dfdy_out[here+1] = (lu-lv)*u*u - 4.0*alphaN1Squared*lv
+	}
+
+	var dA, dB, dAlpha float64
+	for index := 0; index < b.cellcount; index++ {
+		here := index << 1
+		u, v := yT[here], yT[here+1]
+		lu, lv := lambda[here], lambda[here+1]
+		laplaceU, laplaceV := -4.0*u, -4.0*v
+		for _, neighbour := range b.neighbours(index) {
+			laplaceU += yT[neighbour<<1]
+			laplaceV += yT[neighbour<<1+1]
+			dfdy_out[neighbour<<1] += alphaN1Squared * lu
+			dfdy_out[neighbour<<1+1] += alphaN1Squared * lv
+		}
+		dA += (lv - lu) * u
+		dB += lu
+		dAlpha += lu*laplaceU + lv*laplaceV
+	}
+	dfdp_out[0], dfdp_out[1], dfdp_out[2] = dA, dB, n1*n1*dAlpha
+}
+
+// ParametricSystem returns the right hand side of FcnParameters over abstract scalars
+func (b *brusselator) ParametricSystem() *ad.ParametricSystem {
+	return &ad.ParametricSystem{
+		Value:     brusselatorFcn[ad.Float](b),
+		Dual:      brusselatorFcn[ad.Dual](b),
+		HyperDual: brusselatorFcn[ad.HyperDual](b),
+	}
+}
+
+// Bruss2DFcn returns the right hand side of NewBruss2D(N) over the scalar type T,
+// with the parameters A, B and alpha
+func Bruss2DFcn[T ad.Scalar[T]](N uint) ad.ParametricFcn[T] {
+	return brusselatorFcn[T](NewBruss2D(N).(*brusselator))
+}
+
+func brusselatorFcn[T ad.Scalar[T]](b *brusselator) ad.ParametricFcn[T] {
+	return func(t float64, yT, p []T, dy_out []T) {
+		a, bb := p[0], p[1]
+		n1 := float64(b.n) - 1.0
+		alphaN1Squared := p[2].Scale(n1 * n1)
+		a1 := a.Add(a.Const(1.0))
+
+		for index := 0; index < b.cellcount; index++ {
+			here := index << 1
+			u, v := yT[here], yT[here+1]
+			laplaceU, laplaceV := u.Scale(-4.0), v.Scale(-4.0)
+			for _, neighbour := range b.neighbours(index) {
+				laplaceU = laplaceU.Add(yT[neighbour<<1])
+				laplaceV = laplaceV.Add(yT[neighbour<<1+1])
+			}
+			uuv := u.Mul(u).Mul(v)
+			dy_out[here] = bb.Add(uuv).Sub(a1.Mul(u)).Add(alphaN1Squared.Mul(laplaceU))
+			dy_out[here+1] = a.Mul(u).Sub(uuv).Add(alphaN1Squared.Mul(laplaceV))
+		}
+	}
+}
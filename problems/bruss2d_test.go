@@ -1,6 +1,8 @@
 package problems
 
 import "testing"
+import "math"
+import "github.com/rollingthunder/differential/ode"
 import "github.com/rollingthunder/differential/util"
 
 func TestConstructor(t *testing.T) {
@@ -31,3 +33,205 @@ func testBlock(blockSize uint, t *testing.T) {
 
 func TestBlock_even(t *testing.T) { testBlock(100, t) }
 func TestBlock_odd(t *testing.T)  { testBlock(101, t) }
+
+func TestJacobian(t *testing.T) {
+	b := NewBruss2D(6)
+	yT := b.Initialize()
+	n := len(yT)
+	f0 := make([]float64, n)
+	b.Fcn(0, yT, f0)
+
+	config, err := (&ode.Config{Fcn: b.Fcn, Sparsity: b.Sparsity()}).ValidateAndPrepare(uint(n), 0, 1)
+	if err != nil {
+		t.Fatalf("Invalid configuration - %s", err.Error())
+	}
+
+	analytic := ode.NewSparseMatrix(config.Sparsity)
+	b.Jacobian(0, yT, analytic)
+	colored := ode.NewSparseMatrix(config.Sparsity)
+	evaluations := config.EvaluateSparseJacobian(0, yT, f0, colored)
+
+	// column by column without pattern
+	config.Sparsity = nil
+	dense := util.MakeRectangular(uint(n), uint(n))
+	if denseEvaluations := config.EvaluateJacobian(0, yT, f0, dense); denseEvaluations != uint(n) {
+		t.Errorf("Dense approximation needed %d evaluations, expected %d", denseEvaluations, n)
+	}
+	expected := util.MakeRectangular(uint(n), uint(n))
+	analytic.ToDense(expected)
+
+	for i := range expected {
+		for j := range expected[i] {
+			if math.Abs(dense[i][j]-expected[i][j]) > 1e-5*math.Max(1, math.Abs(expected[i][j])) {
+				t.Fatalf("J[%d][%d] = %g, finite differences %g", i, j, expected[i][j], dense[i][j])
+			}
+		}
+	}
+	for k := range analytic.Values {
+		if math.Abs(colored.Values[k]-analytic.Values[k]) > 1e-5*math.Max(1, math.Abs(analytic.Values[k])) {
+			t.Fatalf("Entry %d = %g, colored finite differences %g", k, analytic.Values[k], colored.Values[k])
+		}
+	}
+
+	// the 5 point stencil of two components can be covered by few colors
+	if evaluations > 12 {
+		t.Errorf("Colored approximation needed %d evaluations for %d columns", evaluations, n)
+	}
+	if testing.Verbose() {
+		t.Logf("Colored approximation: %d evaluations for %d columns", evaluations, n)
+	}
+}
+
+func TestSparsityValidation(t *testing.T) {
+	b := NewBruss2D(4)
+	config := ode.Config{Fcn: b.Fcn, Sparsity: b.Sparsity()}
+	if _, err := config.ValidateAndPrepare(10, 0, 1); err == nil {
+		t.Errorf("Pattern of the wrong size was accepted")
+	}
+
+	config = ode.Config{Fcn: b.Fcn, SparseJacobian: b.Jacobian}
+	if _, err := config.ValidateAndPrepare(32, 0, 1); err == nil {
+		t.Errorf("Sparse jacobian without pattern was accepted")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	b := NewBruss2D(6)
+	yT := b.Initialize()
+	n := len(yT)
+	for i := range yT {
+		yT[i] += 0.1 * math.Sin(float64(i))
+	}
+
+	whole, reaction, diffusion := make([]float64, n), make([]float64, n), make([]float64, n)
+	b.Fcn(0.5, yT, whole)
+	b.Reaction(0.5, yT, reaction)
+	b.Diffusion(0.5, yT, diffusion)
+	for i := range whole {
+		if math.Abs(reaction[i]+diffusion[i]-whole[i]) > 1e-12*math.Max(1, math.Abs(whole[i])) {
+			t.Fatalf("Reaction + diffusion [%d] = %g, whole right hand side %g", i, reaction[i]+diffusion[i], whole[i])
+		}
+	}
+
+	config, err := (&ode.Config{Fcn: b.Diffusion, Sparsity: b.DiffusionSparsity()}).ValidateAndPrepare(uint(n), 0, 1)
+	if err != nil {
+		t.Fatalf("Invalid configuration - %s", err.Error())
+	}
+	analytic := ode.NewSparseMatrix(config.Sparsity)
+	b.DiffusionJacobian(0.5, yT, analytic)
+	colored := ode.NewSparseMatrix(config.Sparsity)
+	config.EvaluateSparseJacobian(0.5, yT, diffusion, colored)
+	for k := range analytic.Values {
+		if math.Abs(colored.Values[k]-analytic.Values[k]) > 1e-5*math.Max(1, math.Abs(analytic.Values[k])) {
+			t.Fatalf("Entry %d = %g, colored finite differences %g", k, analytic.Values[k], colored.Values[k])
+		}
+	}
+}
+
+func TestNoisyBruss2D(t *testing.T) {
+	b := NewNoisyBruss2D(4, 0.1)
+	yT := b.Initialize()
+	n := len(yT)
+
+	drift, expected := make([]float64, n), make([]float64, n)
+	b.Fcn(0, yT, drift)
+	NewBruss2D(4).Fcn(0, yT, expected)
+	if !util.ArrayEpsEquals(drift, expected, 1e-14) {
+		t.Errorf("Drift differs from the deterministic Brusselator")
+	}
+
+	noise := make([]float64, n)
+	b.Noise(0, yT, noise)
+	for i := range noise {
+		if math.Abs(noise[i]-0.1*yT[i]) > 1e-14 {
+			t.Errorf("Noise of component %d is %g, expected %g", i, noise[i], 0.1*yT[i])
+		}
+	}
+}
+
+func TestParameters(t *testing.T) {
+	b := NewBruss2D(5)
+	p := b.(ParametricProblem)
+	yT := b.Initialize()
+	expected, dy := make([]float64, len(yT)), make([]float64, len(yT))
+
+	b.Fcn(0, yT, expected)
+	p.FcnParameters(0, yT, p.Parameters(), dy)
+	if !util.ArrayEpsEquals(dy, expected, 1e-12) {
+		t.Errorf("Evaluation with the default parameters differs from Fcn")
+	}
+}
+
+func TestGenericSystem(t *testing.T) {
+	b := NewBruss2D(5)
+	g := b.(GenericProblem)
+	system := g.ParametricSystem().Fix(g.Parameters())
+	yT := b.Initialize()
+	n := len(yT)
+	expected, dy := make([]float64, n), make([]float64, n)
+
+	b.Fcn(0, yT, expected)
+	system.Function()(0, yT, dy)
+	if !util.ArrayEpsEquals(dy, expected, 1e-12) {
+		t.Errorf("Generic right hand side differs from Fcn")
+	}
+
+	sparsity := b.Sparsity()
+	analytic, differentiated := ode.NewSparseMatrix(sparsity), ode.NewSparseMatrix(sparsity)
+	b.Jacobian(0, yT, analytic)
+	system.SparseJacobian()(0, yT, differentiated)
+	dense := util.MakeRectangular(uint(n), uint(n))
+	system.Jacobian()(0, yT, dense)
+	for i := 0; i < n; i++ {
+		for k := sparsity.RowStart[i]; k < sparsity.RowStart[i+1]; k++ {
+			j := sparsity.Columns[k]
+			if math.Abs(differentiated.Values[k]-analytic.Values[k]) > 1e-12 || math.Abs(dense[i][j]-analytic.Values[k]) > 1e-12 {
+				t.Fatalf("J[%d][%d] = %g, automatic differentiation %g, dense %g", i, j, analytic.Values[k], differentiated.Values[k], dense[i][j])
+			}
+		}
+	}
+}
+
+// testVectorJacobian compares the vector-Jacobian products with the Jacobians of automatic differentiation
+func testVectorJacobian(t *testing.T, problem Problem) {
+	a, g := problem.(AdjointProblem), problem.(GenericProblem)
+	p := a.Parameters()
+	yT := problem.Initialize()
+	n := len(yT)
+	lambda := make([]float64, n)
+	for i := range lambda {
+		lambda[i] = math.Sin(float64(i + 1))
+	}
+
+	dfdy, dfdp := make([]float64, n), make([]float64, len(p))
+	a.VectorJacobian(0, yT, p, lambda, dfdy, dfdp)
+
+	// df/dy by rows, df/dp as the derivatives of zero sensitivities
+	jacobian := util.MakeRectangular(uint(n), uint(n))
+	g.ParametricSystem().Fix(p).Jacobian()(0, yT, jacobian)
+	s, ds := util.MakeRectangular(uint(len(p)), uint(n)), util.MakeRectangular(uint(len(p)), uint(n))
+	g.ParametricSystem().Sensitivity()(0, yT, p, s, ds)
+
+	for k := 0; k < n; k++ {
+		expected := 0.0
+		for i := 0; i < n; i++ {
+			expected += lambda[i] * jacobian[i][k]
+		}
+		if math.Abs(dfdy[k]-expected) > 1e-10*(1+math.Abs(expected)) {
+			t.Fatalf("%s: (lambda^T df/dy)[%d] = %g, expected %g", problem.Description(), k, dfdy[k], expected)
+		}
+	}
+	for j := range p {
+		expected := 0.0
+		for i := 0; i < n; i++ {
+			expected += lambda[i] * ds[j][i]
+		}
+		if math.Abs(dfdp[j]-expected) > 1e-10*(1+math.Abs(expected)) {
+			t.Fatalf("%s: (lambda^T df/dp)[%d] = %g, expected %g", problem.Description(), j, dfdp[j], expected)
+		}
+	}
+}
+
+func TestVectorJacobian(t *testing.T) {
+	testVectorJacobian(t, NewBruss2D(5))
+}
@@ -7,6 +7,11 @@
  */
 package problems
 
+import (
+	"github.com/rollingthunder/differential/ad"
+	"github.com/rollingthunder/differential/ode"
+)
+
 type Problem interface {
 	Description() string
 	Initialize() []float64
@@ -17,3 +22,75 @@ type TiledProblem interface {
 	Problem
 	FcnBlock(startIdx, blockSize uint, t float64, yT []float64, dy_out []float64)
 }
+
+// HamiltonianProblem is a separable Hamiltonian system written in
+// positions q and velocities v: q' = v, v' = Acceleration(t, q)
+type HamiltonianProblem interface {
+	Problem
+	InitializeSplit() (q, v []float64)
+	Acceleration(t float64, q []float64, a_out []float64)
+	// Energy is the Hamiltonian, which is invariant under the exact flow
+	Energy(q, v []float64) float64
+}
+
+// SecondOrderProblem is a system ddy = SecondOrderFcn(t, y, dy)
+type SecondOrderProblem interface {
+	Problem
+	InitializeSplit() (y, dy []float64)
+	SecondOrderFcn(t float64, yT, dyT []float64, ddy_out []float64)
+}
+
+// JacobianProblem provides the sparsity pattern and the analytic Jacobian of the right hand side
+type JacobianProblem interface {
+	TiledProblem
+	Sparsity() *ode.Sparsity
+	Jacobian(t float64, yT []float64, jac_out *ode.SparseMatrix)
+}
+
+// ReactionDiffusionProblem is a system y' = Reaction(t, y) + Diffusion(t, y)
+// with a non-stiff reaction and a stiff, linear diffusion part
+type ReactionDiffusionProblem interface {
+	JacobianProblem
+	Reaction(t float64, yT []float64, dy_out []float64)
+	Diffusion(t float64, yT []float64, dy_out []float64)
+	DiffusionSparsity() *ode.Sparsity
+	// DiffusionJacobian is constant, as the diffusion is linear
+	DiffusionJacobian(t float64, yT []float64, jac_out *ode.SparseMatrix)
+}
+
+// PreconditionedProblem provides an approximate solver of the linear systems
+// (I - shift J) z = r of implicit integrators, see ode.PreconditionerFunction
+type PreconditionedProblem interface {
+	Problem
+	Preconditioner(t float64, yT []float64, shift float64, r, z_out []float64)
+}
+
+// StochasticProblem is a system dy = Fcn(t, y) dt + Noise(t, y) dW driven by
+// one Wiener process per component, i.e. with diagonal noise
+type StochasticProblem interface {
+	TiledProblem
+	Noise(t float64, yT []float64, g_out []float64)
+}
+
+// ParametricProblem is a system whose right hand side depends on parameters p,
+// Fcn evaluates it for the values returned by Parameters
+type ParametricProblem interface {
+	Problem
+	Parameters() []float64
+	FcnParameters(t float64, yT, p []float64, dy_out []float64)
+}
+
+// AdjointProblem provides the products of a vector with the Jacobians of FcnParameters,
+// the right hand side of the adjoint system
+type AdjointProblem interface {
+	ParametricProblem
+	// VectorJacobian computes lambda^T df/dy and lambda^T df/dp at (t, yT) for the parameters p
+	VectorJacobian(t float64, yT, p, lambda []float64, dfdy_out, dfdp_out []float64)
+}
+
+// GenericProblem provides its parametric right hand side written once over an abstract scalar type,
+// from which the derivatives are computed by automatic differentiation
+type GenericProblem interface {
+	ParametricProblem
+	ParametricSystem() *ad.ParametricSystem
+}
@@ -1,5 +1,6 @@
 package problems
 
+import "github.com/rollingthunder/differential/ad"
 import "math"
 import "fmt"
 
@@ -9,7 +10,7 @@ type mbody struct {
 	mass []float64
 }
 
-func NewMBody(n uint) (p Problem) {
+func NewMBody(n uint) (p HamiltonianProblem) {
 	var m mbody
 
 	m.mass = make([]float64, n)
@@ -25,7 +26,7 @@ func NewMBody(n uint) (p Problem) {
 }
 
 func (m *mbody) Description() string {
-	return fmt.Sprintf("MBody with %i bodies", len(m.mass))
+	return fmt.Sprintf("MBody with %d bodies", len(m.mass))
 }
 
 func (m *mbody) Initialize() (y0 []float64) {
@@ -79,15 +80,53 @@ func (m *mbody) Fcn(t float64, yT []float64, dy_out []float64) {
 	}
 }
 
-func (m *mbody) invariant(n int, yT []float64) float64 {
+// InitializeSplit returns the initial positions and velocities,
+// three components per body
+func (m *mbody) InitializeSplit() (q, v []float64) {
+	y0 := m.Initialize()
+	q, v = make([]float64, 3*len(m.mass)), make([]float64, 3*len(m.mass))
+	for i := range m.mass {
+		copy(q[3*i:3*i+3], y0[6*i:6*i+3])
+		copy(v[3*i:3*i+3], y0[6*i+3:6*i+6])
+	}
+	return
+}
+
+func (m *mbody) Acceleration(t float64, q []float64, a_out []float64) {
+	for i := range m.mass {
+		ip := 3 * i
+		var f1, f2, f3 float64 = 0, 0, 0
+
+		for j := range m.mass {
+			if i != j {
+				jp := 3 * j
+				dist := meps + math.Pow(q[ip]-q[jp], 2) + math.Pow(q[ip+1]-q[jp+1], 2) + math.Pow(q[ip+2]-q[jp+2], 2)
+				dist = m.mass[j] / (dist * math.Sqrt(dist))
+				f1 = f1 + (q[jp]-q[ip])*dist
+				f2 = f2 + (q[jp+1]-q[ip+1])*dist
+				f3 = f3 + (q[jp+2]-q[ip+2])*dist
+			}
+		}
+
+		a_out[ip] = f1
+		a_out[ip+1] = f2
+		a_out[ip+2] = f3
+	}
+}
+
+func (m *mbody) SecondOrderFcn(t float64, yT, dyT []float64, ddy_out []float64) {
+	m.Acceleration(t, yT, ddy_out)
+}
+
+func (m *mbody) Energy(q, v []float64) float64 {
 	en := 0.0
 
 	for i := range m.mass {
-		ip := 6 * i
-		ei := 0.5 * (math.Pow(yT[ip+4], 2) + math.Pow(yT[ip+5], 2) + math.Pow(yT[ip+6], 2))
-		for j := i; j < len(m.mass); j++ {
-			jp := 6 * j
-			dist := meps + math.Pow(yT[ip+1]-yT[jp+1], 2) + math.Pow(yT[ip+2]-yT[jp+2], 2) + math.Pow(yT[ip+3]-yT[jp+3], 2)
+		ip := 3 * i
+		ei := 0.5 * (math.Pow(v[ip], 2) + math.Pow(v[ip+1], 2) + math.Pow(v[ip+2], 2))
+		for j := i + 1; j < len(m.mass); j++ {
+			jp := 3 * j
+			dist := meps + math.Pow(q[ip]-q[jp], 2) + math.Pow(q[ip+1]-q[jp+1], 2) + math.Pow(q[ip+2]-q[jp+2], 2)
 			ei = ei - m.mass[j]/math.Sqrt(dist)
 		}
 		en = en + m.mass[i]*ei
@@ -95,3 +134,94 @@ func (m *mbody) invariant(n int, yT []float64) float64 {
 
 	return en
 }
+
+// Parameters returns the masses of the bodies
+func (m *mbody) Parameters() []float64 {
+	return append([]float64(nil), m.mass...)
+}
+
+// FcnParameters evaluates the right hand side for the masses p
+func (m *mbody) FcnParameters(t float64, yT, p []float64, dy_out []float64) {
+	scaled := mbody{mass: p}
+	scaled.Fcn(t, yT, dy_out)
+}
+
+// VectorJacobian computes lambda^T df/dy and lambda^T df/dp for the masses p.
+// The acceleration of body i by body k is p_k d / s^(3/2) with d = x_k - x_i and
+// s = meps + |d|^2, its derivative by x_k is p_k K with the symmetric K = (I - 3 d d^T / s) / s^(3/2)
+func (m *mbody) VectorJacobian(t float64, yT, p, lambda []float64, dfdy_out, dfdp_out []float64) {
+	for i := range m.mass {
+		ip := 6 * i
+		// the positions change with the velocities
+		dfdy_out[ip+3], dfdy_out[ip+4], dfdy_out[ip+5] = lambda[ip], lambda[ip+1], lambda[ip+2]
+		dfdy_out[ip], dfdy_out[ip+1], dfdy_out[ip+2] = 0, 0, 0
+		dfdp_out[i] = 0
+	}
+
+	for i := range m.mass {
+		ip := 6 * i
+		for k := range m.mass {
+			if i == k {
+				continue
+			}
+			kp := 6 * k
+			d := [3]float64{yT[kp] - yT[ip], yT[kp+1] - yT[ip+1], yT[kp+2] - yT[ip+2]}
+			s := meps + d[0]*d[0] + d[1]*d[1] + d[2]*d[2]
+			scale := 1.0 / (s * math.Sqrt(s))
+
+			// lambda_i^T K, the weights of the velocities of body i
+			mu := lambda[ip+3 : ip+6]
+			muD := mu[0]*d[0] + mu[1]*d[1] + mu[2]*d[2]
+			for c := 0; c < 3; c++ {
+				muK := scale * (mu[c] - 3.0*muD*d[c]/s)
+				dfdy_out[kp+c] += p[k] * muK
+				dfdy_out[ip+c] -= p[k] * muK
+			}
+			dfdp_out[k] += muD * scale
+		}
+	}
+}
+
+// ParametricSystem returns the right hand side of FcnParameters over abstract scalars
+func (m *mbody) ParametricSystem() *ad.ParametricSystem {
+	bodies := len(m.mass)
+	return &ad.ParametricSystem{
+		Value:     mbodyFcn[ad.Float](bodies),
+		Dual:      mbodyFcn[ad.Dual](bodies),
+		HyperDual: mbodyFcn[ad.HyperDual](bodies),
+	}
+}
+
+// MBodyFcn returns the right hand side of NewMBody(n) over the scalar type T,
+// the parameters are the masses
+func MBodyFcn[T ad.Scalar[T]](n uint) ad.ParametricFcn[T] {
+	return mbodyFcn[T](int(n))
+}
+
+func mbodyFcn[T ad.Scalar[T]](bodies int) ad.ParametricFcn[T] {
+	return func(t float64, yT, mass []T, dy_out []T) {
+		for i := 0; i < bodies; i++ {
+			ip := 6 * i
+			dy_out[ip] = yT[ip+3]
+			dy_out[ip+1] = yT[ip+4]
+			dy_out[ip+2] = yT[ip+5]
+			f1, f2, f3 := yT[ip].Const(0), yT[ip].Const(0), yT[ip].Const(0)
+
+			for j := 0; j < bodies; j++ {
+				if i != j {
+					jp := 6 * j
+					d1, d2, d3 := yT[jp].Sub(yT[ip]), yT[jp+1].Sub(yT[ip+1]), yT[jp+2].Sub(yT[ip+2])
+					dist := d1.Mul(d1).Add(d2.Mul(d2)).Add(d3.Mul(d3)).Add(d1.Const(meps))
+					dist = mass[j].Div(dist.Mul(dist.Sqrt()))
+					f1 = f1.Add(d1.Mul(dist))
+					f2 = f2.Add(d2.Mul(dist))
+					f3 = f3.Add(d3.Mul(dist))
+				}
+			}
+
+			dy_out[ip+3] = f1
+			dy_out[ip+4] = f2
+			dy_out[ip+5] = f3
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package problems
+
+import "testing"
+import "math"
+import "github.com/rollingthunder/differential/ode"
+import "github.com/rollingthunder/differential/util"
+
+func TestMBodySplit(t *testing.T) {
+	m := NewMBody(5)
+	y := m.Initialize()
+	q, v := m.InitializeSplit()
+
+	dy, a := make([]float64, len(y)), make([]float64, len(q))
+	m.Fcn(0, y, dy)
+	m.Acceleration(0, q, a)
+
+	for i := 0; i < 5; i++ {
+		for k := 0; k < 3; k++ {
+			if dy[6*i+k] != v[3*i+k] || dy[6*i+3+k] != a[3*i+k] {
+				t.Fatalf("Split form differs from first order form for body %d", i)
+			}
+		}
+	}
+}
+
+func TestMBodyGeneric(t *testing.T) {
+	m := NewMBody(4).(GenericProblem)
+	system := m.ParametricSystem().Fix(m.Parameters())
+	yT := m.Initialize()
+	n := len(yT)
+	expected, dy := make([]float64, n), make([]float64, n)
+
+	m.Fcn(0, yT, expected)
+	system.Function()(0, yT, dy)
+	if !util.ArrayEpsEquals(dy, expected, 1e-12) {
+		t.Errorf("Generic right hand side differs from Fcn")
+	}
+
+	config, err := (&ode.Config{Fcn: m.Fcn}).ValidateAndPrepare(uint(n), 0, 1)
+	if err != nil {
+		t.Fatalf("Invalid configuration - %s", err.Error())
+	}
+	differences, differentiated := util.MakeRectangular(uint(n), uint(n)), util.MakeRectangular(uint(n), uint(n))
+	config.EvaluateJacobian(0, yT, expected, differences)
+	system.Jacobian()(0, yT, differentiated)
+	for i := range differences {
+		for j := range differences[i] {
+			if math.Abs(differentiated[i][j]-differences[i][j]) > 1e-5*math.Max(1, math.Abs(differentiated[i][j])) {
+				t.Fatalf("J[%d][%d] = %g, finite differences %g", i, j, differentiated[i][j], differences[i][j])
+			}
+		}
+	}
+}
+
+func TestMBodyVectorJacobian(t *testing.T) {
+	testVectorJacobian(t, NewMBody(4))
+}
@@ -0,0 +1,31 @@
+package problems
+
+import "fmt"
+
+// noisyBrusselator perturbs the concentrations of the Brusselator
+// by multiplicative noise, so they stay positive
+type noisyBrusselator struct {
+	*brusselator
+	// relative noise intensity
+	sigma float64
+}
+
+// NewNoisyBruss2D initializes a Brusselator 2D problem on an N x N grid, see NewBruss2D,
+// whose concentrations are subject to multiplicative noise of intensity sigma:
+// du = ... dt + sigma u dW_u, dv = ... dt + sigma v dW_v
+func NewNoisyBruss2D(N uint, sigma float64) StochasticProblem {
+	if N <= 0 {
+		return nil
+	}
+	return &noisyBrusselator{brusselator: NewBruss2D(N).(*brusselator), sigma: sigma}
+}
+
+func (b *noisyBrusselator) Description() string {
+	return fmt.Sprintf("Brusselator2D with %d x %d cells and noise intensity %g", b.n, b.n, b.sigma)
+}
+
+func (b *noisyBrusselator) Noise(t float64, yT []float64, g_out []float64) {
+	for i, y := range yT {
+		g_out[i] = b.sigma * y
+	}
+}
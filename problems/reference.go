@@ -0,0 +1,169 @@
+package problems
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"github.com/rollingthunder/differential/ad"
+	"io"
+	"math/big"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// the reference solutions generated by cmd/reference
+//
+//go:embed references/*.ref
+var references embed.FS
+
+// Reference is the solution of a problem at T1 from its initial values at T0,
+// computed to Digits significant digits. In files it is written as
+//
+//	# comment
+//	problem mbody
+//	size 4
+//	interval 0 1
+//	digits 40
+//
+// followed by one value per line
+type Reference struct {
+	Problem string
+	Size    uint
+	T0, T1  float64
+	Digits  uint
+	Comment string
+	Values  []string
+}
+
+// NewReferenceProblem creates the problem of the given name, mbody or bruss2d, and size
+func NewReferenceProblem(problem string, size uint) (GenericProblem, error) {
+	switch problem {
+	case "mbody":
+		return NewMBody(size).(GenericProblem), nil
+	case "bruss2d":
+		return NewBruss2D(size).(GenericProblem), nil
+	}
+	return nil, fmt.Errorf("unknown reference problem %q", problem)
+}
+
+// ReferenceFcn returns the right hand side of the problem over the scalar type T,
+// the reference problems are autonomous
+func ReferenceFcn[T ad.Scalar[T]](problem string, size uint) (ad.Fcn[T], error) {
+	p, err := NewReferenceProblem(problem, size)
+	if err != nil {
+		return nil, err
+	}
+	switch problem {
+	case "mbody":
+		return ad.Fix(MBodyFcn[T](size), p.Parameters()), nil
+	default:
+		return ad.Fix(Bruss2DFcn[T](size), p.Parameters()), nil
+	}
+}
+
+// ReferenceNames returns the names of the embedded reference solutions
+func ReferenceNames() []string {
+	files, _ := references.ReadDir("references")
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f.Name(), ".ref"))
+	}
+	return names
+}
+
+// LoadReference reads the embedded reference solution of the given name
+func LoadReference(name string) (*Reference, error) {
+	f, err := references.Open(path.Join("references", name+".ref"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReference(f)
+}
+
+// ReadReference parses a reference solution
+func ReadReference(r io.Reader) (*Reference, error) {
+	var ref Reference
+	var comments []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			comments = append(comments, strings.TrimSpace(text[1:]))
+			continue
+		}
+
+		var err error
+		fields := strings.Fields(text)
+		switch fields[0] {
+		case "problem":
+			_, err = fmt.Sscan(text[len(fields[0]):], &ref.Problem)
+		case "size":
+			_, err = fmt.Sscan(text[len(fields[0]):], &ref.Size)
+		case "interval":
+			_, err = fmt.Sscan(text[len(fields[0]):], &ref.T0, &ref.T1)
+		case "digits":
+			_, err = fmt.Sscan(text[len(fields[0]):], &ref.Digits)
+		default:
+			if _, ok := new(big.Float).SetString(text); !ok {
+				err = fmt.Errorf("invalid value %q", text)
+			}
+			ref.Values = append(ref.Values, text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reference line %d: %s", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	ref.Comment = strings.Join(comments, "\n")
+
+	p, err := NewReferenceProblem(ref.Problem, ref.Size)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(p.Initialize()); n != len(ref.Values) {
+		return nil, fmt.Errorf("reference of %s %d has %d values instead of %d", ref.Problem, ref.Size, len(ref.Values), n)
+	}
+	return &ref, nil
+}
+
+// Write writes the reference in the format read by ReadReference
+func (r *Reference) Write(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	if r.Comment != "" {
+		for _, line := range strings.Split(r.Comment, "\n") {
+			fmt.Fprintf(b, "# %s\n", line)
+		}
+	}
+	fmt.Fprintf(b, "problem %s\nsize %d\n", r.Problem, r.Size)
+	fmt.Fprintf(b, "interval %s %s\n", strconv.FormatFloat(r.T0, 'g', -1, 64), strconv.FormatFloat(r.T1, 'g', -1, 64))
+	fmt.Fprintf(b, "digits %d\n", r.Digits)
+	for _, v := range r.Values {
+		fmt.Fprintln(b, v)
+	}
+	return b.Flush()
+}
+
+// Float64 returns the values rounded to float64
+func (r *Reference) Float64() []float64 {
+	y := make([]float64, len(r.Values))
+	for i, v := range r.Values {
+		y[i], _ = strconv.ParseFloat(v, 64)
+	}
+	return y
+}
+
+// Floats returns the values with the given precision in bits
+func (r *Reference) Floats(precision uint) []*big.Float {
+	y := make([]*big.Float, len(r.Values))
+	for i, v := range r.Values {
+		y[i], _ = new(big.Float).SetPrec(precision).SetString(v)
+	}
+	return y
+}
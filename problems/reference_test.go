@@ -0,0 +1,49 @@
+package problems
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReferences(t *testing.T) {
+	names := ReferenceNames()
+	if len(names) == 0 {
+		t.Fatal("No embedded references")
+	}
+	for _, name := range names {
+		ref, err := LoadReference(name)
+		if err != nil {
+			t.Fatalf("Couldn't load reference %s: %s", name, err.Error())
+		}
+		if ref.Digits < 30 {
+			t.Errorf("Reference %s has only %d digits", name, ref.Digits)
+		}
+
+		var buffer bytes.Buffer
+		if err := ref.Write(&buffer); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadReference(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(read, ref) {
+			t.Errorf("Reference %s changed by writing and reading it", name)
+		}
+	}
+}
+
+func TestReadReferenceErrors(t *testing.T) {
+	for _, text := range []string{
+		"problem unknown\nsize 4\n",
+		"problem mbody\nsize four\n",
+		"problem mbody\nsize 1\n1.0\nvalue\n",
+		"problem mbody\nsize 1\n1.0\n",
+	} {
+		if _, err := ReadReference(strings.NewReader(text)); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
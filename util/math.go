@@ -26,7 +26,7 @@ func ArrayEpsEquals(x, y []float64, eps float64) bool {
 	}
 	for i := range x {
 		if !EpsEqual(x[i], y[i], eps) {
-			panic(fmt.Sprintln("Unequal entries at (%d): [%v, %v]", i, x[i], y[i]))
+			panic(fmt.Sprintf("Unequal entries at (%d): [%v, %v]", i, x[i], y[i]))
 		}
 	}
 	return true
@@ -36,6 +36,30 @@ func EpsEqual(x, y, eps float64) bool {
 	return math.Abs(x-y) < eps
 }
 
+// FirstNonFinite returns the index of the first NaN or Inf entry of x, or -1
+func FirstNonFinite(x []float64) int {
+	for i, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return i
+		}
+	}
+	return -1
+}
+
+// EuclideanNorm returns the 2-norm of v
+func EuclideanNorm(v []float64) float64 {
+	sum := 0.0
+	for _, x := range v {
+		sum += x * x
+	}
+	return math.Sqrt(sum)
+}
+
+// IsFinite reports whether x is neither NaN nor Inf
+func IsFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
 func RandomInInterval(low, high float64) float64 {
 	return low + (rand.Float64() * (high - low))
 }
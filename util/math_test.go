@@ -0,0 +1,37 @@
+package util
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsFinite(t *testing.T) {
+	for _, x := range []float64{0.0, -1.5, math.MaxFloat64, math.SmallestNonzeroFloat64} {
+		if !IsFinite(x) {
+			t.Errorf("%g should be finite", x)
+		}
+	}
+	for _, x := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if IsFinite(x) {
+			t.Errorf("%g should not be finite", x)
+		}
+	}
+}
+
+func TestFirstNonFinite(t *testing.T) {
+	if i := FirstNonFinite([]float64{1.0, 2.0}); i != -1 {
+		t.Errorf("Expected -1 for finite entries, got %d", i)
+	}
+	if i := FirstNonFinite([]float64{1.0, math.Inf(-1), math.NaN()}); i != 1 {
+		t.Errorf("Expected 1, got %d", i)
+	}
+}
+
+func TestEuclideanNorm(t *testing.T) {
+	if norm := EuclideanNorm([]float64{3.0, -4.0}); norm != 5.0 {
+		t.Errorf("Expected 5, got %g", norm)
+	}
+	if norm := EuclideanNorm(nil); norm != 0.0 {
+		t.Errorf("Expected 0 for the empty vector, got %g", norm)
+	}
+}